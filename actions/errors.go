@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"io"
+	"net"
+)
+
+// ErrorKind classifies the errors of a PortStore so that callers can handle
+// them without knowing each of them.
+type ErrorKind int
+
+const (
+	// KindInternal is any error not of another kind, e.g. an unexpected
+	// reply from the backend.
+	KindInternal ErrorKind = iota
+	// KindInvalid errors are caused by a request which can never succeed.
+	KindInvalid
+	// KindNotFound errors are about a service or port which isn't there.
+	KindNotFound
+	// KindConflict errors are caused by a request which can't succeed given
+	// the state of the pool, e.g. a port held by another service.
+	KindConflict
+	// KindExhausted errors are returned when the pool has no port left which
+	// fits the request. The request may succeed once ports are released.
+	KindExhausted
+	// KindUnavailable errors are returned when the backend can't be reached
+	// or isn't ready yet.
+	KindUnavailable
+)
+
+// KindOf returns the kind of err, which is that of the error wrapped by a
+// BulkError.
+func KindOf(err error) ErrorKind {
+	switch e := err.(type) {
+	case *PortConflictError:
+		return KindConflict
+	case *BulkError:
+		return KindOf(e.Err)
+	case net.Error:
+		return KindUnavailable
+	}
+	switch err {
+	case ErrInvalidRange, ErrInvalidHost, ErrInvalidID, ErrInvalidName, ErrInvalidLabel:
+		return KindInvalid
+	case ErrServiceNotFound, ErrNotQuarantined, ErrHostNotFound:
+		return KindNotFound
+	case ErrAlreadyInitialized, ErrNoLease, ErrPortUnavailable, ErrAssignmentKind:
+		return KindConflict
+	case ErrPoolExhausted, ErrNoBlock, ErrPortsBusy:
+		return KindExhausted
+	case ErrNotInitialized, io.EOF, io.ErrUnexpectedEOF:
+		return KindUnavailable
+	}
+	return KindInternal
+}
@@ -0,0 +1,357 @@
+package actions
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/therealbill/libredis/client"
+	"github.com/therealbill/port-authority/common"
+)
+
+// The names of the events an EventStore records.
+const (
+	// EventAllocated is recorded for every successful allocation, including
+	// those returning ports the service already held, as their lease or
+	// labels may have changed.
+	EventAllocated = "allocated"
+	// EventReleased is recorded when a service holding ports releases them.
+	EventReleased = "released"
+	// EventExpired is recorded when the reaper releases a service whose
+	// lease ran out.
+	EventExpired = "expired"
+	// EventRenewed is recorded when a lease is renewed.
+	EventRenewed = "renewed"
+	// EventRepaired is recorded for each inconsistency a repair fixed.
+	EventRepaired = "repaired"
+)
+
+// eventsChannel is the Redis pub/sub channel RedisEvents publishes the
+// events of a pool on. Like the keys it is prefixed with the pool's.
+const eventsChannel = "events"
+
+// EventSink receives the events recorded by an EventStore.
+type EventSink interface {
+	Record(e common.Event) error
+}
+
+// EventStore is a PortStore which records every change of hands of its
+// ports as a common.Event in a sink. Data holds the pool, the host if any,
+// the id and, where known, the ports and lease expiry.
+type EventStore struct {
+	PortStore
+	Sink EventSink
+	pool string
+	host string
+}
+
+// NewEventStore wraps store, the store of the named pool, so its changes
+// are recorded in sink.
+func NewEventStore(store PortStore, pool string, sink EventSink) *EventStore {
+	return &EventStore{PortStore: store, Sink: sink, pool: pool}
+}
+
+// record hands the event called name about id to the sink. A sink failing
+// is only logged, as the change it describes has been made already.
+func (s *EventStore) record(name, id string, data map[string]string) {
+	if data == nil {
+		data = make(map[string]string)
+	}
+	data["pool"] = s.pool
+	if len(s.host) > 0 {
+		data["host"] = s.host
+	}
+	if len(id) > 0 {
+		data["id"] = id
+	}
+	if err := s.Sink.Record(common.Event{Name: name, Stamp: time.Now(), Data: data}); err != nil {
+		log.Printf("Error recording %s event for '%s': %v", name, id, err)
+	}
+}
+
+// recordAssignment records the event called name about a.
+func (s *EventStore) recordAssignment(name string, a Assignment) {
+	data := make(map[string]string)
+	if len(a.Named) > 0 {
+		var named []string
+		for portName, port := range a.Named {
+			named = append(named, portName+"="+strconv.Itoa(port))
+		}
+		sort.Strings(named)
+		data["named"] = strings.Join(named, ",")
+	}
+	var ports []string
+	for _, port := range a.Ports() {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	data["ports"] = strings.Join(ports, ",")
+	if !a.Expires.IsZero() {
+		data["expires"] = a.Expires.UTC().Format(time.RFC3339)
+	}
+	s.record(name, a.ID, data)
+}
+
+func (s *EventStore) Allocate(id string, opts AllocateOptions) (Assignment, error) {
+	a, err := s.PortStore.Allocate(id, opts)
+	if err == nil {
+		s.recordAssignment(EventAllocated, a)
+	}
+	return a, err
+}
+
+func (s *EventStore) AllocateAll(reqs []AllocateRequest) (map[string]Assignment, error) {
+	assignments, err := s.PortStore.AllocateAll(reqs)
+	if err == nil {
+		for _, req := range reqs {
+			s.recordAssignment(EventAllocated, assignments[req.ID])
+		}
+	}
+	return assignments, err
+}
+
+func (s *EventStore) RemoveService(id string) error {
+	_, err := s.RemoveServices([]string{id})
+	return err
+}
+
+func (s *EventStore) RemoveServices(ids []string) (map[string]bool, error) {
+	held, err := s.PortStore.RemoveServices(ids)
+	if err == nil {
+		recorded := make(map[string]bool)
+		for _, id := range ids {
+			if held[id] && !recorded[id] {
+				s.record(EventReleased, id, nil)
+				recorded[id] = true
+			}
+		}
+	}
+	return held, err
+}
+
+func (s *EventStore) RenewLease(id string, lease time.Duration) (Assignment, error) {
+	a, err := s.PortStore.RenewLease(id, lease)
+	if err == nil {
+		s.recordAssignment(EventRenewed, a)
+	}
+	return a, err
+}
+
+// ReapExpired records an EventExpired for each service reaped, under its
+// host for those reaped from a host of the pool.
+func (s *EventStore) ReapExpired(now time.Time) ([]Reaped, error) {
+	reaped, err := s.PortStore.ReapExpired(now)
+	for _, r := range reaped {
+		data := make(map[string]string)
+		if len(r.Host) > 0 {
+			data["host"] = r.Host
+		}
+		s.record(EventExpired, r.ID, data)
+	}
+	return reaped, err
+}
+
+// Check records an EventRepaired for each inconsistency fixed when repair
+// is set.
+func (s *EventStore) Check(repair bool) ([]Inconsistency, error) {
+	found, err := s.PortStore.Check(repair)
+	if repair && err == nil {
+		for _, i := range found {
+			s.record(EventRepaired, i.ID, map[string]string{"kind": i.Kind, "port": i.Port, "repair": i.Repair})
+		}
+	}
+	return found, err
+}
+
+// Host returns the store of the named host, recording its changes too.
+func (s *EventStore) Host(name string) (PortStore, error) {
+	h, err := s.PortStore.Host(name)
+	if err != nil {
+		return nil, err
+	}
+	return &EventStore{PortStore: h, Sink: s.Sink, pool: s.pool, host: name}, nil
+}
+
+// LookupHost returns the store of a known host, recording its changes too.
+func (s *EventStore) LookupHost(name string) (PortStore, error) {
+	h, err := s.PortStore.LookupHost(name)
+	if err != nil {
+		return nil, err
+	}
+	return &EventStore{PortStore: h, Sink: s.Sink, pool: s.pool, host: name}, nil
+}
+
+// EventLog is an EventSink keeping the events of the last eventexpiration
+// seconds in memory and handing new ones to its subscribers.
+type EventLog struct {
+	mu          sync.Mutex
+	keep        time.Duration
+	lastID      int64
+	events      []common.Event
+	subscribers map[chan common.Event]bool
+}
+
+// NewEventLog returns an empty EventLog.
+func NewEventLog() *EventLog {
+	return &EventLog{
+		keep:        time.Duration(eventexpiration) * time.Second,
+		subscribers: make(map[chan common.Event]bool),
+	}
+}
+
+// Record adds e to the log, numbering it if its ID is zero, and hands it to
+// the subscribers. A subscriber which isn't keeping up is dropped, its
+// channel closed, so it can catch up with Since instead of missing events.
+func (l *EventLog) Record(e common.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.ID == 0 {
+		e.ID = l.lastID + 1
+	}
+	if e.ID > l.lastID {
+		l.lastID = e.ID
+	}
+	if e.Stamp.IsZero() {
+		e.Stamp = time.Now()
+	}
+	l.events = append(l.events, e)
+	cutoff := time.Now().Add(-l.keep)
+	expired := 0
+	for expired < len(l.events) && l.events[expired].Stamp.Before(cutoff) {
+		expired++
+	}
+	l.events = l.events[expired:]
+	for ch := range l.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Since returns the events kept whose ID is above id, oldest first.
+func (l *EventLog) Since(id int64) []common.Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var events []common.Event
+	for _, e := range l.events {
+		if e.ID > id {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// LastID returns the ID of the latest event recorded, zero if there was
+// none.
+func (l *EventLog) LastID() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastID
+}
+
+// Subscribe returns a channel receiving the events recorded from now on,
+// and the function to call once done with it.
+func (l *EventLog) Subscribe() (<-chan common.Event, func()) {
+	ch := make(chan common.Event, 64)
+	l.mu.Lock()
+	l.subscribers[ch] = true
+	l.mu.Unlock()
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.subscribers[ch] {
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// RedisEvents is an EventSink publishing the events of a pool as JSON on
+// its events channel, so every instance serving the pool sees them, see
+// Listen. They are published without an ID, as each instance numbers the
+// events it serves itself.
+type RedisEvents struct {
+	conn    *client.Redis
+	channel string
+}
+
+// NewRedisEvents returns the sink publishing the events of the pool of
+// store, on its Redis server.
+func NewRedisEvents(store *RedisStore) *RedisEvents {
+	return &RedisEvents{conn: store.conn, channel: store.key(eventsChannel)}
+}
+
+func (r *RedisEvents) Record(e common.Event) error {
+	e.ID = 0
+	packed, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = r.conn.Publish(r.channel, string(packed))
+	return err
+}
+
+// Listen records the events published on the events channel of the pool,
+// by any instance, in sink until stop is closed, dropping any ID for sink
+// to number them. It resubscribes when the connection fails and is meant
+// to be run in its own goroutine.
+func (r *RedisEvents) Listen(sink EventSink, stop <-chan struct{}) {
+	for {
+		if err := r.listen(sink, stop); err != nil {
+			log.Printf("Error listening for events: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// listen is a single subscription of Listen.
+func (r *RedisEvents) listen(sink EventSink, stop <-chan struct{}) error {
+	ps, err := r.conn.PubSub()
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		ps.Close()
+	}()
+	if err := ps.Subscribe(r.channel); err != nil {
+		return err
+	}
+	for {
+		msg, err := ps.Receive()
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return err
+			}
+		}
+		if len(msg) != 3 || msg[0] != "message" {
+			continue
+		}
+		var e common.Event
+		if err := json.Unmarshal([]byte(msg[2]), &e); err != nil {
+			log.Printf("Ignoring malformed event: %v", err)
+			continue
+		}
+		e.ID = 0
+		sink.Record(e)
+	}
+}
@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEventStoreReapExpired(t *testing.T) {
+	log := NewEventLog()
+	s := NewEventStore(NewMemoryStore(), "blue", log)
+	if err := s.InitializePorts(7000, 7010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	h, err := s.Host("node1")
+	if err != nil {
+		t.Fatalf("Host(): %v", err)
+	}
+	allocate(t, s, "job@2", AllocateOptions{Lease: time.Minute})
+	allocate(t, h, "web", AllocateOptions{Lease: time.Minute})
+	if _, err := s.ReapExpired(time.Now().Add(2 * time.Minute)); err != nil {
+		t.Fatalf("ReapExpired(): %v", err)
+	}
+	var expired []map[string]string
+	for _, e := range log.Since(0) {
+		if e.Name == EventExpired {
+			expired = append(expired, e.Data)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i]["id"] < expired[j]["id"] })
+	want := []map[string]string{
+		{"pool": "blue", "id": "job@2"},
+		{"pool": "blue", "host": "node1", "id": "web"},
+	}
+	if !reflect.DeepEqual(expired, want) {
+		t.Errorf("Expired events = %v, want %v", expired, want)
+	}
+}
@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Exclusion is a run of ports, from Start up to and including End, which a
+// pool never hands out even though they lie within its range.
+type Exclusion struct {
+	Start int
+	End   int
+}
+
+// String formats e as "PORT" or "START-END", the syntax ParseExclusions
+// reads.
+func (e Exclusion) String() string {
+	if e.Start == e.End {
+		return strconv.Itoa(e.Start)
+	}
+	return fmt.Sprintf("%d-%d", e.Start, e.End)
+}
+
+// MarshalText makes exclusions show up as "START-END" in JSON.
+func (e Exclusion) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// Contains reports whether port is excluded by e.
+func (e Exclusion) Contains(port int) bool {
+	return port >= e.Start && port <= e.End
+}
+
+// ParseExclusions reads a comma separated list of ports and inclusive
+// START-END port ranges, e.g. "32400, 31000-31010". Whitespace and empty
+// items are ignored.
+func ParseExclusions(spec string) ([]Exclusion, error) {
+	var excluded []Exclusion
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		bounds := strings.SplitN(item, "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid port '%s' in exclusion list", item)
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, fmt.Errorf("Invalid port range '%s' in exclusion list", item)
+			}
+		}
+		if start <= 0 || end < start {
+			return nil, fmt.Errorf("Invalid port range '%s' in exclusion list", item)
+		}
+		excluded = append(excluded, Exclusion{Start: start, End: end})
+	}
+	return excluded, nil
+}
+
+// FormatExclusions is the reverse of ParseExclusions.
+func FormatExclusions(excluded []Exclusion) string {
+	items := make([]string, len(excluded))
+	for i, e := range excluded {
+		items[i] = e.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// isExcluded reports whether port is excluded by any of excluded.
+func isExcluded(excluded []Exclusion, port int) bool {
+	for _, e := range excluded {
+		if e.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// countExcluded returns how many of the ports from start up to, but not
+// including, end are excluded.
+func countExcluded(excluded []Exclusion, start, end int) int {
+	count := 0
+	for port := start; port < end; port++ {
+		if isExcluded(excluded, port) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,25 @@
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExclusions(t *testing.T) {
+	excluded, err := ParseExclusions(" 32400, 31000-31010,,7000 - 7001 ")
+	want := []Exclusion{{Start: 32400, End: 32400}, {Start: 31000, End: 31010}, {Start: 7000, End: 7001}}
+	if err != nil || !reflect.DeepEqual(excluded, want) {
+		t.Errorf("ParseExclusions() = %v, %v, want %v", excluded, err, want)
+	}
+	if spec := FormatExclusions(excluded); spec != "32400,31000-31010,7000-7001" {
+		t.Errorf("FormatExclusions() = %q", spec)
+	}
+	if excluded, err := ParseExclusions(""); err != nil || len(excluded) != 0 {
+		t.Errorf("ParseExclusions(\"\") = %v, %v, want nothing", excluded, err)
+	}
+	for _, spec := range []string{"http", "7000-", "7001-7000", "0", "-5"} {
+		if _, err := ParseExclusions(spec); err == nil {
+			t.Errorf("ParseExclusions(%q) succeeded, want an error", spec)
+		}
+	}
+}
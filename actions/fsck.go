@@ -0,0 +1,369 @@
+package actions
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The kinds of inconsistency Check reports.
+const (
+	// InvalidPort is an i2port entry whose port isn't a number.
+	InvalidPort = "invalid_port"
+	// DuplicatePort is a port mapped to more than one id in i2port.
+	DuplicatePort = "duplicate_port"
+	// MissingReverse is a mapped port without a port2i entry.
+	MissingReverse = "missing_reverse"
+	// WrongReverse is a port2i entry naming another id than i2port does.
+	WrongReverse = "wrong_reverse"
+	// StaleReverse is a port2i entry for a port nobody holds.
+	StaleReverse = "stale_reverse"
+	// UnassignedMapping is a mapped port missing from assigned_ports.
+	UnassignedMapping = "unassigned_mapping"
+	// OwnedPortOpen is a mapped port which is also in open_ports.
+	OwnedPortOpen = "owned_port_open"
+	// OpenAndAssigned is an unmapped port in both open_ports and
+	// assigned_ports.
+	OpenAndAssigned = "open_and_assigned"
+	// AssignedWithoutOwner is an unmapped port in assigned_ports only.
+	AssignedWithoutOwner = "assigned_without_owner"
+	// OrphanLease is a lease held by an id without a mapping.
+	OrphanLease = "orphan_lease"
+	// InvalidBlock is a blocks entry whose size isn't a number above one.
+	InvalidBlock = "invalid_block"
+	// OrphanBlock is a blocks entry for an id without a mapping.
+	OrphanBlock = "orphan_block"
+	// QuarantinedHeld is a quarantined port someone holds.
+	QuarantinedHeld = "quarantined_held"
+	// QuarantinedOpen is a quarantined port which is also in open_ports.
+	QuarantinedOpen = "quarantined_open"
+	// CoolingHeld is a cooling port someone holds.
+	CoolingHeld = "cooling_held"
+	// CoolingOpen is a cooling port which is also in open_ports.
+	CoolingOpen = "cooling_open"
+	// WrongNames is a names entry not listing exactly the named ports the
+	// service holds.
+	WrongNames = "wrong_names"
+	// OrphanLabels are labels stored for an id holding no port.
+	OrphanLabels = "orphan_labels"
+)
+
+// Inconsistency is a single problem found by Check, along with what repairing
+// it does.
+type Inconsistency struct {
+	Kind   string
+	ID     string
+	Port   string
+	Detail string
+	Repair string
+	fixes  []fix
+}
+
+// fixOp is a primitive change to the data model used to repair it.
+type fixOp string
+
+const (
+	fixSetAdd       fixOp = "sadd"
+	fixSetRemove    fixOp = "srem"
+	fixHashSet      fixOp = "hset"
+	fixHashDelete   fixOp = "hdel"
+	fixLeaseDelete  fixOp = "unlease"
+	fixUnquarantine fixOp = "unquarantine"
+	fixUncool       fixOp = "uncool"
+)
+
+// fix applies op to the data model structure named by key, which is one of
+// open_ports, assigned_ports, i2port, port2i, blocks, names, labels, leases,
+// quarantine or cooling. For sets field is the member, for hashes it is the
+// field set to value, for leases it is the id and for quarantine and cooling
+// the port.
+type fix struct {
+	op    fixOp
+	key   string
+	field string
+	value string
+}
+
+// snapshot is a copy of the data model taken at a single point in time.
+type snapshot struct {
+	open     map[string]bool
+	assigned map[string]bool
+	i2port   map[string]string
+	port2i   map[string]string
+	leases   map[string]bool
+	blocks   map[string]string
+	names    map[string]string
+	labels   map[string]string
+	// quarantine and cooling hold the quarantined and cooling ports.
+	quarantine map[string]bool
+	cooling    map[string]bool
+	// start, end and excluded are the range and exclusions of the pool.
+	start    int
+	end      int
+	excluded []Exclusion
+}
+
+// free reports whether port belongs in open_ports when nobody holds it: it
+// is within the range, not excluded, and neither quarantined nor cooling.
+func (snap snapshot) free(port string) bool {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < snap.start || p >= snap.end || isExcluded(snap.excluded, p) {
+		return false
+	}
+	return !snap.quarantine[port] && !snap.cooling[port]
+}
+
+// findInconsistencies compares the structures in snap with one another. The
+// i2port hash is taken as the truth as to who holds which port, the other
+// structures are expected to agree with it.
+func findInconsistencies(snap snapshot) []Inconsistency {
+	var found []Inconsistency
+	add := func(i Inconsistency) {
+		found = append(found, i)
+	}
+
+	// work out who owns each port. Ids port2i agrees with go first so they
+	// win any dispute, otherwise the order is stable so reruns agree.
+	var agreed, others []string
+	for _, id := range hashFields(snap.i2port) {
+		if snap.port2i[snap.i2port[id]] == id {
+			agreed = append(agreed, id)
+		} else {
+			others = append(others, id)
+		}
+	}
+	owners := make(map[string]string)
+	for _, id := range append(agreed, others...) {
+		port := snap.i2port[id]
+		first, err := strconv.Atoi(port)
+		if err != nil {
+			add(Inconsistency{Kind: InvalidPort, ID: id, Port: port,
+				Detail: fmt.Sprintf("'%s' is mapped to '%s', which is not a port", id, port),
+				Repair: "remove the mapping",
+				fixes:  unmapFixes(id)})
+			continue
+		}
+		size := 1
+		if block, ok := snap.blocks[id]; ok {
+			if size, err = strconv.Atoi(block); err != nil || size < 2 {
+				add(Inconsistency{Kind: InvalidBlock, ID: id, Port: port,
+					Detail: fmt.Sprintf("'%s' has a block size of '%s'", id, block),
+					Repair: "treat it as holding a single port",
+					fixes:  []fix{{op: fixHashDelete, key: "blocks", field: id}}})
+				size = 1
+			}
+		}
+		ports := make([]string, size)
+		clash := ""
+		for i := range ports {
+			ports[i] = strconv.Itoa(first + i)
+			if _, taken := owners[ports[i]]; taken && clash == "" {
+				clash = ports[i]
+			}
+		}
+		if clash != "" {
+			owner := owners[clash]
+			add(Inconsistency{Kind: DuplicatePort, ID: id, Port: clash,
+				Detail: fmt.Sprintf("port %s is mapped to both '%s' and '%s'", clash, owner, id),
+				Repair: fmt.Sprintf("remove the mapping of '%s', leaving the port with '%s'", id, owner),
+				fixes:  unmapFixes(id)})
+			continue
+		}
+		for _, p := range ports {
+			owners[p] = id
+		}
+	}
+
+	for _, port := range hashFields(owners) {
+		id := owners[port]
+		switch reverse, ok := snap.port2i[port]; {
+		case !ok:
+			add(Inconsistency{Kind: MissingReverse, ID: id, Port: port,
+				Detail: fmt.Sprintf("port %s is held by '%s' but missing from port2i", port, id),
+				Repair: "add it to port2i",
+				fixes:  []fix{{op: fixHashSet, key: "port2i", field: port, value: id}}})
+		case reverse != id:
+			add(Inconsistency{Kind: WrongReverse, ID: id, Port: port,
+				Detail: fmt.Sprintf("port %s is held by '%s' but port2i says '%s'", port, id, reverse),
+				Repair: fmt.Sprintf("point port2i at '%s'", id),
+				fixes:  []fix{{op: fixHashSet, key: "port2i", field: port, value: id}}})
+		}
+		if !snap.assigned[port] {
+			add(Inconsistency{Kind: UnassignedMapping, ID: id, Port: port,
+				Detail: fmt.Sprintf("port %s is held by '%s' but missing from assigned_ports", port, id),
+				Repair: "add it to assigned_ports",
+				fixes:  []fix{{op: fixSetAdd, key: "assigned_ports", field: port}}})
+		}
+		if snap.open[port] {
+			add(Inconsistency{Kind: OwnedPortOpen, ID: id, Port: port,
+				Detail: fmt.Sprintf("port %s is held by '%s' but still in open_ports", port, id),
+				Repair: "remove it from open_ports",
+				fixes:  []fix{{op: fixSetRemove, key: "open_ports", field: port}}})
+		}
+	}
+
+	for _, port := range hashFields(snap.port2i) {
+		if _, owned := owners[port]; !owned {
+			id := snap.port2i[port]
+			add(Inconsistency{Kind: StaleReverse, ID: id, Port: port,
+				Detail: fmt.Sprintf("port2i maps %s to '%s' but nobody holds the port", port, id),
+				Repair: "remove it from port2i",
+				fixes:  []fix{{op: fixHashDelete, key: "port2i", field: port}}})
+		}
+	}
+
+	for _, port := range setMembers(snap.assigned) {
+		if _, owned := owners[port]; owned {
+			continue
+		}
+		if snap.open[port] {
+			add(Inconsistency{Kind: OpenAndAssigned, Port: port,
+				Detail: fmt.Sprintf("port %s is in both open_ports and assigned_ports but nobody holds it", port),
+				Repair: "remove it from assigned_ports",
+				fixes:  []fix{{op: fixSetRemove, key: "assigned_ports", field: port}}})
+			continue
+		}
+		i := Inconsistency{Kind: AssignedWithoutOwner, Port: port,
+			Detail: fmt.Sprintf("port %s is in assigned_ports but nobody holds it", port),
+			Repair: "remove it from assigned_ports",
+			fixes:  []fix{{op: fixSetRemove, key: "assigned_ports", field: port}}}
+		if snap.free(port) {
+			i.Repair = "move it back to open_ports"
+			i.fixes = append(i.fixes, fix{op: fixSetAdd, key: "open_ports", field: port})
+		}
+		add(i)
+	}
+
+	for _, id := range hashFields(snap.blocks) {
+		if _, ok := snap.i2port[id]; !ok {
+			add(Inconsistency{Kind: OrphanBlock, ID: id,
+				Detail: fmt.Sprintf("'%s' has a block size but holds no port", id),
+				Repair: "remove the block size",
+				fixes:  []fix{{op: fixHashDelete, key: "blocks", field: id}}})
+		}
+	}
+
+	for _, port := range setMembers(snap.quarantine) {
+		if id, owned := owners[port]; owned {
+			add(Inconsistency{Kind: QuarantinedHeld, ID: id, Port: port,
+				Detail: fmt.Sprintf("port %s is quarantined but held by '%s'", port, id),
+				Repair: "take it out of quarantine",
+				fixes:  []fix{{op: fixUnquarantine, key: "quarantine", field: port}}})
+		} else if snap.open[port] {
+			add(Inconsistency{Kind: QuarantinedOpen, Port: port,
+				Detail: fmt.Sprintf("port %s is quarantined but still in open_ports", port),
+				Repair: "remove it from open_ports",
+				fixes:  []fix{{op: fixSetRemove, key: "open_ports", field: port}}})
+		}
+	}
+
+	for _, port := range setMembers(snap.cooling) {
+		if id, owned := owners[port]; owned {
+			add(Inconsistency{Kind: CoolingHeld, ID: id, Port: port,
+				Detail: fmt.Sprintf("port %s is cooling down but held by '%s'", port, id),
+				Repair: "take it out of cooling",
+				fixes:  []fix{{op: fixUncool, key: "cooling", field: port}}})
+		} else if snap.open[port] {
+			add(Inconsistency{Kind: CoolingOpen, Port: port,
+				Detail: fmt.Sprintf("port %s is cooling down but already in open_ports", port),
+				Repair: "take it out of cooling",
+				fixes:  []fix{{op: fixUncool, key: "cooling", field: port}}})
+		}
+	}
+
+	// the named ports of a service are the ids ID/NAME left holding a port.
+	held := make(map[string]bool)
+	for _, id := range owners {
+		held[id] = true
+	}
+	named := make(map[string][]string)
+	for _, id := range hashFields(snap.i2port) {
+		if i := strings.LastIndex(id, "/"); i > 0 && held[id] {
+			named[id[:i]] = append(named[id[:i]], id[i+1:])
+		}
+	}
+	services := make(map[string]string)
+	for id := range snap.names {
+		services[id] = ""
+	}
+	for id := range named {
+		services[id] = ""
+	}
+	for _, id := range hashFields(services) {
+		listed := strings.Split(snap.names[id], ",")
+		sort.Strings(listed)
+		want := strings.Join(named[id], ",")
+		if strings.Join(listed, ",") == want {
+			continue
+		}
+		i := Inconsistency{Kind: WrongNames, ID: id,
+			Detail: fmt.Sprintf("names lists '%s' for '%s' but it holds '%s'", snap.names[id], id, want)}
+		if len(want) == 0 {
+			i.Repair = "remove the names entry"
+			i.fixes = []fix{{op: fixHashDelete, key: "names", field: id}}
+		} else {
+			i.Repair = fmt.Sprintf("set it to '%s'", want)
+			i.fixes = []fix{{op: fixHashSet, key: "names", field: id, value: want}}
+		}
+		add(i)
+	}
+
+	for _, id := range setMembers(snap.leases) {
+		if _, ok := snap.i2port[id]; !ok && len(named[id]) == 0 {
+			add(Inconsistency{Kind: OrphanLease, ID: id,
+				Detail: fmt.Sprintf("'%s' has a lease but holds no port", id),
+				Repair: "remove the lease",
+				fixes:  []fix{{op: fixLeaseDelete, key: "leases", field: id}}})
+		}
+	}
+
+	for _, id := range hashFields(snap.labels) {
+		if _, ok := snap.i2port[id]; !ok && len(named[id]) == 0 {
+			add(Inconsistency{Kind: OrphanLabels, ID: id,
+				Detail: fmt.Sprintf("'%s' has labels but holds no port", id),
+				Repair: "remove the labels",
+				fixes:  []fix{{op: fixHashDelete, key: "labels", field: id}}})
+		}
+	}
+	return found
+}
+
+// unmapFixes returns the fixes removing every trace of id's mapping.
+func unmapFixes(id string) []fix {
+	return []fix{
+		{op: fixHashDelete, key: "i2port", field: id},
+		{op: fixHashDelete, key: "blocks", field: id},
+		{op: fixLeaseDelete, key: "leases", field: id},
+		{op: fixHashDelete, key: "labels", field: id},
+	}
+}
+
+// collectFixes flattens the fixes of every inconsistency.
+func collectFixes(found []Inconsistency) []fix {
+	var fixes []fix
+	for _, i := range found {
+		fixes = append(fixes, i.fixes...)
+	}
+	return fixes
+}
+
+// hashFields returns the fields of a hash in order.
+func hashFields(hash map[string]string) []string {
+	fields := make([]string, 0, len(hash))
+	for f := range hash {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// setMembers returns the members of a set in order.
+func setMembers(set map[string]bool) []string {
+	members := make([]string, 0, len(set))
+	for m := range set {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
@@ -0,0 +1,247 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxLabelLength is the longest a label key or value may be.
+const maxLabelLength = 63
+
+// Labels are key, value pairs stored with an assignment to record e.g. the
+// team owning it, the container using it or the ticket it was made for.
+type Labels map[string]string
+
+// checkLabels returns ErrInvalidLabel unless every label can be stored. Keys
+// and values are made of letters, digits, '.', '_' and '-', keys may also
+// contain '/', much like Kubernetes labels.
+func checkLabels(labels Labels) error {
+	for key, value := range labels {
+		if len(key) == 0 || !validLabel(key, true) || !validLabel(value, false) {
+			return ErrInvalidLabel
+		}
+	}
+	return nil
+}
+
+// validLabel reports whether s is a valid label key, or value when key is
+// false.
+func validLabel(s string, key bool) bool {
+	if len(s) > maxLabelLength {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("._-", r):
+		case key && r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// encodeLabels returns labels as the JSON object the stores keep them as. It
+// is empty for nil labels, which leave the stored labels alone, and "{}" for
+// empty ones, which remove them.
+func encodeLabels(labels Labels) string {
+	if labels == nil {
+		return ""
+	}
+	encoded, _ := json.Marshal(labels)
+	return string(encoded)
+}
+
+// decodeLabels is the reverse of encodeLabels. Labels which can't be decoded
+// are treated as none.
+func decodeLabels(encoded string) Labels {
+	if len(encoded) == 0 {
+		return nil
+	}
+	var labels Labels
+	if err := json.Unmarshal([]byte(encoded), &labels); err != nil || len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// serviceOf returns the id of the service a mapped id belongs to, which is
+// ID for the named port ID/NAME and id itself otherwise.
+func serviceOf(id string) string {
+	if i := strings.LastIndex(id, "/"); i > 0 {
+		return id[:i]
+	}
+	return id
+}
+
+// setRequirement matches the "KEY in (A,B)" and "KEY notin (A,B)" forms of a
+// selector requirement.
+var setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// Requirement is a single condition of a Selector on the label Key.
+type Requirement struct {
+	Key string
+	// Op is one of "=", "!=", "in", "notin", "exists" and "!exists".
+	Op     string
+	Values []string
+}
+
+// Matches reports whether labels satisfy the requirement. Like in
+// Kubernetes, "!=" and "notin" match labels without the key.
+func (r Requirement) Matches(labels Labels) bool {
+	value, ok := labels[r.Key]
+	switch r.Op {
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	case "=", "in":
+		return ok && contains(r.Values, value)
+	case "!=", "notin":
+		return !ok || !contains(r.Values, value)
+	}
+	return false
+}
+
+// Selector selects assignments by their labels. It matches the labels which
+// satisfy all of its requirements, so an empty Selector matches everything.
+type Selector []Requirement
+
+// ParseSelector reads a comma separated list of requirements in the syntax
+// of Kubernetes label selectors, e.g. "team=payments,env!=prod". Each is one
+// of KEY=VALUE (or KEY==VALUE), KEY!=VALUE, KEY in (A,B), KEY notin (A,B),
+// KEY, which requires the label to be set, and !KEY, which requires it not
+// to be.
+func ParseSelector(spec string) (Selector, error) {
+	var sel Selector
+	for _, term := range splitSelector(spec) {
+		term = strings.TrimSpace(term)
+		if len(term) == 0 {
+			continue
+		}
+		var r Requirement
+		if m := setRequirement.FindStringSubmatch(term); m != nil {
+			r = Requirement{Key: m[1], Op: m[2]}
+			for _, value := range strings.Split(m[3], ",") {
+				r.Values = append(r.Values, strings.TrimSpace(value))
+			}
+		} else if i := strings.Index(term, "!="); i >= 0 {
+			r = Requirement{Key: term[:i], Op: "!=", Values: []string{term[i+2:]}}
+		} else if i := strings.Index(term, "="); i >= 0 {
+			r = Requirement{Key: term[:i], Op: "=", Values: []string{strings.TrimPrefix(term[i+1:], "=")}}
+		} else if strings.HasPrefix(term, "!") {
+			r = Requirement{Key: term[1:], Op: "!exists"}
+		} else {
+			r = Requirement{Key: term, Op: "exists"}
+		}
+		r.Key = strings.TrimSpace(r.Key)
+		valid := len(r.Key) > 0 && validLabel(r.Key, true)
+		for i, value := range r.Values {
+			r.Values[i] = strings.TrimSpace(value)
+			valid = valid && validLabel(r.Values[i], false)
+		}
+		if !valid {
+			return nil, fmt.Errorf("Invalid requirement '%s' in label selector", term)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// splitSelector splits spec at the commas which aren't within parentheses.
+func splitSelector(spec string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range spec {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, spec[start:])
+}
+
+// Matches reports whether labels satisfy every requirement of sel.
+func (sel Selector) Matches(labels Labels) bool {
+	for _, r := range sel {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether values holds value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// selectPageSize is how many ids SelectServices asks ListMappings for at a
+// time.
+const selectPageSize = 1000
+
+// SelectServices returns the assignments of every service in store whose
+// labels match sel, in no particular order. It pages through the whole
+// mapping, so services allocated or released meanwhile may or may not be
+// included.
+func SelectServices(store PortStore, sel Selector) ([]Assignment, error) {
+	seen := make(map[string]bool)
+	var selected []Assignment
+	var cursor uint64
+	for {
+		page, err := store.ListMappings(cursor, "", selectPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for id := range page.Mappings {
+			service := serviceOf(id)
+			if seen[service] || !sel.Matches(page.Labels[id]) {
+				continue
+			}
+			seen[service] = true
+			a, err := store.GetAssignment(service)
+			if err != nil {
+				return nil, err
+			}
+			if !a.Empty() {
+				selected = append(selected, a)
+			}
+		}
+		if cursor = page.Cursor; cursor == 0 {
+			return selected, nil
+		}
+	}
+}
+
+// ReleaseServices releases every service in store whose labels match sel,
+// one at a time, and returns the ids released. On error it returns those
+// released so far.
+func ReleaseServices(store PortStore, sel Selector) ([]string, error) {
+	selected, err := SelectServices(store, sel)
+	if err != nil {
+		return nil, err
+	}
+	var released []string
+	for _, a := range selected {
+		if err := store.RemoveService(a.ID); err != nil {
+			return released, err
+		}
+		released = append(released, a.ID)
+	}
+	return released, nil
+}
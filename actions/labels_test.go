@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	sel, err := ParseSelector("team=payments, env!=prod,tier in (web, api),zone notin (a),canary,!legacy,app==shop")
+	want := Selector{
+		{Key: "team", Op: "=", Values: []string{"payments"}},
+		{Key: "env", Op: "!=", Values: []string{"prod"}},
+		{Key: "tier", Op: "in", Values: []string{"web", "api"}},
+		{Key: "zone", Op: "notin", Values: []string{"a"}},
+		{Key: "canary", Op: "exists"},
+		{Key: "legacy", Op: "!exists"},
+		{Key: "app", Op: "=", Values: []string{"shop"}},
+	}
+	if err != nil || !reflect.DeepEqual(sel, want) {
+		t.Errorf("ParseSelector() = %+v, %v, want %+v", sel, err, want)
+	}
+	if sel, err := ParseSelector(" "); err != nil || len(sel) != 0 {
+		t.Errorf("ParseSelector(\" \") = %+v, %v, want an empty selector", sel, err)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := Labels{"team": "payments", "env": "prod"}
+	for spec, want := range map[string]bool{
+		"":                     true,
+		"team=payments":        true,
+		"team=search":          false,
+		"env!=staging":         true,
+		"zone!=a":              true,
+		"env in (prod,dev)":    true,
+		"env notin (prod,dev)": false,
+		"zone notin (a)":       true,
+		"team":                 true,
+		"zone":                 false,
+		"!zone":                true,
+		"team=payments,!env":   false,
+	} {
+		sel, err := ParseSelector(spec)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", spec, err)
+		}
+		if got := sel.Matches(labels); got != want {
+			t.Errorf("%q matches %v = %v, want %v", spec, labels, got, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package actions
+
+import (
+	"log"
+	"time"
+)
+
+// StartReaper releases expired leases from store every interval until stop is
+// closed. It is meant to be run in its own goroutine.
+func StartReaper(store PortStore, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			reaped, err := store.ReapExpired(now)
+			if err != nil {
+				log.Printf("Error reaping expired leases: %v", err)
+				continue
+			}
+			for _, r := range reaped {
+				log.Printf("Lease for '%s' expired, its port is back in the pool", r)
+			}
+		}
+	}
+}
+
+// toMillis converts t to the millisecond timestamps leases are stored as.
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// fromMillis is the inverse of toMillis.
+func fromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,942 @@
+package actions
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a PortStore which keeps everything in process memory. It
+// mirrors the Redis data model and is meant for tests and development where
+// running a Redis server is not worth the trouble. Nothing survives a
+// restart.
+type MemoryStore struct {
+	mu          sync.Mutex
+	initialized bool
+	start       int
+	end         int
+	excluded    []Exclusion
+	open        map[string]bool
+	assigned    map[string]bool
+	i2port      map[string]string
+	port2i      map[string]string
+	blocks      map[string]string
+	names       map[string]string
+	labels      map[string]string
+	quarantine  map[string]time.Time
+	cooling     map[string]time.Time
+	cooldown    time.Duration
+	strategy    Strategy
+	leases      map[string]time.Time
+	durations   map[string]time.Duration
+	// hosts holds the stores of the pool's hosts, parent the store of the
+	// pool a host store belongs to.
+	hosts  map[string]*MemoryStore
+	parent *MemoryStore
+}
+
+// NewMemoryStore returns an empty, uninitialized MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		open:       make(map[string]bool),
+		assigned:   make(map[string]bool),
+		i2port:     make(map[string]string),
+		port2i:     make(map[string]string),
+		blocks:     make(map[string]string),
+		names:      make(map[string]string),
+		labels:     make(map[string]string),
+		quarantine: make(map[string]time.Time),
+		cooling:    make(map[string]time.Time),
+		leases:     make(map[string]time.Time),
+		durations:  make(map[string]time.Duration),
+		hosts:      make(map[string]*MemoryStore),
+	}
+}
+
+// SetCooldown makes released ports wait for d before they can be assigned
+// again. Hosts opened afterwards inherit the cooldown.
+func (s *MemoryStore) SetCooldown(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cooldown = d
+}
+
+// SetStrategy makes the store pick the ports of new assignments with st.
+// Hosts opened afterwards inherit the strategy.
+func (s *MemoryStore) SetStrategy(st Strategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strategy = st
+}
+
+func (s *MemoryStore) InitializePorts(start, end int, excluded []Exclusion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.initialized {
+		return ErrAlreadyInitialized
+	}
+	_, err := s.reconcile(start, end, excluded)
+	return err
+}
+
+func (s *MemoryStore) Reconcile(start, end int, excluded []Exclusion) (ReconcileReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reconcile(start, end, excluded)
+}
+
+// reconcile is Reconcile for callers already holding s.mu.
+func (s *MemoryStore) reconcile(start, end int, excluded []Exclusion) (ReconcileReport, error) {
+	report := ReconcileReport{Start: start, End: end, Stranded: make(map[string]string), Excluded: excluded}
+	if end <= start {
+		return report, ErrInvalidRange
+	}
+	s.start, s.end, s.excluded, s.initialized = start, end, excluded, true
+	for i := start; i < end; i++ {
+		port := strconv.Itoa(i)
+		_, quarantined := s.quarantine[port]
+		_, cooling := s.cooling[port]
+		if s.inPool(port) && !s.open[port] && !s.assigned[port] && s.holder(port) == "" && !quarantined && !cooling {
+			s.open[port] = true
+			report.Added = append(report.Added, port)
+		}
+	}
+	for _, port := range sortedPorts(s.open) {
+		if !s.inPool(port) {
+			delete(s.open, port)
+			report.Removed = append(report.Removed, port)
+		}
+	}
+	for port := range s.assigned {
+		if !s.inPool(port) {
+			report.Stranded[port] = s.holder(port)
+		}
+	}
+	for _, h := range s.hosts {
+		if _, err := h.Reconcile(start, end, excluded); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (s *MemoryStore) GetRange() (start, end int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start, s.end, nil
+}
+
+func (s *MemoryStore) GetExclusions() ([]Exclusion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.excluded, nil
+}
+
+// inPool reports whether port lies within the pool's range and isn't
+// excluded. The caller must hold s.mu.
+func (s *MemoryStore) inPool(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= s.start && n < s.end && !isExcluded(s.excluded, n)
+}
+
+func (s *MemoryStore) Allocate(id string, opts AllocateOptions) (Assignment, error) {
+	if err := checkID(id); err != nil {
+		return Assignment{}, err
+	}
+	if err := checkNames(opts.Names); err != nil {
+		return Assignment{}, err
+	}
+	if err := checkLabels(opts.Labels); err != nil {
+		return Assignment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promote(time.Now())
+	return s.allocate(id, opts)
+}
+
+// AllocateAll holds the lock for all of the allocations, see PortStore.
+func (s *MemoryStore) AllocateAll(reqs []AllocateRequest) (map[string]Assignment, error) {
+	for _, req := range reqs {
+		if err := checkID(req.ID); err != nil {
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+		if err := checkNames(req.Options.Names); err != nil {
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+		if err := checkLabels(req.Options.Labels); err != nil {
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promote(time.Now())
+	assigned := make(map[string]Assignment)
+	var created []string
+	var saved []heldState
+	for _, req := range reqs {
+		_, held := s.i2port[req.ID]
+		_, named := s.names[req.ID]
+		if held || named {
+			saved = append(saved, s.save(req.ID))
+		}
+		a, err := s.allocate(req.ID, req.Options)
+		if err != nil {
+			for i := len(saved) - 1; i >= 0; i-- {
+				s.restore(saved[i])
+			}
+			for _, id := range created {
+				s.release(id, time.Time{})
+			}
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+		if !held && !named {
+			created = append(created, req.ID)
+		}
+		assigned[req.ID] = a
+	}
+	return assigned, nil
+}
+
+// heldState is what allocating ports for a service already holding some may
+// change, saved by AllocateAll to put back when a later allocation fails.
+type heldState struct {
+	id       string
+	names    string
+	named    bool
+	labels   string
+	lease    time.Time
+	duration time.Duration
+}
+
+// save returns the heldState of id. The caller must hold s.mu.
+func (s *MemoryStore) save(id string) heldState {
+	names, named := s.names[id]
+	return heldState{id: id, names: names, named: named, labels: s.labels[id], lease: s.leases[id], duration: s.durations[id]}
+}
+
+// restore puts back the names, labels and lease saved in h, releasing the
+// named ports given to the service since. The caller must hold s.mu.
+func (s *MemoryStore) restore(h heldState) {
+	had := make(map[string]bool)
+	for _, name := range strings.Split(h.names, ",") {
+		had[name] = true
+	}
+	for _, name := range s.namesOf(h.id) {
+		if !had[name] {
+			s.release(NamedID(h.id, name), time.Time{})
+		}
+	}
+	if h.named {
+		s.names[h.id] = h.names
+	}
+	if len(h.labels) > 0 {
+		s.labels[h.id] = h.labels
+	} else {
+		delete(s.labels, h.id)
+	}
+	if h.lease.IsZero() {
+		delete(s.leases, h.id)
+		delete(s.durations, h.id)
+	} else {
+		s.leases[h.id] = h.lease
+		s.durations[h.id] = h.duration
+	}
+}
+
+// allocate assigns ports to id, or returns those it already holds, changing
+// nothing when it fails. The caller must hold s.mu and have checked opts.
+func (s *MemoryStore) allocate(id string, opts AllocateOptions) (Assignment, error) {
+	size := opts.Block
+	if size < 1 {
+		size = 1
+	}
+	held, ok := s.i2port[id]
+	_, named := s.names[id]
+	switch {
+	case len(opts.Names) > 0:
+		if ok {
+			return Assignment{}, ErrAssignmentKind
+		}
+		if err := s.allocateNames(id, opts.Names); err != nil {
+			return Assignment{}, err
+		}
+		ok = true
+	case named:
+		if opts.Port != 0 && !opts.Prefer {
+			return Assignment{}, ErrAssignmentKind
+		}
+		ok = true
+	}
+	if ok && len(opts.Names) == 0 && opts.Port != 0 && !opts.Prefer && held != strconv.Itoa(opts.Port) {
+		heldPort, _ := strconv.Atoi(held)
+		return Assignment{}, &PortConflictError{ID: id, Port: opts.Port, Owner: id, Held: heldPort}
+	}
+	if !ok {
+		first := 0
+		if opts.Port != 0 {
+			owner, free := s.blockTaken(opts.Port, size)
+			switch {
+			case free:
+				first = opts.Port
+			case opts.Prefer:
+			case owner != "":
+				return Assignment{}, &PortConflictError{ID: id, Port: opts.Port, Owner: owner}
+			default:
+				return Assignment{}, ErrPortUnavailable
+			}
+		}
+		if first == 0 && size > 1 {
+			if first = s.findBlock(size, s.startOf(id)); first == 0 {
+				return Assignment{}, ErrNoBlock
+			}
+		}
+		if first == 0 {
+			if first = s.pick(id); first == 0 {
+				return Assignment{}, ErrPoolExhausted
+			}
+		}
+		s.assign(id, first, size)
+	}
+	if opts.Lease > 0 {
+		s.leases[id] = time.Now().Add(opts.Lease)
+		s.durations[id] = opts.Lease
+	}
+	switch encoded := encodeLabels(opts.Labels); encoded {
+	case "":
+	case "{}":
+		delete(s.labels, id)
+	default:
+		s.labels[id] = encoded
+	}
+	return s.assignment(id)
+}
+
+// allocateNames assigns a port to each of names id doesn't hold yet, all or
+// none of them. The caller must hold s.mu.
+func (s *MemoryStore) allocateNames(id string, names []string) error {
+	list := s.namesOf(id)
+	held := make(map[string]bool)
+	for _, name := range list {
+		held[name] = true
+	}
+	var missing []string
+	for _, name := range names {
+		if !held[name] {
+			held[name] = true
+			list = append(list, name)
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > len(s.open) {
+		return ErrPoolExhausted
+	}
+	for _, name := range missing {
+		s.assign(NamedID(id, name), s.pick(NamedID(id, name)), 1)
+	}
+	s.names[id] = strings.Join(list, ",")
+	return nil
+}
+
+// assign maps owner to the size ports from first on. The caller must hold
+// s.mu.
+func (s *MemoryStore) assign(owner string, first, size int) {
+	for n := first; n < first+size; n++ {
+		port := strconv.Itoa(n)
+		delete(s.open, port)
+		s.assigned[port] = true
+		s.port2i[port] = owner
+	}
+	s.i2port[owner] = strconv.Itoa(first)
+	if size > 1 {
+		s.blocks[owner] = strconv.Itoa(size)
+	}
+}
+
+// namesOf returns the port names held by id. The caller must hold s.mu.
+func (s *MemoryStore) namesOf(id string) []string {
+	if len(s.names[id]) == 0 {
+		return nil
+	}
+	return strings.Split(s.names[id], ",")
+}
+
+// promote moves the ports which have cooled down by now back into the pool.
+// The caller must hold s.mu.
+func (s *MemoryStore) promote(now time.Time) {
+	for port, released := range s.cooling {
+		if released.Add(s.cooldown).After(now) {
+			continue
+		}
+		delete(s.cooling, port)
+		if s.inPool(port) && s.holder(port) == "" && !s.assigned[port] {
+			s.open[port] = true
+		}
+	}
+}
+
+// startOf returns the port the strategy starts looking for a free port for
+// owner at, or zero for a random port. The caller must hold s.mu.
+func (s *MemoryStore) startOf(owner string) int {
+	if s.end <= s.start {
+		return 0
+	}
+	switch s.strategy {
+	case StrategyLowest:
+		return s.start
+	case StrategyHash:
+		return s.start + hashOffset(owner, s.end-s.start)
+	}
+	return 0
+}
+
+// pick returns the open port the strategy picks for owner, or zero when
+// there is none. It goes through the range from where the strategy starts,
+// wrapping around at its end. The caller must hold s.mu.
+func (s *MemoryStore) pick(owner string) int {
+	from := s.startOf(owner)
+	if from == 0 {
+		// map iteration order is randomized, which is as good as SPOP for us
+		for p := range s.open {
+			port, _ := strconv.Atoi(p)
+			return port
+		}
+		return 0
+	}
+	size := s.end - s.start
+	for i := 0; i < size; i++ {
+		port := s.start + (from-s.start+i)%size
+		if s.open[strconv.Itoa(port)] {
+			return port
+		}
+	}
+	return 0
+}
+
+// blockTaken reports whether the size ports from first on are all free, and
+// if not, who holds one of them. The owner is empty when a port is simply not
+// in the pool. The caller must hold s.mu.
+func (s *MemoryStore) blockTaken(first, size int) (owner string, free bool) {
+	for n := first; n < first+size; n++ {
+		port := strconv.Itoa(n)
+		if owner := s.holder(port); owner != "" {
+			return owner, false
+		}
+		if !s.open[port] {
+			return "", false
+		}
+	}
+	return "", true
+}
+
+// findBlock returns the first port of the lowest run of size free ports
+// beginning at from or above, or else of the lowest run overall. It returns
+// zero when there is none. The caller must hold s.mu.
+func (s *MemoryStore) findBlock(size, from int) int {
+	run := 0
+	prev := 0
+	lowest := 0
+	for _, port := range sortedPorts(s.open) {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		if run > 0 && n == prev+1 {
+			run++
+		} else {
+			run = 1
+		}
+		prev = n
+		if run >= size {
+			if n-size+1 >= from {
+				return n - size + 1
+			}
+			if lowest == 0 {
+				lowest = n - size + 1
+			}
+		}
+	}
+	return lowest
+}
+
+// holder returns the id whose assignment covers port, or an empty string.
+// The caller must hold s.mu.
+func (s *MemoryStore) holder(port string) string {
+	id, ok := s.port2i[port]
+	if !ok {
+		return ""
+	}
+	first, err := strconv.Atoi(s.i2port[id])
+	if err != nil {
+		return ""
+	}
+	size := s.blockSize(id)
+	n, err := strconv.Atoi(port)
+	if err != nil || n < first || n >= first+size {
+		return ""
+	}
+	return id
+}
+
+// blockSize returns the number of ports held by id. The caller must hold
+// s.mu.
+func (s *MemoryStore) blockSize(id string) int {
+	if size, err := strconv.Atoi(s.blocks[id]); err == nil {
+		return size
+	}
+	return 1
+}
+
+func (s *MemoryStore) GetAssignment(id string) (Assignment, error) {
+	if err := checkID(id); err != nil {
+		return Assignment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.assignment(id)
+}
+
+// assignment builds the Assignment for id. The caller must hold s.mu.
+func (s *MemoryStore) assignment(id string) (Assignment, error) {
+	a := Assignment{ID: id, Block: s.blockSize(id), Expires: s.leases[id], Labels: decodeLabels(s.labels[id])}
+	if names := s.namesOf(id); len(names) > 0 {
+		a.Named = make(map[string]int)
+		for _, name := range names {
+			a.Named[name], _ = strconv.Atoi(s.i2port[NamedID(id, name)])
+		}
+	}
+	port, ok := s.i2port[id]
+	if !ok {
+		return a, nil
+	}
+	var err error
+	a.Port, err = strconv.Atoi(port)
+	return a, err
+}
+
+func (s *MemoryStore) GetInstanceFromPort(port int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.port2i[strconv.Itoa(port)], nil
+}
+
+func (s *MemoryStore) GetOpenPortCount() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.open)), nil
+}
+
+func (s *MemoryStore) GetOpenPortList() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedPorts(s.open), nil
+}
+
+func (s *MemoryStore) GetReservedPortCount() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.assigned)), nil
+}
+
+func (s *MemoryStore) GetReservedPortList() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedPorts(s.assigned), nil
+}
+
+func (s *MemoryStore) GetCoolingPortCount() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.cooling)), nil
+}
+
+func (s *MemoryStore) GetCoolingPortList() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cooling := make(map[string]bool)
+	for port := range s.cooling {
+		cooling[port] = true
+	}
+	return sortedPorts(cooling), nil
+}
+
+// ListMappings pages through the ids in sorted order, the cursor being the
+// position in that order.
+func (s *MemoryStore) ListMappings(cursor uint64, match string, count int) (MappingPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.i2port))
+	for id := range s.i2port {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if count < 1 {
+		count = 10
+	}
+	page := MappingPage{Mappings: make(map[string]int), Labels: make(map[string]Labels)}
+	for i := cursor; i < uint64(len(ids)); i++ {
+		if i == cursor+uint64(count) {
+			page.Cursor = i
+			break
+		}
+		if len(match) == 0 || globMatch(match, ids[i]) {
+			page.Mappings[ids[i]], _ = strconv.Atoi(s.i2port[ids[i]])
+			if labels := decodeLabels(s.labels[serviceOf(ids[i])]); labels != nil {
+				page.Labels[ids[i]] = labels
+			}
+		}
+	}
+	return page, nil
+}
+
+func (s *MemoryStore) RemoveService(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.release(id, s.releasedAt(time.Now()))
+	return nil
+}
+
+func (s *MemoryStore) RemoveServices(ids []string) (map[string]bool, error) {
+	if err := checkIDs(ids); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	held := make(map[string]bool)
+	releasedAt := s.releasedAt(time.Now())
+	for _, id := range ids {
+		held[id] = s.release(id, releasedAt) || held[id]
+	}
+	return held, nil
+}
+
+// releasedAt returns the time ports released at t go into cooling with, the
+// zero time when there is no cooldown.
+func (s *MemoryStore) releasedAt(t time.Time) time.Time {
+	if s.cooldown <= 0 {
+		return time.Time{}
+	}
+	return t
+}
+
+// release unmaps id, along with its named ports, and returns its ports to the
+// pool, or to cooling when releasedAt isn't zero. It reports whether id held
+// anything. The caller must hold s.mu.
+func (s *MemoryStore) release(id string, releasedAt time.Time) bool {
+	names := s.namesOf(id)
+	for _, name := range names {
+		s.release(NamedID(id, name), releasedAt)
+	}
+	delete(s.names, id)
+	delete(s.labels, id)
+	delete(s.leases, id)
+	delete(s.durations, id)
+	port, ok := s.i2port[id]
+	if !ok {
+		return len(names) > 0
+	}
+	size := s.blockSize(id)
+	delete(s.i2port, id)
+	delete(s.blocks, id)
+	first, err := strconv.Atoi(port)
+	if err != nil {
+		return true
+	}
+	for n := first; n < first+size; n++ {
+		port := strconv.Itoa(n)
+		if s.port2i[port] == id {
+			delete(s.port2i, port)
+		}
+		delete(s.assigned, port)
+		switch {
+		case !s.inPool(port):
+		case !releasedAt.IsZero():
+			s.cooling[port] = releasedAt
+		default:
+			s.open[port] = true
+		}
+	}
+	return true
+}
+
+func (s *MemoryStore) RenewLease(id string, lease time.Duration) (Assignment, error) {
+	if err := checkID(id); err != nil {
+		return Assignment{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, named := s.names[id]
+	if _, ok := s.i2port[id]; !ok && !named {
+		return Assignment{}, ErrServiceNotFound
+	}
+	if lease == 0 {
+		lease = s.durations[id]
+	}
+	if lease == 0 {
+		return Assignment{}, ErrNoLease
+	}
+	s.leases[id] = time.Now().Add(lease)
+	s.durations[id] = lease
+	return s.assignment(id)
+}
+
+func (s *MemoryStore) ReapExpired(now time.Time) ([]Reaped, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promote(now)
+	var reaped []Reaped
+	for id, expires := range s.leases {
+		if !expires.After(now) {
+			s.release(id, s.releasedAt(now))
+			reaped = append(reaped, Reaped{ID: id})
+		}
+	}
+	for name, h := range s.hosts {
+		fromHost, _ := h.ReapExpired(now)
+		for _, r := range fromHost {
+			reaped = append(reaped, Reaped{ID: r.ID, Host: name})
+		}
+	}
+	return reaped, nil
+}
+
+func (s *MemoryStore) Quarantine(port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := strconv.Itoa(port)
+	_, cooling := s.cooling[p]
+	if s.open[p] || cooling {
+		delete(s.open, p)
+		delete(s.cooling, p)
+		s.quarantine[p] = time.Now()
+	}
+	return nil
+}
+
+func (s *MemoryStore) Unquarantine(port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := strconv.Itoa(port)
+	if _, ok := s.quarantine[p]; !ok {
+		return ErrNotQuarantined
+	}
+	delete(s.quarantine, p)
+	if s.inPool(p) && s.holder(p) == "" && !s.assigned[p] {
+		s.open[p] = true
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetQuarantine() ([]QuarantinedPort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var quarantined []QuarantinedPort
+	for p, since := range s.quarantine {
+		port, _ := strconv.Atoi(p)
+		quarantined = append(quarantined, QuarantinedPort{Port: port, Since: since})
+	}
+	sort.Slice(quarantined, func(i, j int) bool { return quarantined[i].Port < quarantined[j].Port })
+	return quarantined, nil
+}
+
+func (s *MemoryStore) Host(name string) (PortStore, error) {
+	if s.parent != nil {
+		return s.parent.Host(name)
+	}
+	if err := checkHost(name); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, ok := s.hosts[name]; ok {
+		return h, nil
+	}
+	if !s.initialized {
+		return nil, ErrNotInitialized
+	}
+	h := NewMemoryStore()
+	h.parent = s
+	h.cooldown = s.cooldown
+	h.strategy = s.strategy
+	if _, err := h.reconcile(s.start, s.end, s.excluded); err != nil {
+		return nil, err
+	}
+	s.hosts[name] = h
+	return h, nil
+}
+
+func (s *MemoryStore) LookupHost(name string) (PortStore, error) {
+	if s.parent != nil {
+		return s.parent.LookupHost(name)
+	}
+	if err := checkHost(name); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h, ok := s.hosts[name]; ok {
+		return h, nil
+	}
+	return nil, ErrHostNotFound
+}
+
+func (s *MemoryStore) Hosts() ([]string, error) {
+	if s.parent != nil {
+		return s.parent.Hosts()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hosts := make([]string, 0, len(s.hosts))
+	for name := range s.hosts {
+		hosts = append(hosts, name)
+	}
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+func (s *MemoryStore) Check(repair bool) ([]Inconsistency, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := make(map[string]bool)
+	for id := range s.leases {
+		leases[id] = true
+	}
+	quarantine := make(map[string]bool)
+	for port := range s.quarantine {
+		quarantine[port] = true
+	}
+	cooling := make(map[string]bool)
+	for port := range s.cooling {
+		cooling[port] = true
+	}
+	found := findInconsistencies(snapshot{
+		open:       s.open,
+		assigned:   s.assigned,
+		i2port:     s.i2port,
+		port2i:     s.port2i,
+		leases:     leases,
+		blocks:     s.blocks,
+		names:      s.names,
+		labels:     s.labels,
+		quarantine: quarantine,
+		cooling:    cooling,
+		start:      s.start,
+		end:        s.end,
+		excluded:   s.excluded,
+	})
+	if repair {
+		for _, f := range collectFixes(found) {
+			s.applyFix(f)
+		}
+	}
+	return found, nil
+}
+
+// applyFix makes the change described by f. The caller must hold s.mu.
+func (s *MemoryStore) applyFix(f fix) {
+	sets := map[string]map[string]bool{"open_ports": s.open, "assigned_ports": s.assigned}
+	hashes := map[string]map[string]string{"i2port": s.i2port, "port2i": s.port2i, "blocks": s.blocks, "names": s.names, "labels": s.labels}
+	switch f.op {
+	case fixSetAdd:
+		sets[f.key][f.field] = true
+	case fixSetRemove:
+		delete(sets[f.key], f.field)
+	case fixHashSet:
+		hashes[f.key][f.field] = f.value
+	case fixHashDelete:
+		delete(hashes[f.key], f.field)
+	case fixLeaseDelete:
+		delete(s.leases, f.field)
+		delete(s.durations, f.field)
+	case fixUnquarantine:
+		delete(s.quarantine, f.field)
+	case fixUncool:
+		delete(s.cooling, f.field)
+	}
+}
+
+// sortedPorts returns the members of set in numeric order.
+func sortedPorts(set map[string]bool) []string {
+	ports := make([]string, 0, len(set))
+	for p := range set {
+		ports = append(ports, p)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		a, _ := strconv.Atoi(ports[i])
+		b, _ := strconv.Atoi(ports[j])
+		return a < b
+	})
+	return ports
+}
+
+// globMatch reports whether name matches the glob pattern the way Redis'
+// MATCH does: * and ? match any run of characters and any one character,
+// [...] a character class, optionally negated with ^, and \ escapes the
+// next character.
+func globMatch(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if globMatch(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(name) == 0 {
+				return false
+			}
+		case '[':
+			if len(name) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				return pattern == name
+			}
+			class := pattern[1 : end+1]
+			negate := strings.HasPrefix(class, "^")
+			if negate {
+				class = class[1:]
+			}
+			matched := false
+			for i := 0; i < len(class); i++ {
+				if i+2 < len(class) && class[i+1] == '-' {
+					if name[0] >= class[i] && name[0] <= class[i+2] {
+						matched = true
+					}
+					i += 2
+				} else if class[i] == name[0] {
+					matched = true
+				}
+			}
+			if matched == negate {
+				return false
+			}
+			pattern = pattern[end+1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(name) == 0 || pattern[0] != name[0] {
+				return false
+			}
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,85 @@
+package actions
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newMemoryStore(t *testing.T) PortStore {
+	return NewMemoryStore()
+}
+
+func TestMemoryInitializePorts(t *testing.T) { testInitializePorts(t, newMemoryStore) }
+
+func TestMemoryAllocate(t *testing.T) { testAllocate(t, newMemoryStore) }
+
+func TestMemoryAllocateIdempotent(t *testing.T) { testAllocateIdempotent(t, newMemoryStore) }
+
+func TestMemoryRelease(t *testing.T) { testRelease(t, newMemoryStore) }
+
+func TestMemoryConcurrentAllocate(t *testing.T) { testConcurrentAllocate(t, newMemoryStore) }
+
+func TestMemoryAllocateWait(t *testing.T) { testAllocateWait(t, newMemoryStore) }
+
+func TestMemoryLeases(t *testing.T) { testLeases(t, newMemoryStore) }
+
+func TestMemoryReapExpired(t *testing.T) { testReapExpired(t, newMemoryStore) }
+
+func TestMemoryStartReaper(t *testing.T) { testStartReaper(t, newMemoryStore) }
+
+func TestMemoryLookupHost(t *testing.T) { testLookupHost(t, newMemoryStore) }
+
+// corruptMemory applies f to a MemoryStore.
+func corruptMemory(t *testing.T, s PortStore, f fix) {
+	m := s.(*MemoryStore)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.applyFix(f)
+}
+
+func TestMemoryCheck(t *testing.T) { testCheck(t, newMemoryStore, corruptMemory) }
+
+func TestMemoryCheckAssignedWithoutOwner(t *testing.T) {
+	testCheckAssignedWithoutOwner(t, newMemoryStore, corruptMemory)
+}
+
+func TestMemoryBlocks(t *testing.T) { testBlocks(t, newMemoryStore) }
+
+func TestMemoryNamedPorts(t *testing.T) { testNamedPorts(t, newMemoryStore) }
+
+func TestMemoryInvalidIDs(t *testing.T) { testInvalidIDs(t, newMemoryStore) }
+
+func TestMemoryExclusions(t *testing.T) { testExclusions(t, newMemoryStore) }
+
+func TestMemoryCooldown(t *testing.T) { testCooldown(t, newMemoryStore) }
+
+func TestMemoryStrategyLowest(t *testing.T) { testStrategyLowest(t, newMemoryStore) }
+
+func TestMemoryStrategyHash(t *testing.T) { testStrategyHash(t, newMemoryStore) }
+
+func TestMemorySelectors(t *testing.T) { testSelectors(t, newMemoryStore) }
+
+func TestMemoryListMappings(t *testing.T) { testListMappings(t, newMemoryStore) }
+
+func TestMemoryListMappingsCursor(t *testing.T) {
+	s := initialized(t, newMemoryStore, 7000, 7100, nil)
+	for i := 0; i < 20; i++ {
+		allocate(t, s, fmt.Sprintf("svc%02d", i), AllocateOptions{})
+	}
+	for cursor, want := range map[uint64]uint64{0: 7, 7: 14, 14: 0} {
+		page, err := s.ListMappings(cursor, "", 7)
+		if err != nil || page.Cursor != want {
+			t.Errorf("ListMappings(%d) = cursor %d, %v, want %d", cursor, page.Cursor, err, want)
+		}
+		first := fmt.Sprintf("svc%02d", cursor)
+		if _, ok := page.Mappings[first]; !ok || len(page.Mappings) > 7 {
+			t.Errorf("ListMappings(%d) = %v, want at most 7 ids from %s", cursor, page.Mappings, first)
+		}
+	}
+}
+
+func TestMemoryAllocateAll(t *testing.T) { testAllocateAll(t, newMemoryStore) }
+
+func TestMemoryAllocateAllRollback(t *testing.T) { testAllocateAllRollback(t, newMemoryStore) }
+
+func TestMemoryRemoveServices(t *testing.T) { testRemoveServices(t, newMemoryStore) }
@@ -0,0 +1,25 @@
+package actions
+
+import "sort"
+
+// DefaultPool is the name of the pool served by the original, pool-less API
+// routes and configured by the top level ports_begin and ports_end keys.
+const DefaultPool = "default"
+
+// Pools maps the name of each pool served by this instance to its store.
+type Pools map[string]PortStore
+
+// Default returns the store of the default pool.
+func (p Pools) Default() PortStore {
+	return p[DefaultPool]
+}
+
+// Names returns the names of all pools, sorted.
+func (p Pools) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,199 @@
+package actions
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+// maxProbeAttempts is how many assignments ProbingStore tries before giving
+// up on finding ports which aren't busy.
+const maxProbeAttempts = 5
+
+// Prober tells whether something outside of port-authority already uses a
+// port.
+type Prober interface {
+	Busy(port int) bool
+}
+
+// BindProber considers a port busy when it can't be bound on Address, the
+// interface of the server host the services run on. An empty Address means
+// all interfaces.
+type BindProber struct {
+	Address string
+}
+
+func (p BindProber) Busy(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort(p.Address, strconv.Itoa(port)))
+	if err != nil {
+		return true
+	}
+	l.Close()
+	return false
+}
+
+// DialProber considers a port busy when something accepts TCP connections on
+// it at Host, for when the services don't run on the server host.
+type DialProber struct {
+	Host    string
+	Timeout time.Duration
+}
+
+func (p DialProber) Busy(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(p.Host, strconv.Itoa(port)), p.Timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// NewProber returns the prober of the given kind, "bind" or "dial", probing
+// address. The kind "none" returns a nil Prober.
+func NewProber(kind, address string) (Prober, error) {
+	switch kind {
+	case "none", "":
+		return nil, nil
+	case "bind":
+		return BindProber{Address: address}, nil
+	case "dial":
+		if len(address) == 0 {
+			return nil, fmt.Errorf("The dial prober needs an address to probe")
+		}
+		return DialProber{Host: address, Timeout: time.Second}, nil
+	}
+	return nil, fmt.Errorf("Unknown prober '%s'", kind)
+}
+
+// ProbingStore is a PortStore which probes the ports of every new assignment
+// before handing them out. Busy ports are quarantined and the assignment is
+// retried with other ports.
+type ProbingStore struct {
+	PortStore
+	Prober Prober
+}
+
+// NewProbingStore wraps store so its new assignments are probed with prober.
+func NewProbingStore(store PortStore, prober Prober) *ProbingStore {
+	return &ProbingStore{PortStore: store, Prober: prober}
+}
+
+// Allocate assigns ports like the wrapped store does, but quarantines and
+// replaces those the prober finds busy. Ports of an assignment the service
+// already holds aren't probed, as the service itself is likely using them.
+func (s *ProbingStore) Allocate(id string, opts AllocateOptions) (Assignment, error) {
+	held, err := s.PortStore.GetAssignment(id)
+	if err != nil {
+		return held, err
+	}
+	if !held.Empty() {
+		return s.PortStore.Allocate(id, opts)
+	}
+	for attempt := 0; attempt < maxProbeAttempts; attempt++ {
+		a, err := s.PortStore.Allocate(id, opts)
+		if err != nil {
+			return a, err
+		}
+		var busy []int
+		for _, port := range a.Ports() {
+			if s.Prober.Busy(port) {
+				busy = append(busy, port)
+			}
+		}
+		if len(busy) == 0 {
+			return a, nil
+		}
+		if err := s.PortStore.RemoveService(id); err != nil {
+			return Assignment{}, err
+		}
+		for _, port := range busy {
+			log.Printf("Port %d is in use outside of port-authority, quarantining it", port)
+			if err := s.PortStore.Quarantine(port); err != nil {
+				return Assignment{}, err
+			}
+		}
+		if opts.Port != 0 && !opts.Prefer {
+			return Assignment{}, ErrPortUnavailable
+		}
+	}
+	return Assignment{}, ErrPortsBusy
+}
+
+// AllocateAll makes the allocations like the wrapped store does, then probes
+// the ports of the services which held none before. When some of them are
+// busy those services are released, the busy ports quarantined and the
+// allocations retried.
+func (s *ProbingStore) AllocateAll(reqs []AllocateRequest) (map[string]Assignment, error) {
+	fresh := make(map[string]bool)
+	for _, req := range reqs {
+		held, err := s.PortStore.GetAssignment(req.ID)
+		if err != nil {
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+		if held.Empty() {
+			fresh[req.ID] = true
+		}
+	}
+	for attempt := 0; attempt < maxProbeAttempts; attempt++ {
+		assigned, err := s.PortStore.AllocateAll(reqs)
+		if err != nil {
+			return assigned, err
+		}
+		var busy []int
+		var unavailable string
+		for _, req := range reqs {
+			if !fresh[req.ID] {
+				continue
+			}
+			for _, port := range assigned[req.ID].Ports() {
+				if s.Prober.Busy(port) {
+					busy = append(busy, port)
+					if req.Options.Port != 0 && !req.Options.Prefer && len(unavailable) == 0 {
+						unavailable = req.ID
+					}
+				}
+			}
+		}
+		if len(busy) == 0 {
+			return assigned, nil
+		}
+		ids := make([]string, 0, len(fresh))
+		for id := range fresh {
+			ids = append(ids, id)
+		}
+		if _, err := s.PortStore.RemoveServices(ids); err != nil {
+			return nil, err
+		}
+		for _, port := range busy {
+			log.Printf("Port %d is in use outside of port-authority, quarantining it", port)
+			if err := s.PortStore.Quarantine(port); err != nil {
+				return nil, err
+			}
+		}
+		if len(unavailable) > 0 {
+			return nil, &BulkError{ID: unavailable, Err: ErrPortUnavailable}
+		}
+	}
+	return nil, ErrPortsBusy
+}
+
+// Host returns the store of the named host, probing its new assignments too.
+func (s *ProbingStore) Host(name string) (PortStore, error) {
+	h, err := s.PortStore.Host(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewProbingStore(h, s.Prober), nil
+}
+
+// LookupHost returns the store of a known host, probing its new assignments
+// too.
+func (s *ProbingStore) LookupHost(name string) (PortStore, error) {
+	h, err := s.PortStore.LookupHost(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewProbingStore(h, s.Prober), nil
+}
@@ -0,0 +1,124 @@
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+// busyPorts is a Prober finding the ports it holds busy, counting the
+// probes.
+type busyPorts struct {
+	ports  map[int]bool
+	probes int
+}
+
+func (p *busyPorts) Busy(port int) bool {
+	p.probes++
+	return p.ports[port]
+}
+
+// probing returns a ProbingStore over a memory store holding the ports from
+// 7000 up to 7010, handing out the lowest free port first.
+func probing(t *testing.T, busy ...int) (*ProbingStore, *busyPorts) {
+	t.Helper()
+	m := NewMemoryStore()
+	m.SetStrategy(StrategyLowest)
+	if err := m.InitializePorts(7000, 7010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	p := &busyPorts{ports: make(map[int]bool)}
+	for _, port := range busy {
+		p.ports[port] = true
+	}
+	return NewProbingStore(m, p), p
+}
+
+// checkQuarantined fails the test unless exactly ports are quarantined in s.
+func checkQuarantined(t *testing.T, s PortStore, ports ...int) {
+	t.Helper()
+	q, err := s.GetQuarantine()
+	if err != nil {
+		t.Fatalf("GetQuarantine(): %v", err)
+	}
+	var got []int
+	for _, qp := range q {
+		got = append(got, qp.Port)
+	}
+	if !reflect.DeepEqual(got, ports) {
+		t.Errorf("GetQuarantine() = %v, want %v", got, ports)
+	}
+}
+
+func TestProbingStoreAllocate(t *testing.T) {
+	s, p := probing(t, 7000, 7001)
+	a := allocate(t, s, "web", AllocateOptions{})
+	if a.Port != 7002 {
+		t.Errorf("Allocate() past busy ports = port %d, want 7002", a.Port)
+	}
+	if p.probes != 3 {
+		t.Errorf("Allocate() probed %d times, want 3", p.probes)
+	}
+	checkQuarantined(t, s, 7000, 7001)
+	checkOwner(t, s, 7000, "")
+	checkOwner(t, s, 7001, "")
+	checkOwner(t, s, 7002, "web")
+	checkClean(t, s)
+
+	// The ports a service holds aren't probed again.
+	p.ports[7002] = true
+	p.probes = 0
+	if again := allocate(t, s, "web", AllocateOptions{}); again.Port != 7002 || p.probes != 0 {
+		t.Errorf("Allocate() again = port %d with %d probes, want port 7002 unprobed", again.Port, p.probes)
+	}
+}
+
+func TestProbingStoreAllocatePort(t *testing.T) {
+	s, _ := probing(t, 7005, 7006)
+	if _, err := s.Allocate("db", AllocateOptions{Port: 7005}); err != ErrPortUnavailable {
+		t.Errorf("Allocate() of a busy port = %v, want ErrPortUnavailable", err)
+	}
+	if a, err := s.GetAssignment("db"); err != nil || !a.Empty() {
+		t.Errorf("GetAssignment() after a busy port = %+v, %v, want nothing", a, err)
+	}
+	checkQuarantined(t, s, 7005)
+
+	a := allocate(t, s, "db", AllocateOptions{Port: 7006, Prefer: true})
+	if a.Port != 7000 {
+		t.Errorf("Allocate() preferring a busy port = port %d, want 7000", a.Port)
+	}
+	checkQuarantined(t, s, 7005, 7006)
+	checkClean(t, s)
+}
+
+func TestProbingStoreAllocateBusy(t *testing.T) {
+	s, p := probing(t, 7000, 7001, 7002, 7003, 7004, 7005, 7006, 7007, 7008, 7009)
+	if _, err := s.Allocate("web", AllocateOptions{}); err != ErrPortsBusy {
+		t.Errorf("Allocate() with every port busy = %v, want ErrPortsBusy", err)
+	}
+	if p.probes != maxProbeAttempts {
+		t.Errorf("Allocate() probed %d times, want %d", p.probes, maxProbeAttempts)
+	}
+	if a, err := s.GetAssignment("web"); err != nil || !a.Empty() {
+		t.Errorf("GetAssignment() after giving up = %+v, %v, want nothing", a, err)
+	}
+	checkQuarantined(t, s, 7000, 7001, 7002, 7003, 7004)
+	checkCounts(t, s, 5, 0)
+	checkClean(t, s)
+}
+
+func TestProbingStoreAllocateAll(t *testing.T) {
+	s, p := probing(t)
+	allocate(t, s, "db", AllocateOptions{})
+	p.ports[7000] = true
+	p.ports[7001] = true
+	assigned, err := s.AllocateAll([]AllocateRequest{{ID: "db"}, {ID: "web"}, {ID: "cache"}})
+	if err != nil {
+		t.Fatalf("AllocateAll(): %v", err)
+	}
+	got := map[string]int{"db": assigned["db"].Port, "web": assigned["web"].Port, "cache": assigned["cache"].Port}
+	if want := map[string]int{"db": 7000, "web": 7002, "cache": 7003}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AllocateAll() past a busy port = %v, want %v", got, want)
+	}
+	checkQuarantined(t, s, 7001)
+	checkClean(t, s)
+}
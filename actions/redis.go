@@ -0,0 +1,787 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/therealbill/libredis/client"
+)
+
+// RedisStore is a PortStore which keeps its data in Redis.
+type RedisStore struct {
+	conn *client.Redis
+	// prefix namespaces the keys of every pool but the default one, which
+	// keeps the bare key names it has always used.
+	prefix string
+	// parent is the store of the pool a host store belongs to, nil for the
+	// store of a pool.
+	parent *RedisStore
+	// cooldown is how long released ports wait before they can be assigned
+	// again.
+	cooldown time.Duration
+	// strategy picks the ports of new assignments.
+	strategy Strategy
+}
+
+// NewRedisStore connects to the Redis server at address and returns a
+// PortStore backed by it.
+func NewRedisStore(address, auth string) (*RedisStore, error) {
+	conn, err := client.DialWithConfig(&client.DialConfig{Address: address, Password: auth})
+	if err != nil {
+		log.Print("Failed NewRedisStore with err: ", err.Error())
+		return nil, err
+	}
+	return &RedisStore{conn: conn}, nil
+}
+
+// Connection returns the underlying Redis connection.
+func (s *RedisStore) Connection() *client.Redis {
+	return s.conn
+}
+
+// Pool returns a RedisStore for the named pool sharing this store's
+// connection, cooldown and strategy. Its keys are prefixed with
+// "pool:NAME:".
+func (s *RedisStore) Pool(name string) *RedisStore {
+	if name == DefaultPool {
+		return &RedisStore{conn: s.conn, cooldown: s.cooldown, strategy: s.strategy}
+	}
+	return &RedisStore{conn: s.conn, prefix: fmt.Sprintf("pool:%s:", name), cooldown: s.cooldown, strategy: s.strategy}
+}
+
+// SetCooldown makes released ports wait for d before they can be assigned
+// again. They are kept in the cooling sorted set meanwhile. Pools and hosts
+// opened afterwards inherit the cooldown.
+func (s *RedisStore) SetCooldown(d time.Duration) {
+	s.cooldown = d
+}
+
+// SetStrategy makes the store pick the ports of new assignments with st.
+// Pools and hosts opened afterwards inherit the strategy.
+func (s *RedisStore) SetStrategy(st Strategy) {
+	s.strategy = st
+}
+
+// key returns the name of the given key in this store's pool.
+func (s *RedisStore) key(name string) string {
+	return s.prefix + name
+}
+
+func (s *RedisStore) InitializePorts(start, end int, excluded []Exclusion) error {
+	rc := s.conn
+	for _, key := range []string{s.key("open_ports"), s.key("pool_range")} {
+		exists, err := rc.Exists(key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadyInitialized
+		}
+	}
+	_, err := s.Reconcile(start, end, excluded)
+	if err != nil {
+		return err
+	}
+	added, err := rc.SCard(s.key("open_ports"))
+	needed := end - start - countExcluded(excluded, start, end)
+	if added != int64(needed) {
+		errm := fmt.Sprintf("Needed %d ports initialized, got %d", needed, added)
+		log.Print(errm)
+		return fmt.Errorf("%s", errm)
+	}
+	return nil
+}
+
+// Reconcile makes start to end the range of the pool, see PortStore.
+func (s *RedisStore) Reconcile(start, end int, excluded []Exclusion) (ReconcileReport, error) {
+	report := ReconcileReport{Start: start, End: end, Excluded: excluded}
+	if end <= start {
+		return report, ErrInvalidRange
+	}
+	reply, err := s.eval(reconcileScript, s.keys(), strconv.Itoa(start), strconv.Itoa(end), FormatExclusions(excluded))
+	if err != nil {
+		return report, err
+	}
+	if err = s.eachHost(func(h *RedisStore) error {
+		_, err := h.Reconcile(start, end, excluded)
+		return err
+	}); err != nil {
+		return report, err
+	}
+	parts, err := reply.MultiValue()
+	if err != nil {
+		return report, err
+	}
+	if len(parts) != 3 {
+		return report, fmt.Errorf("Unexpected reply from reconcile script with %d parts", len(parts))
+	}
+	if report.Added, err = parts[0].ListValue(); err != nil {
+		return report, err
+	}
+	if report.Removed, err = parts[1].ListValue(); err != nil {
+		return report, err
+	}
+	stranded, err := parts[2].ListValue()
+	if err != nil {
+		return report, err
+	}
+	report.Stranded = make(map[string]string)
+	for i := 0; i+1 < len(stranded); i += 2 {
+		report.Stranded[stranded[i]] = stranded[i+1]
+	}
+	return report, nil
+}
+
+// GetRange returns the range the pool was last initialized or reconciled
+// with.
+func (s *RedisStore) GetRange() (start, end int, err error) {
+	values, err := s.conn.HMGet(s.key("pool_range"), "begin", "end")
+	if err != nil || len(values) != 2 || len(values[0]) == 0 || len(values[1]) == 0 {
+		return 0, 0, err
+	}
+	if start, err = strconv.Atoi(string(values[0])); err != nil {
+		return 0, 0, err
+	}
+	end, err = strconv.Atoi(string(values[1]))
+	return start, end, err
+}
+
+// GetExclusions returns the exclusions the pool was last initialized or
+// reconciled with.
+func (s *RedisStore) GetExclusions() ([]Exclusion, error) {
+	spec, err := s.conn.HGet(s.key("pool_range"), "excluded")
+	if err != nil {
+		return nil, err
+	}
+	return ParseExclusions(string(spec))
+}
+
+// Allocate assigns the requested port, or one picked by the store's strategy,
+// to iname, or returns the one it already holds. The whole operation runs as
+// a single script on the server so concurrent callers can never interleave.
+func (s *RedisStore) Allocate(iname string, opts AllocateOptions) (Assignment, error) {
+	args, err := allocateArgs(iname, opts)
+	if err != nil {
+		return Assignment{}, err
+	}
+	reply, err := s.eval(allocateScript, s.keys(), append(s.allocateGlobals(), args...)...)
+	if err != nil {
+		if err := allocateError(iname, opts, err.Error()); err != nil {
+			return Assignment{}, err
+		}
+		log.Printf("Error allocating a port for '%s': %v", iname, err)
+		return Assignment{}, err
+	}
+	return assignmentFromReply(iname, reply)
+}
+
+// AllocateAll makes all the allocations in a single script, see PortStore.
+func (s *RedisStore) AllocateAll(reqs []AllocateRequest) (map[string]Assignment, error) {
+	args := s.allocateGlobals()
+	for _, req := range reqs {
+		reqArgs, err := allocateArgs(req.ID, req.Options)
+		if err != nil {
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+		args = append(args, reqArgs...)
+	}
+	assigned := make(map[string]Assignment)
+	if len(reqs) == 0 {
+		return assigned, nil
+	}
+	reply, err := s.eval(bulkAllocateScript, s.keys(), args...)
+	if err != nil {
+		parts := strings.SplitN(err.Error(), " ", 2)
+		n, convErr := strconv.Atoi(parts[0])
+		if convErr != nil || n < 1 || n > len(reqs) || len(parts) < 2 {
+			log.Printf("Error allocating ports for %d services: %v", len(reqs), err)
+			return nil, err
+		}
+		req := reqs[n-1]
+		if err := allocateError(req.ID, req.Options, parts[1]); err != nil {
+			return nil, &BulkError{ID: req.ID, Err: err}
+		}
+		return nil, &BulkError{ID: req.ID, Err: errors.New(parts[1])}
+	}
+	replies, err := reply.MultiValue()
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) != len(reqs) {
+		return nil, fmt.Errorf("Unexpected reply from bulk allocation with %d assignments for %d services", len(replies), len(reqs))
+	}
+	for i, req := range reqs {
+		if assigned[req.ID], err = assignmentFromReply(req.ID, replies[i]); err != nil {
+			return nil, err
+		}
+	}
+	return assigned, nil
+}
+
+// allocateGlobals returns the arguments allocateScript and
+// bulkAllocateScript take ahead of the allocations: the cooldown cutoff and
+// the strategy.
+func (s *RedisStore) allocateGlobals() []string {
+	cutoff := strconv.FormatInt(toMillis(time.Now().Add(-s.cooldown)), 10)
+	return []string{cutoff, string(s.strategy)}
+}
+
+// allocateArgs returns the arguments describing a single allocation to
+// allocateScript and bulkAllocateScript, or the error when opts are invalid.
+func allocateArgs(id string, opts AllocateOptions) ([]string, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	if err := checkNames(opts.Names); err != nil {
+		return nil, err
+	}
+	if err := checkLabels(opts.Labels); err != nil {
+		return nil, err
+	}
+	var expires int64
+	if opts.Lease > 0 {
+		expires = toMillis(time.Now().Add(opts.Lease))
+	}
+	var want, required string
+	if opts.Port != 0 {
+		want = strconv.Itoa(opts.Port)
+		if !opts.Prefer {
+			required = "1"
+		}
+	}
+	size := opts.Block
+	if size < 1 {
+		size = 1
+	}
+	return []string{id, durationMillis(opts.Lease), strconv.FormatInt(expires, 10), want, required,
+		strconv.Itoa(size), strings.Join(opts.Names, ","), encodeLabels(opts.Labels)}, nil
+}
+
+// allocateError turns the error reply msg of allocating id with opts into
+// the matching error, or nil when it isn't one of the allocation errors.
+func allocateError(id string, opts AllocateOptions, msg string) error {
+	switch {
+	case strings.HasPrefix(msg, "EXHAUSTED"):
+		return ErrPoolExhausted
+	case strings.HasPrefix(msg, "NOBLOCK"):
+		return ErrNoBlock
+	case strings.HasPrefix(msg, "UNAVAILABLE"):
+		return ErrPortUnavailable
+	case strings.HasPrefix(msg, "KIND"):
+		return ErrAssignmentKind
+	case strings.HasPrefix(msg, "CONFLICT "):
+		return &PortConflictError{ID: id, Port: opts.Port, Owner: strings.TrimPrefix(msg, "CONFLICT ")}
+	case strings.HasPrefix(msg, "HOLDS "):
+		held, _ := strconv.Atoi(strings.TrimPrefix(msg, "HOLDS "))
+		return &PortConflictError{ID: id, Port: opts.Port, Owner: id, Held: held}
+	}
+	return nil
+}
+
+// GetAssignment returns the ports and lease held by id.
+func (s *RedisStore) GetAssignment(id string) (Assignment, error) {
+	if err := checkID(id); err != nil {
+		return Assignment{ID: id}, err
+	}
+	reply, err := s.eval(lookupScript, s.keys(), id)
+	if err != nil {
+		return Assignment{ID: id}, err
+	}
+	return assignmentFromReply(id, reply)
+}
+
+func (s *RedisStore) GetInstanceFromPort(port int) (iname string, err error) {
+	id, err := s.conn.HGet(s.key("port2i"), fmt.Sprintf("%d", port))
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (s *RedisStore) GetOpenPortCount() (int64, error) {
+	return s.conn.SCard(s.key("open_ports"))
+}
+
+func (s *RedisStore) GetOpenPortList() (ports []string, err error) {
+	return s.conn.SMembers(s.key("open_ports"))
+}
+
+func (s *RedisStore) GetReservedPortCount() (int64, error) {
+	return s.conn.SCard(s.key("assigned_ports"))
+}
+
+func (s *RedisStore) GetReservedPortList() (ports []string, err error) {
+	return s.conn.SMembers(s.key("assigned_ports"))
+}
+
+func (s *RedisStore) GetCoolingPortCount() (int64, error) {
+	return s.conn.ZCard(s.key("cooling"))
+}
+
+func (s *RedisStore) GetCoolingPortList() (ports []string, err error) {
+	return s.conn.ZRange(s.key("cooling"), 0, -1, false)
+}
+
+// ListMappings pages through i2port with HSCAN, so like it a page may
+// list an id again which was updated while paging. The labels are read for
+// the whole page at once.
+func (s *RedisStore) ListMappings(cursor uint64, match string, count int) (MappingPage, error) {
+	next, pairs, err := s.conn.HScan(s.key("i2port"), cursor, match, count)
+	if err != nil {
+		return MappingPage{}, err
+	}
+	page := MappingPage{Mappings: make(map[string]int, len(pairs)), Labels: make(map[string]Labels), Cursor: next}
+	ids := make([]string, 0, len(pairs))
+	for id, port := range pairs {
+		page.Mappings[id], _ = strconv.Atoi(port)
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return page, nil
+	}
+	services := make([]string, len(ids))
+	for i, id := range ids {
+		services[i] = serviceOf(id)
+	}
+	labels, err := s.conn.HMGet(s.key("labels"), services...)
+	if err != nil {
+		return page, err
+	}
+	for i, encoded := range labels {
+		if l := decodeLabels(string(encoded)); l != nil {
+			page.Labels[ids[i]] = l
+		}
+	}
+	return page, nil
+}
+
+// RemoveService releases the ports held by id back into open_ports, or into
+// cooling when there is a cooldown. Like Allocate it is a single server-side
+// operation.
+func (s *RedisStore) RemoveService(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	_, err := s.eval(releaseScript, s.keys(), id, s.releasedAt(time.Now()))
+	if err != nil {
+		log.Printf("Error releasing the port for '%s': %v", id, err)
+	}
+	return err
+}
+
+// RemoveServices releases all of ids in a single script, see PortStore.
+func (s *RedisStore) RemoveServices(ids []string) (map[string]bool, error) {
+	if err := checkIDs(ids); err != nil {
+		return nil, err
+	}
+	held := make(map[string]bool)
+	if len(ids) == 0 {
+		return held, nil
+	}
+	reply, err := s.eval(bulkReleaseScript, s.keys(), append([]string{s.releasedAt(time.Now())}, ids...)...)
+	if err != nil {
+		log.Printf("Error releasing the ports of %d services: %v", len(ids), err)
+		return nil, err
+	}
+	flags, err := reply.MultiValue()
+	if err != nil {
+		return nil, err
+	}
+	for i, flag := range flags {
+		if i < len(ids) {
+			n, _ := flag.IntegerValue()
+			held[ids[i]] = held[ids[i]] || n == 1
+		}
+	}
+	return held, nil
+}
+
+// RenewLease extends the lease held by id, see PortStore.
+func (s *RedisStore) RenewLease(id string, lease time.Duration) (Assignment, error) {
+	if err := checkID(id); err != nil {
+		return Assignment{}, err
+	}
+	now := strconv.FormatInt(toMillis(time.Now()), 10)
+	reply, err := s.eval(renewScript, s.keys(), id, durationMillis(lease), now)
+	if err != nil {
+		switch {
+		case isScriptError(err, "NOTFOUND"):
+			return Assignment{}, ErrServiceNotFound
+		case isScriptError(err, "NOLEASE"):
+			return Assignment{}, ErrNoLease
+		}
+		return Assignment{}, err
+	}
+	return assignmentFromReply(id, reply)
+}
+
+// ReapExpired releases every service whose lease ran out before now, after
+// moving the ports which cooled down back to open_ports. It works in batches
+// so a large backlog doesn't block Redis for long.
+func (s *RedisStore) ReapExpired(now time.Time) ([]Reaped, error) {
+	const batch = 100
+	var reaped []Reaped
+	cutoff := strconv.FormatInt(toMillis(now.Add(-s.cooldown)), 10)
+	if _, err := s.eval(promoteScript, s.keys(), cutoff); err != nil {
+		return nil, err
+	}
+	for {
+		reply, err := s.eval(reapScript, s.keys(), strconv.FormatInt(toMillis(now), 10), strconv.Itoa(batch), s.releasedAt(now))
+		if err != nil {
+			return reaped, err
+		}
+		ids, err := reply.ListValue()
+		if err != nil {
+			return reaped, err
+		}
+		for _, id := range ids {
+			reaped = append(reaped, Reaped{ID: id})
+		}
+		if len(ids) < batch {
+			break
+		}
+	}
+	err := s.eachHost(func(h *RedisStore) error {
+		fromHost, err := h.ReapExpired(now)
+		for _, r := range fromHost {
+			reaped = append(reaped, Reaped{ID: r.ID, Host: h.host()})
+		}
+		return err
+	})
+	return reaped, err
+}
+
+// Quarantine takes port out of open_ports, see PortStore.
+func (s *RedisStore) Quarantine(port int) error {
+	_, err := s.eval(quarantineScript, s.keys(), strconv.Itoa(port), strconv.FormatInt(toMillis(time.Now()), 10))
+	return err
+}
+
+// Unquarantine puts port back into open_ports, see PortStore.
+func (s *RedisStore) Unquarantine(port int) error {
+	_, err := s.eval(unquarantineScript, s.keys(), strconv.Itoa(port))
+	if err != nil && isScriptError(err, "NOTQUARANTINED") {
+		return ErrNotQuarantined
+	}
+	return err
+}
+
+// GetQuarantine lists the quarantined ports along with when they were
+// quarantined.
+func (s *RedisStore) GetQuarantine() ([]QuarantinedPort, error) {
+	reply, err := s.eval(quarantineListScript, s.keys())
+	if err != nil {
+		return nil, err
+	}
+	values, err := reply.ListValue()
+	if err != nil {
+		return nil, err
+	}
+	var quarantined []QuarantinedPort
+	for i := 0; i+1 < len(values); i += 2 {
+		port, err := strconv.Atoi(values[i])
+		if err != nil {
+			continue
+		}
+		since, err := parseExpiry(values[i+1])
+		if err != nil {
+			return nil, err
+		}
+		quarantined = append(quarantined, QuarantinedPort{Port: port, Since: since})
+	}
+	sort.Slice(quarantined, func(i, j int) bool { return quarantined[i].Port < quarantined[j].Port })
+	return quarantined, nil
+}
+
+// releasedAt returns the score ports released at t go into cooling with,
+// empty when there is no cooldown.
+func (s *RedisStore) releasedAt(t time.Time) string {
+	if s.cooldown <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(toMillis(t), 10)
+}
+
+// Host returns the store of the named host in this pool, see PortStore. Its
+// keys are prefixed with "host:NAME:" within the pool, which lists its hosts
+// in the hosts set.
+func (s *RedisStore) Host(name string) (PortStore, error) {
+	if s.parent != nil {
+		return s.parent.Host(name)
+	}
+	if err := checkHost(name); err != nil {
+		return nil, err
+	}
+	h := s.hostStore(name)
+	exists, err := s.conn.Exists(h.key("pool_range"))
+	if err != nil || exists {
+		return h, err
+	}
+	start, end, err := s.GetRange()
+	if err != nil {
+		return nil, err
+	}
+	if start == 0 && end == 0 {
+		return nil, ErrNotInitialized
+	}
+	excluded, err := s.GetExclusions()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.conn.SAdd(s.key("hosts"), name); err != nil {
+		return nil, err
+	}
+	if _, err = h.Reconcile(start, end, excluded); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// LookupHost returns the store of a host of this pool, see PortStore.
+func (s *RedisStore) LookupHost(name string) (PortStore, error) {
+	if s.parent != nil {
+		return s.parent.LookupHost(name)
+	}
+	if err := checkHost(name); err != nil {
+		return nil, err
+	}
+	h := s.hostStore(name)
+	exists, err := s.conn.Exists(h.key("pool_range"))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrHostNotFound
+	}
+	return h, nil
+}
+
+// Hosts returns the hosts of the pool, see PortStore.
+func (s *RedisStore) Hosts() ([]string, error) {
+	if s.parent != nil {
+		return s.parent.Hosts()
+	}
+	hosts, err := s.conn.SMembers(s.key("hosts"))
+	sort.Strings(hosts)
+	return hosts, err
+}
+
+// hostStore returns the store of the named host without initializing it.
+func (s *RedisStore) hostStore(name string) *RedisStore {
+	return &RedisStore{conn: s.conn, prefix: fmt.Sprintf("%shost:%s:", s.prefix, name), parent: s, cooldown: s.cooldown, strategy: s.strategy}
+}
+
+// host returns the name of the host of a host store.
+func (s *RedisStore) host() string {
+	name := strings.TrimPrefix(s.prefix, s.parent.prefix+"host:")
+	return strings.TrimSuffix(name, ":")
+}
+
+// eachHost runs fn on the store of every host of a pool store, stopping at
+// the first error. It does nothing for a host store.
+func (s *RedisStore) eachHost(fn func(h *RedisStore) error) error {
+	if s.parent != nil {
+		return nil
+	}
+	hosts, err := s.Hosts()
+	if err != nil {
+		return err
+	}
+	for _, name := range hosts {
+		if err := fn(s.hostStore(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check compares the data model structures with one another, see
+// findInconsistencies. Repairs are applied by a single script, but the pool
+// may have changed between reading it and repairing it, so repair is best
+// run while the pool is quiet.
+func (s *RedisStore) Check(repair bool) ([]Inconsistency, error) {
+	snap, err := s.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	found := findInconsistencies(snap)
+	if !repair || len(found) == 0 {
+		return found, nil
+	}
+	var args []string
+	for _, f := range collectFixes(found) {
+		args = append(args, string(f.op), f.key, f.field, f.value)
+	}
+	_, err = s.eval(fixScript, s.keys(), args...)
+	return found, err
+}
+
+// snapshot reads the whole data model.
+func (s *RedisStore) snapshot() (snapshot, error) {
+	snap := snapshot{
+		open:       make(map[string]bool),
+		assigned:   make(map[string]bool),
+		i2port:     make(map[string]string),
+		port2i:     make(map[string]string),
+		leases:     make(map[string]bool),
+		blocks:     make(map[string]string),
+		names:      make(map[string]string),
+		quarantine: make(map[string]bool),
+		cooling:    make(map[string]bool),
+		labels:     make(map[string]string),
+	}
+	reply, err := s.eval(snapshotScript, s.keys())
+	if err != nil {
+		return snap, err
+	}
+	parts, err := reply.MultiValue()
+	if err != nil {
+		return snap, err
+	}
+	if len(parts) != 11 {
+		return snap, fmt.Errorf("Unexpected reply from snapshot script with %d parts", len(parts))
+	}
+	lists := make([][]string, len(parts))
+	for i, part := range parts {
+		if lists[i], err = part.ListValue(); err != nil {
+			return snap, err
+		}
+	}
+	for _, port := range lists[0] {
+		snap.open[port] = true
+	}
+	for _, port := range lists[1] {
+		snap.assigned[port] = true
+	}
+	for i := 0; i+1 < len(lists[2]); i += 2 {
+		snap.i2port[lists[2][i]] = lists[2][i+1]
+	}
+	for i := 0; i+1 < len(lists[3]); i += 2 {
+		snap.port2i[lists[3][i]] = lists[3][i+1]
+	}
+	for _, id := range lists[4] {
+		snap.leases[id] = true
+	}
+	for i := 0; i+1 < len(lists[5]); i += 2 {
+		snap.blocks[lists[5][i]] = lists[5][i+1]
+	}
+	for i := 0; i+1 < len(lists[6]); i += 2 {
+		snap.names[lists[6][i]] = lists[6][i+1]
+	}
+	for _, port := range lists[7] {
+		snap.quarantine[port] = true
+	}
+	for _, port := range lists[8] {
+		snap.cooling[port] = true
+	}
+	for i := 0; i+1 < len(lists[9]); i += 2 {
+		snap.labels[lists[9][i]] = lists[9][i+1]
+	}
+	poolRange := make(map[string]string)
+	for i := 0; i+1 < len(lists[10]); i += 2 {
+		poolRange[lists[10][i]] = lists[10][i+1]
+	}
+	snap.start, _ = strconv.Atoi(poolRange["begin"])
+	snap.end, _ = strconv.Atoi(poolRange["end"])
+	if snap.excluded, err = ParseExclusions(poolRange["excluded"]); err != nil {
+		return snap, err
+	}
+	return snap, nil
+}
+
+// keys returns the keys making up the data model, in the order the scripts
+// expect them.
+func (s *RedisStore) keys() []string {
+	return []string{
+		s.key("open_ports"), s.key("assigned_ports"), s.key("i2port"), s.key("port2i"),
+		s.key("leases"), s.key("lease_durations"), s.key("pool_range"), s.key("blocks"),
+		s.key("names"), s.key("quarantine"), s.key("cooling"), s.key("labels"),
+	}
+}
+
+// replyStrings unpacks the array of strings a script returned.
+func replyStrings(reply *client.Reply) ([]string, error) {
+	multi, err := reply.MultiValue()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(multi))
+	for i, r := range multi {
+		if values[i], err = r.StringValue(); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// assignmentFromReply builds an Assignment from the port, expiry, size and
+// name, port pairs returned by the allocate, renew and lookup scripts. An
+// empty port means the service holds no single port.
+func assignmentFromReply(id string, reply *client.Reply) (Assignment, error) {
+	a := Assignment{ID: id}
+	values, err := replyStrings(reply)
+	if err != nil {
+		return a, err
+	}
+	if len(values) < 4 || len(values)%2 != 0 {
+		return a, fmt.Errorf("Unexpected reply from script: %v", values)
+	}
+	if len(values[0]) > 0 {
+		if a.Port, err = strconv.Atoi(values[0]); err != nil {
+			return a, err
+		}
+	}
+	if a.Block, err = strconv.Atoi(values[2]); err != nil {
+		return a, err
+	}
+	a.Labels = decodeLabels(values[3])
+	if len(values) > 4 {
+		a.Named = make(map[string]int)
+		for i := 4; i+1 < len(values); i += 2 {
+			if a.Named[values[i]], err = strconv.Atoi(values[i+1]); err != nil {
+				return a, err
+			}
+		}
+	}
+	a.Expires, err = parseExpiry(values[1])
+	return a, err
+}
+
+// parseExpiry turns a lease score into a time, an empty score meaning no
+// lease at all.
+func parseExpiry(score string) (time.Time, error) {
+	if len(score) == 0 {
+		return time.Time{}, nil
+	}
+	ms, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fromMillis(int64(ms)), nil
+}
+
+// durationMillis formats d as the millisecond count the scripts expect.
+func durationMillis(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
+
+// eval runs script, only shipping its source when the server doesn't have it
+// cached yet.
+func (s *RedisStore) eval(script *luaScript, keys []string, args ...string) (*client.Reply, error) {
+	reply, err := s.conn.EvalSha(script.sha, keys, args)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		return s.conn.Eval(script.src, keys, args)
+	}
+	return reply, err
+}
+
+// isScriptError reports whether err is the error_reply a script raised with
+// the given code.
+func isScriptError(err error, code string) bool {
+	return strings.HasPrefix(err.Error(), code)
+}
@@ -0,0 +1,151 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/therealbill/port-authority/common"
+)
+
+// newRedisStore returns a RedisStore talking to a fresh miniredis server,
+// which is shut down at the end of the test.
+func newRedisStore(t *testing.T) PortStore {
+	return newMiniRedisStore(t)
+}
+
+// newMiniRedisStore is newRedisStore for tests needing the *RedisStore.
+func newMiniRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+	m := miniredis.RunT(t)
+	s, err := NewRedisStore(m.Addr(), "")
+	if err != nil {
+		t.Fatalf("NewRedisStore(): %v", err)
+	}
+	return s
+}
+
+func TestRedisInitializePorts(t *testing.T) { testInitializePorts(t, newRedisStore) }
+
+func TestRedisAllocate(t *testing.T) { testAllocate(t, newRedisStore) }
+
+func TestRedisAllocateIdempotent(t *testing.T) { testAllocateIdempotent(t, newRedisStore) }
+
+func TestRedisRelease(t *testing.T) { testRelease(t, newRedisStore) }
+
+func TestRedisConcurrentAllocate(t *testing.T) { testConcurrentAllocate(t, newRedisStore) }
+
+func TestRedisAllocateWait(t *testing.T) { testAllocateWait(t, newRedisStore) }
+
+func TestRedisPoolKeys(t *testing.T) {
+	s := newMiniRedisStore(t)
+	pool := s.Pool("blue")
+	if err := pool.InitializePorts(7000, 7010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	if exists, err := s.conn.Exists("open_ports"); err != nil || exists {
+		t.Errorf("open_ports exists = %v, %v, want the default pool left alone", exists, err)
+	}
+	if n, err := s.conn.SCard("pool:blue:open_ports"); err != nil || n != 10 {
+		t.Errorf("SCARD pool:blue:open_ports = %d, %v, want 10", n, err)
+	}
+	a := allocate(t, pool, "web", AllocateOptions{})
+	checkOwner(t, pool, a.Port, "web")
+	checkOwner(t, s, a.Port, "")
+}
+
+func TestRedisEvents(t *testing.T) {
+	s := newMiniRedisStore(t)
+	sink := NewEventLog()
+	stop := make(chan struct{})
+	defer close(stop)
+	go NewRedisEvents(s.Pool("blue")).Listen(sink, stop)
+
+	// Listen may not have subscribed yet, so record until an event is seen.
+	deadline := time.Now().Add(2 * time.Second)
+	for sink.LastID() == 0 && time.Now().Before(deadline) {
+		for _, pool := range []string{DefaultPool, "blue"} {
+			if err := NewRedisEvents(s.Pool(pool)).Record(common.Event{ID: 7, Name: EventReleased, Data: map[string]string{"pool": pool}}); err != nil {
+				t.Fatalf("Record(): %v", err)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	events := sink.Since(0)
+	if len(events) == 0 {
+		t.Fatalf("Listen() recorded no events of the blue pool")
+	}
+	for i, e := range events {
+		if e.Data["pool"] != "blue" || e.ID != int64(i+1) {
+			t.Errorf("Listen() recorded %+v, want the events of the blue pool numbered by the log", e)
+		}
+	}
+	reply, err := s.conn.ExecuteCommand("EXISTS", "event_id", "pool:blue:event_id")
+	if err != nil {
+		t.Fatalf("EXISTS: %v", err)
+	}
+	if n, err := reply.IntegerValue(); err != nil || n != 0 {
+		t.Errorf("EXISTS event_id = %d, %v, want Record() to store nothing", n, err)
+	}
+}
+
+func TestRedisLeases(t *testing.T) { testLeases(t, newRedisStore) }
+
+func TestRedisReapExpired(t *testing.T) { testReapExpired(t, newRedisStore) }
+
+func TestRedisStartReaper(t *testing.T) { testStartReaper(t, newRedisStore) }
+
+func TestRedisLookupHost(t *testing.T) { testLookupHost(t, newRedisStore) }
+
+// corruptRedis applies f to a RedisStore with the script repairs use.
+func corruptRedis(t *testing.T, s PortStore, f fix) {
+	t.Helper()
+	rs := s.(*RedisStore)
+	if _, err := rs.eval(fixScript, rs.keys(), string(f.op), f.key, f.field, f.value); err != nil {
+		t.Fatalf("Applying %+v: %v", f, err)
+	}
+}
+
+func TestRedisCheck(t *testing.T) { testCheck(t, newRedisStore, corruptRedis) }
+
+func TestRedisCheckAssignedWithoutOwner(t *testing.T) {
+	testCheckAssignedWithoutOwner(t, newRedisStore, corruptRedis)
+}
+
+func TestRedisBlocks(t *testing.T) { testBlocks(t, newRedisStore) }
+
+func TestRedisNamedPorts(t *testing.T) { testNamedPorts(t, newRedisStore) }
+
+func TestRedisInvalidIDs(t *testing.T) { testInvalidIDs(t, newRedisStore) }
+
+func TestRedisExclusions(t *testing.T) { testExclusions(t, newRedisStore) }
+
+func TestRedisCooldown(t *testing.T) { testCooldown(t, newRedisStore) }
+
+func TestRedisStrategyLowest(t *testing.T) { testStrategyLowest(t, newRedisStore) }
+
+func TestRedisStrategyHash(t *testing.T) { testStrategyHash(t, newRedisStore) }
+
+func TestRedisPoolStrategy(t *testing.T) {
+	s := newMiniRedisStore(t)
+	s.SetStrategy(StrategyLowest)
+	pool := s.Pool("blue")
+	if err := pool.InitializePorts(7000, 7010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	for i, id := range []string{"a", "b"} {
+		if a := allocate(t, pool, id, AllocateOptions{}); a.Port != 7000+i {
+			t.Errorf("Allocate(%q) in a pool = port %d, want %d", id, a.Port, 7000+i)
+		}
+	}
+}
+
+func TestRedisSelectors(t *testing.T) { testSelectors(t, newRedisStore) }
+
+func TestRedisListMappings(t *testing.T) { testListMappings(t, newRedisStore) }
+
+func TestRedisAllocateAll(t *testing.T) { testAllocateAll(t, newRedisStore) }
+
+func TestRedisAllocateAllRollback(t *testing.T) { testAllocateAllRollback(t, newRedisStore) }
+
+func TestRedisRemoveServices(t *testing.T) { testRemoveServices(t, newRedisStore) }
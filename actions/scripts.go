@@ -0,0 +1,624 @@
+package actions
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// luaScript is a Lua script run server-side by the RedisStore, along with the
+// SHA1 Redis knows it by once loaded.
+type luaScript struct {
+	src string
+	sha string
+}
+
+func newLuaScript(src string) *luaScript {
+	sum := sha1.Sum([]byte(src))
+	return &luaScript{src: src, sha: hex.EncodeToString(sum[:])}
+}
+
+// All scripts take the data model keys in this order:
+//   KEYS[1] open_ports, KEYS[2] assigned_ports, KEYS[3] i2port, KEYS[4] port2i,
+//   KEYS[5] leases, KEYS[6] lease_durations, KEYS[7] pool_range,
+//   KEYS[8] blocks, KEYS[9] names, KEYS[10] quarantine, KEYS[11] cooling,
+//   KEYS[12] labels
+//
+// leases is a sorted set of ids scored by the millisecond timestamp their
+// lease expires at, lease_durations holds the lease length in milliseconds
+// so a renewal can reuse it. pool_range is a hash holding the begin and end
+// of the range the pool was last reconciled with, and under excluded the
+// comma separated ports and START-END runs of ports left out of it. blocks
+// holds the size of every assignment of more than one port; i2port maps those
+// to their first port and port2i maps each of their ports. names holds the
+// comma separated port names of every service holding named ports, each of
+// which is mapped as the id ID/NAME. The lease of such a service is held by
+// ID. quarantine is a sorted set of the ports found in use outside of
+// port-authority, scored by the millisecond timestamp they were quarantined
+// at. cooling is a sorted set of released ports waiting out the cooldown
+// before they go back into open_ports, scored by the millisecond timestamp
+// they were released at. labels holds the labels of a service as a JSON
+// object.
+
+// inPoolFunc defines inPool(port), which reports whether port lies within
+// pool_range and isn't excluded. Every port does while the range is unknown.
+const inPoolFunc = `
+local function inPool(port)
+	local range = redis.call('HMGET', KEYS[7], 'begin', 'end', 'excluded')
+	local begin, finish, n = tonumber(range[1]), tonumber(range[2]), tonumber(port)
+	if not begin or not finish then
+		return true
+	end
+	if n == nil or n < begin or n >= finish then
+		return false
+	end
+	for first, last in string.gmatch(range[3] or '', '(%d+)-?(%d*)') do
+		if n >= tonumber(first) and n <= (tonumber(last) or tonumber(first)) then
+			return false
+		end
+	end
+	return true
+end
+`
+
+// holderFunc defines holder(port), which returns the id whose assignment
+// covers port, or nil. Unlike a plain port2i lookup it ignores entries left
+// behind by ids which no longer hold the port.
+const holderFunc = `
+local function holder(port)
+	local id = redis.call('HGET', KEYS[4], port)
+	if not id then
+		return nil
+	end
+	local first = tonumber(redis.call('HGET', KEYS[3], id))
+	local size = tonumber(redis.call('HGET', KEYS[8], id) or '1')
+	local n = tonumber(port)
+	if first and n and n >= first and n < first + size then
+		return id
+	end
+	return nil
+end
+`
+
+// namesFunc defines namesOf(id), which returns the list of port names held
+// by id, and assignment(id), which returns what id holds as the port (empty
+// for none), the lease expiry (empty for no lease), the number of ports, the
+// labels (empty for none) and a name, port pair for every named port.
+const namesFunc = `
+local function namesOf(id)
+	local names = {}
+	local list = redis.call('HGET', KEYS[9], id)
+	if list then
+		for name in string.gmatch(list, '[^,]+') do
+			table.insert(names, name)
+		end
+	end
+	return names
+end
+
+local function assignment(id)
+	local reply = {redis.call('HGET', KEYS[3], id) or '', redis.call('ZSCORE', KEYS[5], id) or '', redis.call('HGET', KEYS[8], id) or '1', redis.call('HGET', KEYS[12], id) or ''}
+	for _, name in ipairs(namesOf(id)) do
+		table.insert(reply, name)
+		table.insert(reply, redis.call('HGET', KEYS[3], id .. '/' .. name) or '')
+	end
+	return reply
+end
+`
+
+// coolFunc defines promote(cutoff), which moves the ports released at or
+// before cutoff from cooling back to open_ports, unless they have since left
+// the pool or been assigned. It needs inPoolFunc and holderFunc.
+const coolFunc = `
+local function promote(cutoff)
+	for _, port in ipairs(redis.call('ZRANGEBYSCORE', KEYS[11], '-inf', cutoff)) do
+		redis.call('ZREM', KEYS[11], port)
+		if inPool(port) and not holder(port) and redis.call('SISMEMBER', KEYS[2], port) == 0 then
+			redis.call('SADD', KEYS[1], port)
+		end
+	end
+end
+`
+
+// releaseFunc defines release(id, releasedAt), which unmaps id, along with
+// its named ports, and returns its ports to open_ports, unless they have since
+// fallen out of the pool's range or been excluded. Its labels are dropped.
+// When releasedAt isn't empty the ports go to cooling with that score
+// instead. It returns the released (first) port, true when id held named
+// ports only, or false when id held nothing. It needs inPoolFunc and
+// namesFunc.
+const releaseFunc = `
+local function release(id, releasedAt)
+	local names = namesOf(id)
+	for _, name in ipairs(names) do
+		release(id .. '/' .. name, releasedAt)
+	end
+	redis.call('HDEL', KEYS[9], id)
+	redis.call('HDEL', KEYS[12], id)
+	local port = redis.call('HGET', KEYS[3], id)
+	redis.call('ZREM', KEYS[5], id)
+	redis.call('HDEL', KEYS[6], id)
+	if not port then
+		return #names > 0
+	end
+	local size = tonumber(redis.call('HGET', KEYS[8], id) or '1')
+	redis.call('HDEL', KEYS[3], id)
+	redis.call('HDEL', KEYS[8], id)
+	local first = tonumber(port)
+	if not first then
+		return port
+	end
+	for n = first, first + size - 1 do
+		local p = tostring(n)
+		if redis.call('HGET', KEYS[4], p) == id then
+			redis.call('HDEL', KEYS[4], p)
+		end
+		redis.call('SREM', KEYS[2], p)
+		if inPool(p) and releasedAt ~= '' then
+			redis.call('ZADD', KEYS[11], releasedAt, p)
+		elseif inPool(p) then
+			redis.call('SADD', KEYS[1], p)
+		end
+	end
+	return port
+end
+`
+
+// allocFunc defines allocate(id, lease, expires, want, required, size,
+// wanted, strategy, labels), which returns the assignment held by id, or
+// assigns it size ports, moving them from open_ports to assigned_ports and
+// mapping them in the hashes. The (first) port is want if that is set and
+// free, otherwise any unless required is true, which makes want a hard
+// requirement. Otherwise ports are picked with the strategy, see Strategy:
+// blocks are the lowest run of contiguous open ports, or for the hash
+// strategy the first run from the hashed port on. When wanted is a comma
+// separated list of names a port is assigned to every name id doesn't hold yet
+// instead, all or none of them. Ports found in open_ports which are held are
+// put back into assigned_ports and skipped rather than handed out twice. When
+// lease is a non-zero length in milliseconds the lease is set to expire at
+// expires. When labels isn't empty it replaces the labels, an empty object
+// removing them. It returns the assignment, see namesFunc, or an error reply
+// without having changed anything. It needs holderFunc and namesFunc.
+const allocFunc = `
+-- bounds returns the begin and end of pool_range, nil while unknown.
+local function bounds()
+	local range = redis.call('HMGET', KEYS[7], 'begin', 'end')
+	return tonumber(range[1]), tonumber(range[2])
+end
+
+-- start returns the port the strategy starts looking for a free port for
+-- owner at, or nil for a random port. See hashOffset for the hash.
+local function start(owner, strategy)
+	local begin, finish = bounds()
+	if (strategy ~= 'lowest' and strategy ~= 'hash') or not begin or not finish or finish <= begin then
+		return nil
+	end
+	if strategy == 'hash' then
+		return begin + tonumber(string.sub(redis.sha1hex(owner), 1, 8), 16) % (finish - begin)
+	end
+	return begin
+end
+
+-- blockTaken returns nil when the size ports from first on are all free,
+-- otherwise the id holding one of them or '' when one is not in the pool.
+local function blockTaken(first, size)
+	for n = first, first + size - 1 do
+		local port = tostring(n)
+		local owner = holder(port)
+		if owner then
+			return owner
+		end
+		if redis.call('SISMEMBER', KEYS[1], port) == 0 then
+			return ''
+		end
+	end
+	return nil
+end
+
+-- findBlock returns the first port of the lowest run of size free ports
+-- beginning at from or above, or else of the lowest run overall.
+local function findBlock(size, from)
+	local ports = {}
+	for _, port in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+		local n = tonumber(port)
+		if n and not holder(port) then
+			table.insert(ports, n)
+		end
+	end
+	table.sort(ports)
+	local run, lowest = 0, nil
+	for i, n in ipairs(ports) do
+		if i > 1 and n == ports[i - 1] + 1 then
+			run = run + 1
+		else
+			run = 1
+		end
+		if run >= size then
+			if n - size + 1 >= from then
+				return n - size + 1
+			end
+			lowest = lowest or n - size + 1
+		end
+	end
+	return lowest
+end
+
+-- take moves port out of open_ports and returns it, or moves it to
+-- assigned_ports and returns nil when it is held.
+local function take(port)
+	redis.call('SREM', KEYS[1], port)
+	if holder(port) then
+		redis.call('SADD', KEYS[2], port)
+		return nil
+	end
+	return port
+end
+
+-- pop takes the free port the strategy picks for owner out of open_ports, or
+-- returns nil. It goes through the range from where the strategy starts,
+-- wrapping around at its end.
+local function pop(owner, strategy)
+	local from = start(owner, strategy)
+	if not from then
+		repeat
+			local port = redis.call('SPOP', KEYS[1])
+			if not port then
+				return nil
+			end
+			if not holder(port) then
+				return port
+			end
+			redis.call('SADD', KEYS[2], port)
+		until false
+	end
+	local begin, finish = bounds()
+	for i = 0, finish - begin - 1 do
+		local port = tostring(begin + (from - begin + i) % (finish - begin))
+		if redis.call('SISMEMBER', KEYS[1], port) == 1 and take(port) then
+			return port
+		end
+	end
+	return nil
+end
+
+-- assign maps owner to the size ports from first on.
+local function assign(owner, first, size)
+	for n = tonumber(first), tonumber(first) + size - 1 do
+		local p = tostring(n)
+		redis.call('SREM', KEYS[1], p)
+		redis.call('SADD', KEYS[2], p)
+		redis.call('HSET', KEYS[4], p, owner)
+	end
+	redis.call('HSET', KEYS[3], owner, first)
+	if size > 1 then
+		redis.call('HSET', KEYS[8], owner, tostring(size))
+	end
+end
+
+local function allocate(id, lease, expires, want, required, size, wanted, strategy, labels)
+	local port = redis.call('HGET', KEYS[3], id)
+	local named = redis.call('HEXISTS', KEYS[9], id) == 1
+	if wanted ~= '' then
+		if port then
+			return redis.error_reply('KIND ' .. id .. ' holds a single port')
+		end
+		local names, held, missing = namesOf(id), {}, {}
+		for _, name in ipairs(names) do
+			held[name] = true
+		end
+		for name in string.gmatch(wanted, '[^,]+') do
+			if not held[name] then
+				held[name] = true
+				table.insert(names, name)
+				table.insert(missing, name)
+			end
+		end
+		local popped = {}
+		for i = 1, #missing do
+			popped[i] = pop(id .. '/' .. missing[i], strategy)
+			if not popped[i] then
+				for _, p in ipairs(popped) do
+					redis.call('SADD', KEYS[1], p)
+				end
+				return redis.error_reply('EXHAUSTED no open ports left')
+			end
+		end
+		for i, name in ipairs(missing) do
+			assign(id .. '/' .. name, popped[i], 1)
+		end
+		redis.call('HSET', KEYS[9], id, table.concat(names, ','))
+	elseif named then
+		if required then
+			return redis.error_reply('KIND ' .. id .. ' holds named ports')
+		end
+	elseif port and required and port ~= want then
+		return redis.error_reply('HOLDS ' .. port)
+	elseif not port then
+		if want ~= '' then
+			local taken = blockTaken(tonumber(want), size)
+			if not taken then
+				port = want
+			elseif required and taken ~= '' then
+				return redis.error_reply('CONFLICT ' .. taken)
+			elseif required then
+				return redis.error_reply('UNAVAILABLE port ' .. want .. ' is not available')
+			end
+		end
+		if not port and size > 1 then
+			local first = findBlock(size, start(id, strategy) or 0)
+			if not first then
+				return redis.error_reply('NOBLOCK no run of ' .. size .. ' open ports left')
+			end
+			port = tostring(first)
+		end
+		if not port then
+			port = pop(id, strategy)
+			if not port then
+				return redis.error_reply('EXHAUSTED no open ports left')
+			end
+		end
+		assign(id, port, size)
+	end
+	if tonumber(lease) > 0 then
+		redis.call('ZADD', KEYS[5], expires, id)
+		redis.call('HSET', KEYS[6], id, lease)
+	end
+	if labels == '{}' then
+		redis.call('HDEL', KEYS[12], id)
+	elseif labels ~= '' then
+		redis.call('HSET', KEYS[12], id, labels)
+	end
+	return assignment(id)
+end
+`
+
+// allocateScript allocates ARGV[3], see allocFunc, with ARGV[4] to ARGV[10]
+// as the lease, expires, want, required ("1" for true), size, names and
+// labels, and ARGV[2] as the strategy. Ports which cooled down by ARGV[1] are
+// promoted first.
+var allocateScript = newLuaScript(inPoolFunc + holderFunc + namesFunc + coolFunc + allocFunc + `
+redis.replicate_commands()
+promote(ARGV[1])
+return allocate(ARGV[3], ARGV[4], ARGV[5], ARGV[6], ARGV[7] == '1', tonumber(ARGV[8]), ARGV[9], ARGV[2], ARGV[10])
+`)
+
+// bulkAllocateScript allocates a list of ids all or nothing. Like for
+// allocateScript ARGV[1] is the cooldown cutoff and ARGV[2] the strategy, but
+// ARGV[3] to ARGV[10], ARGV[11] to ARGV[18] and so on each hold one
+// allocation. When an allocation fails the ids assigned ports by those
+// before it are released again, the ids which held ports already get back
+// their names, labels and lease, and its error is returned prefixed with its
+// position in the list, counting from 1. Otherwise it returns the
+// assignments in order.
+var bulkAllocateScript = newLuaScript(inPoolFunc + holderFunc + namesFunc + coolFunc + releaseFunc + allocFunc + `
+-- save returns what allocate may change of an id holding ports already,
+-- which restore puts back, releasing the named ports given to the id since.
+-- put sets the field id of the hash key to value, or deletes it when false.
+local function save(id)
+	return {id = id, names = redis.call('HGET', KEYS[9], id), labels = redis.call('HGET', KEYS[12], id), expires = redis.call('ZSCORE', KEYS[5], id), lease = redis.call('HGET', KEYS[6], id)}
+end
+
+local function put(key, id, value)
+	if value then
+		redis.call('HSET', key, id, value)
+	else
+		redis.call('HDEL', key, id)
+	end
+end
+
+local function restore(saved)
+	local had = {}
+	for name in string.gmatch(saved.names or '', '[^,]+') do
+		had[name] = true
+	end
+	for _, name in ipairs(namesOf(saved.id)) do
+		if not had[name] then
+			release(saved.id .. '/' .. name, '')
+		end
+	end
+	put(KEYS[9], saved.id, saved.names)
+	put(KEYS[12], saved.id, saved.labels)
+	put(KEYS[6], saved.id, saved.lease)
+	if saved.expires then
+		redis.call('ZADD', KEYS[5], saved.expires, saved.id)
+	else
+		redis.call('ZREM', KEYS[5], saved.id)
+	end
+end
+
+redis.replicate_commands()
+promote(ARGV[1])
+local replies, created, saved = {}, {}, {}
+for i = 3, #ARGV, 8 do
+	local id = ARGV[i]
+	local new = redis.call('HEXISTS', KEYS[3], id) == 0 and redis.call('HEXISTS', KEYS[9], id) == 0
+	if not new then
+		table.insert(saved, save(id))
+	end
+	local reply = allocate(id, ARGV[i + 1], ARGV[i + 2], ARGV[i + 3], ARGV[i + 4] == '1', tonumber(ARGV[i + 5]), ARGV[i + 6], ARGV[2], ARGV[i + 7])
+	if reply.err then
+		for j = #saved, 1, -1 do
+			restore(saved[j])
+		end
+		for _, c in ipairs(created) do
+			release(c, '')
+		end
+		return redis.error_reply(string.format('%d %s', (i - 3) / 8 + 1, reply.err))
+	end
+	if new then
+		table.insert(created, id)
+	end
+	table.insert(replies, reply)
+end
+return replies
+`)
+
+// bulkReleaseScript releases ARGV[2] and on at ARGV[1], see releaseFunc. It
+// returns for each of them whether it held anything, 1 or 0.
+var bulkReleaseScript = newLuaScript(inPoolFunc + namesFunc + releaseFunc + `
+local held = {}
+for i = 2, #ARGV do
+	held[i - 1] = release(ARGV[i], ARGV[1]) and 1 or 0
+end
+return held
+`)
+
+// releaseScript releases ARGV[1] at ARGV[2], see releaseFunc.
+var releaseScript = newLuaScript(inPoolFunc + namesFunc + releaseFunc + `
+return release(ARGV[1], ARGV[2])
+`)
+
+// renewScript extends the lease of ARGV[1] to ARGV[3] plus the lease length,
+// which is ARGV[2] or, when that is zero, the length it was last given. It
+// returns the assignment, see namesFunc.
+var renewScript = newLuaScript(namesFunc + `
+local id = ARGV[1]
+if redis.call('HEXISTS', KEYS[3], id) == 0 and redis.call('HEXISTS', KEYS[9], id) == 0 then
+	return redis.error_reply('NOTFOUND no port is assigned to ' .. id)
+end
+local lease = tonumber(ARGV[2])
+if lease == 0 then
+	lease = tonumber(redis.call('HGET', KEYS[6], id) or '0')
+end
+if lease == 0 then
+	return redis.error_reply('NOLEASE ' .. id .. ' has no lease to renew')
+end
+local expires = string.format('%.0f', tonumber(ARGV[3]) + lease)
+redis.call('ZADD', KEYS[5], expires, id)
+redis.call('HSET', KEYS[6], id, string.format('%.0f', lease))
+return assignment(id)
+`)
+
+// lookupScript returns the assignment held by ARGV[1], see namesFunc.
+var lookupScript = newLuaScript(namesFunc + `
+return assignment(ARGV[1])
+`)
+
+// reapScript releases, at ARGV[3], up to ARGV[2] ids whose lease expired at
+// or before ARGV[1] and returns them.
+var reapScript = newLuaScript(inPoolFunc + namesFunc + releaseFunc + `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[5], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, id in ipairs(ids) do
+	release(id, ARGV[3])
+end
+return ids
+`)
+
+// promoteScript moves the ports released at or before ARGV[1] from cooling
+// back to open_ports.
+var promoteScript = newLuaScript(inPoolFunc + holderFunc + coolFunc + `
+promote(ARGV[1])
+return true
+`)
+
+// snapshotScript returns the content of every data model structure, read at
+// a single point in time. The hashes are returned as flat field/value lists.
+var snapshotScript = newLuaScript(`
+return {
+	redis.call('SMEMBERS', KEYS[1]),
+	redis.call('SMEMBERS', KEYS[2]),
+	redis.call('HGETALL', KEYS[3]),
+	redis.call('HGETALL', KEYS[4]),
+	redis.call('ZRANGE', KEYS[5], 0, -1),
+	redis.call('HGETALL', KEYS[8]),
+	redis.call('HGETALL', KEYS[9]),
+	redis.call('ZRANGE', KEYS[10], 0, -1),
+	redis.call('ZRANGE', KEYS[11], 0, -1),
+	redis.call('HGETALL', KEYS[12]),
+	redis.call('HGETALL', KEYS[7])
+}
+`)
+
+// fixScript applies the repairs passed in ARGV as op, key, field, value
+// quadruples, see fix.
+var fixScript = newLuaScript(`
+local keys = {open_ports = KEYS[1], assigned_ports = KEYS[2], i2port = KEYS[3], port2i = KEYS[4], blocks = KEYS[8], names = KEYS[9], labels = KEYS[12]}
+for i = 1, #ARGV, 4 do
+	local op, key, field, value = ARGV[i], keys[ARGV[i + 1]], ARGV[i + 2], ARGV[i + 3]
+	if op == 'sadd' then
+		redis.call('SADD', key, field)
+	elseif op == 'srem' then
+		redis.call('SREM', key, field)
+	elseif op == 'hset' then
+		redis.call('HSET', key, field, value)
+	elseif op == 'hdel' then
+		redis.call('HDEL', key, field)
+	elseif op == 'unlease' then
+		redis.call('ZREM', KEYS[5], field)
+		redis.call('HDEL', KEYS[6], field)
+	elseif op == 'unquarantine' then
+		redis.call('ZREM', KEYS[10], field)
+	elseif op == 'uncool' then
+		redis.call('ZREM', KEYS[11], field)
+	end
+end
+return true
+`)
+
+// reconcileScript sets pool_range to ARGV[1] up to ARGV[2] with the
+// exclusions ARGV[3], adds every port in it which is neither free, held,
+// excluded, quarantined nor cooling to open_ports and drops free ports
+// outside of it or excluded. It returns the added ports, the dropped ports
+// and a flat port, id list of held ports outside the range or excluded.
+var reconcileScript = newLuaScript(inPoolFunc + holderFunc + `
+local begin, finish = tonumber(ARGV[1]), tonumber(ARGV[2])
+redis.call('HSET', KEYS[7], 'begin', ARGV[1])
+redis.call('HSET', KEYS[7], 'end', ARGV[2])
+redis.call('HSET', KEYS[7], 'excluded', ARGV[3])
+
+-- tracked reports whether port is free, assigned, quarantined or cooling.
+local function tracked(port)
+	return redis.call('SISMEMBER', KEYS[1], port) == 1 or redis.call('SISMEMBER', KEYS[2], port) == 1
+		or holder(port) ~= nil or redis.call('ZSCORE', KEYS[10], port) or redis.call('ZSCORE', KEYS[11], port)
+end
+
+local added, removed, stranded = {}, {}, {}
+for n = begin, finish - 1 do
+	local port = tostring(n)
+	if inPool(port) and not tracked(port) then
+		redis.call('SADD', KEYS[1], port)
+		table.insert(added, port)
+	end
+end
+for _, port in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	if not inPool(port) then
+		redis.call('SREM', KEYS[1], port)
+		table.insert(removed, port)
+	end
+end
+for _, port in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	if not inPool(port) then
+		table.insert(stranded, port)
+		table.insert(stranded, holder(port) or '')
+	end
+end
+return {added, removed, stranded}
+`)
+
+// quarantineScript moves port ARGV[1] from open_ports or cooling to
+// quarantine, scored ARGV[2]. It returns 0 when the port wasn't free.
+var quarantineScript = newLuaScript(`
+if redis.call('SREM', KEYS[1], ARGV[1]) == 0 and redis.call('ZREM', KEYS[11], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[10], ARGV[2], ARGV[1])
+return 1
+`)
+
+// unquarantineScript takes port ARGV[1] out of quarantine and puts it back
+// into open_ports, unless it has since left the pool or been assigned.
+var unquarantineScript = newLuaScript(inPoolFunc + holderFunc + `
+local port = ARGV[1]
+if redis.call('ZREM', KEYS[10], port) == 0 then
+	return redis.error_reply('NOTQUARANTINED port ' .. port .. ' is not quarantined')
+end
+if inPool(port) and not holder(port) and redis.call('SISMEMBER', KEYS[2], port) == 0 then
+	redis.call('SADD', KEYS[1], port)
+end
+return 1
+`)
+
+// quarantineListScript returns the quarantined ports as a flat port, score
+// list.
+var quarantineListScript = newLuaScript(`
+return redis.call('ZRANGE', KEYS[10], 0, -1, 'WITHSCORES')
+`)
@@ -3,200 +3,352 @@ package actions
 import (
 	"errors"
 	"fmt"
-	"log"
-	"strconv"
-
-	"github.com/therealbill/libredis/client"
+	"sort"
+	"strings"
+	"time"
 )
 
 var (
-	rediscon         *client.Redis
-	redisInitialized bool
-	eventexpiration  int
+	// eventexpiration is how many seconds an EventLog keeps events for.
+	eventexpiration = 600
+
+	// ErrAlreadyInitialized is returned by InitializePorts when the backend
+	// already holds a pool.
+	ErrAlreadyInitialized = errors.New("The backend has already been initialized, so I won't re-initialize it")
+
+	// ErrPoolExhausted is returned when there is no open port left to hand out.
+	ErrPoolExhausted = errors.New("No open ports left in the pool")
+
+	// ErrServiceNotFound is returned when an operation needs an existing
+	// assignment and the service holds none.
+	ErrServiceNotFound = errors.New("No port is assigned to that service")
+
+	// ErrNoLease is returned when renewing a service which was never given a
+	// lease without saying how long the new one should be.
+	ErrNoLease = errors.New("The service has no lease to renew")
+
+	// ErrInvalidRange is returned when a pool's range ends before it begins.
+	ErrInvalidRange = errors.New("The end of the port range must be above its beginning")
+
+	// ErrNoBlock is returned when no run of contiguous open ports is large
+	// enough for a block allocation.
+	ErrNoBlock = errors.New("No run of contiguous open ports that large is left")
+
+	// ErrPortUnavailable is returned when a requested port is neither free
+	// nor held, e.g. because it is outside of the pool's range or still
+	// cooling down.
+	ErrPortUnavailable = errors.New("The requested port is not available in the pool")
+
+	// ErrAssignmentKind is returned when named ports are requested for a
+	// service holding a single port or block, or a specific port for one
+	// holding named ports.
+	ErrAssignmentKind = errors.New("The service already holds a different kind of assignment, release it first")
+
+	// ErrInvalidHost is returned for a host name which is empty or contains
+	// a colon or whitespace.
+	ErrInvalidHost = errors.New("Host names must be non-empty and can't contain ':' or whitespace")
+
+	// ErrHostNotFound is returned when looking up a host which no port was
+	// ever allocated on.
+	ErrHostNotFound = errors.New("The pool has no such host")
+
+	// ErrNotInitialized is returned when a host store is needed before the
+	// pool it belongs to was given a range.
+	ErrNotInitialized = errors.New("The pool has not been initialized yet")
+
+	// ErrPortsBusy is returned when every port tried for a new assignment was
+	// found in use outside of port-authority.
+	ErrPortsBusy = errors.New("Every port tried is in use outside of port-authority")
+
+	// ErrNotQuarantined is returned when releasing a port from quarantine
+	// which isn't quarantined.
+	ErrNotQuarantined = errors.New("The port is not quarantined")
+
+	// ErrInvalidID is returned for a service ID which is empty or contains a
+	// slash or a comma, as the named ports of a service are mapped as
+	// ID/NAME.
+	ErrInvalidID = errors.New("Service IDs must be non-empty and can't contain '/' or ','")
+
+	// ErrInvalidName is returned for a port name which is empty or contains a
+	// slash or a comma.
+	ErrInvalidName = errors.New("Port names must be non-empty and can't contain '/' or ','")
+
+	// ErrInvalidLabel is returned for a label which can't be stored, see
+	// checkLabels.
+	ErrInvalidLabel = errors.New("Label keys must be 1 to 63 letters, digits, '.', '_', '-' or '/', and values up to 63 of them but '/'")
 )
 
-func InitializeRedisClient(address, auth string) (err error) {
-	rediscon, err = client.DialWithConfig(&client.DialConfig{Address: address, Password: auth})
-	if err != nil {
-		log.Print("Failed InitializeRedisClient with err: ", err.Error())
-		return err
-	}
-	redisInitialized = true
-	return nil
+// PortConflictError is returned when a service requires a port it can't
+// have, either because another service holds it or because the service
+// already holds a different one.
+type PortConflictError struct {
+	ID   string
+	Port int
+	// Owner is the id holding Port, which is ID itself when the service
+	// already holds a different port.
+	Owner string
+	// Held is the port ID already holds, zero if it holds none.
+	Held int
 }
 
-func RedisConnection() (*client.Redis, error) {
-	if !redisInitialized {
-		return rediscon, errors.New("Need to call InitializeRedisClient first!")
+func (e *PortConflictError) Error() string {
+	if e.Held != 0 {
+		return fmt.Sprintf("'%s' already holds port %d, release it before requesting port %d", e.ID, e.Held, e.Port)
 	}
-	return rediscon, nil
+	return fmt.Sprintf("Port %d is already assigned to '%s'", e.Port, e.Owner)
 }
 
-func InitializePorts(start, end int) error {
-	rc, err := RedisConnection()
-	if err != nil {
-		return err
-	}
-	exists, err := rc.Exists("open_ports")
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("The backend has already been initialized, so I won't re-initialize it")
-	}
-	for i := start; i < end; i++ {
-		rc.SAdd("open_ports", fmt.Sprintf("%d", i))
-	}
-	added, err := rc.SCard("open_ports")
-	needed := end - start
-	if added != int64(needed) {
-		errm := fmt.Sprintf("Needed %d ports initialized, got %d", needed, added)
-		log.Print(errm)
-		return fmt.Errorf(errm)
-	}
-	return nil
+// AllocateOptions tunes how Allocate assigns a port.
+type AllocateOptions struct {
+	// Lease, when non-zero, makes the assignment expire unless it is renewed
+	// within this duration.
+	Lease time.Duration
+	// Port, when non-zero, is the port to assign. Unless Prefer is set it is
+	// a requirement and allocation fails if the port is taken.
+	Port int
+	// Prefer makes Port a preference, falling back to any open port when it
+	// is taken.
+	Prefer bool
+	// Block, when above one, assigns that many contiguous ports as a single
+	// assignment. Port is then the first port of the block.
+	Block int
+	// Names, when set, assigns a random port to each name which the service
+	// doesn't hold yet instead of a single port. Port, Prefer and Block
+	// don't apply to named ports.
+	Names []string
+	// Labels, when not nil, replace the labels stored with the assignment.
+	// Empty labels remove them.
+	Labels Labels
 }
 
-func GetOpenPort(iname string) (int, error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return 0, err
-	}
-	already_there, _ := rc.HGet("i2port", iname)
-	aport := string(already_there)
-	if len(aport) > 0 {
-		log.Printf("An open port request for '%s' was made, but i already have a port for it in the i2port, returning it.", iname)
-		iport, _ := strconv.Atoi(aport)
-		return iport, nil
-	}
+// AllocateRequest asks AllocateAll for the ports of one service.
+type AllocateRequest struct {
+	ID      string
+	Options AllocateOptions
+}
 
-	bport, err := rc.SPop("open_ports")
-	port := string(bport)
-	if err != nil {
-		log.Printf("Error on SPOP: %v", err)
-		return 0, err
-	}
+// BulkError is returned by AllocateAll when one of the allocations fails.
+type BulkError struct {
+	// ID is the service whose allocation failed.
+	ID  string
+	Err error
+}
 
-	added, err := rc.SAdd("assigned_ports", string(port))
-	if err != nil {
-		log.Printf("Error on SAdd '%d' to 'assigned_ports': %v", port, err)
-		return 0, err
-	}
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("Allocating ports for '%s' failed: %v", e.ID, e.Err)
+}
 
-	if added == 0 {
-		log.Printf("Error on SAdd '%d' already in 'assigned_ports'! This likely means something didn't get cleaned up.", port)
-		assigned_to, _ := rc.HGet("i2port", port)
-		if len(assigned_to) != 0 {
-			log.Printf("Assigned_to: %s (%d)", assigned_to, len(assigned_to))
-			log.Printf("Looks like this port as previously assigned to '%s'. I am now going to abort to avoid stomping on things.", assigned_to)
-			return 0, fmt.Errorf("Port obtained from GetOpenPort returned a port listed as assigned.")
-		} else {
-			log.Printf("Looks like this port was in the assigned_ports set, but no mapping id was found. This is sucky but non-fatal so I will continue to do my job. Someone does need to look into why this happened.", assigned_to)
-		}
-	}
+// Assignment describes the port held by a service.
+type Assignment struct {
+	ID string
+	// Port is the port held, or the first of them for a block.
+	Port int
+	// Block is the number of contiguous ports held from Port on.
+	Block int
+	// Named maps each port name to its port for a service holding named
+	// ports, in which case Port is zero.
+	Named map[string]int
+	// Expires is when the lease runs out, or the zero time if the assignment
+	// has no lease.
+	Expires time.Time
+	// Labels are the labels stored with the assignment.
+	Labels Labels
+}
 
-	isnew, err := rc.HSetnx("i2port", iname, port)
-	if !isnew {
-		assigned_to, _ := rc.HGet("i2port", iname)
-		log.Printf("Looks like this id was previously assigned the port '%s', though it was not in the `assigned_ports` set. I am now going return the pulled port to the pool and return the previously assigned port.", assigned_to)
-		already_there, _ := rc.HGet("i2port", iname)
-		oport, _ := strconv.Atoi(string(already_there))
-		// If we reach here this should already be done by the other process but we can do it here to ensure it is correct
-		rc.SAdd("open_ports", port)
-		rc.SAdd("assigned_ports", port)
-		return oport, nil
-	}
-	isnew, err = rc.HSetnx("port2i", port, iname)
-	if !isnew {
-		assigned_to, _ := rc.HGet("i2port", iname)
-		em := fmt.Errorf("Looks like this port was previously assigned the id '%s', though it was not in the `assigned_ports` set. I am now going do a full error and abor tbecause somethis is rotten in Denmark, Bob. Someone need to look into this imediately!", assigned_to)
-		log.Print(em.Error())
-		return 0, em
-	}
-	iport, _ := strconv.Atoi(port)
+// MappingPage is a page of the mapping of ids to ports, see
+// PortStore.ListMappings.
+type MappingPage struct {
+	// Mappings maps each id to its port, or the first port of its block.
+	// Named ports are listed under their ID/NAME.
+	Mappings map[string]int
+	// Labels holds the labels of the listed ids which have any. Those of a
+	// named port are the labels of its service.
+	Labels map[string]Labels
+	// Cursor is where the next page starts, zero once everything has been
+	// listed.
+	Cursor uint64
+}
 
-	return iport, nil
+// QuarantinedPort is a port taken out of the pool because it was found in
+// use outside of port-authority.
+type QuarantinedPort struct {
+	Port  int
+	Since time.Time
 }
 
-func GetInstanceFromPort(port int) (iname string, err error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return "", err
-	}
-	id, err := rc.HGet("port2i", fmt.Sprintf("%d", port))
-	if err != nil {
-		return "", err
+// Reaped is a service whose lease ran out, released by ReapExpired.
+type Reaped struct {
+	ID string
+	// Host is the host the service held its ports on, empty when it held
+	// them in the pool itself.
+	Host string
+}
+
+// String returns the ID, followed by @HOST for a host.
+func (r Reaped) String() string {
+	if len(r.Host) == 0 {
+		return r.ID
 	}
-	return string(id), nil
+	return r.ID + "@" + r.Host
+}
+
+// ReconcileReport describes what Reconcile changed.
+type ReconcileReport struct {
+	Start int
+	End   int
+	// Added are the ports newly put in the pool.
+	Added []string
+	// Removed are the free ports taken out of the pool.
+	Removed []string
+	// Stranded maps the assigned ports outside of the range, or excluded,
+	// to the id holding them. They are left alone, and leave the pool once
+	// released.
+	Stranded map[string]string
+	// Excluded are the exclusions now in force.
+	Excluded []Exclusion
 }
 
-func GetPortFromInstance(id string) (port string, err error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return "", err
+// Ports returns every port of the assignment, in order.
+func (a Assignment) Ports() []int {
+	if len(a.Named) > 0 {
+		ports := make([]int, 0, len(a.Named))
+		for _, port := range a.Named {
+			ports = append(ports, port)
+		}
+		sort.Ints(ports)
+		return ports
 	}
-	bport, err := rc.HGet("i2port", id)
-	if err != nil {
-		return "", err
+	ports := make([]int, 0, a.Block)
+	for i := 0; i < a.Block; i++ {
+		ports = append(ports, a.Port+i)
 	}
-	port = string(bport)
-	return port, nil
+	return ports
 }
 
-func GetOpenPortCount() (int64, error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return 0, err
-	}
-	return rc.SCard("open_ports")
+// Empty reports whether the service holds no port at all.
+func (a Assignment) Empty() bool {
+	return a.Port == 0 && len(a.Named) == 0
 }
 
-func GetOpenPortList() (ports []string, err error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return ports, err
-	}
-	return rc.SMembers("open_ports")
+// NamedID returns the id the port called name of the service id is mapped
+// under, e.g. "webapp-cars/http". It is what GetInstanceFromPort returns for
+// a named port.
+func NamedID(id, name string) string {
+	return id + "/" + name
 }
 
-func GetReservedPortCount() (int64, error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return 0, err
+// checkHost returns ErrInvalidHost unless name can be used as a host name.
+func checkHost(name string) error {
+	if len(name) == 0 || strings.ContainsAny(name, ": \t\n") {
+		return ErrInvalidHost
 	}
-	return rc.SCard("assigned_ports")
+	return nil
 }
 
-func GetReservedPortList() (ports []string, err error) {
-	rc, err := RedisConnection()
-	if err != nil {
-		return ports, err
+// checkID returns ErrInvalidID unless id can be used as a service ID.
+func checkID(id string) error {
+	if len(id) == 0 || strings.ContainsAny(id, "/,") {
+		return ErrInvalidID
 	}
-	return rc.SMembers("assigned_ports")
+	return nil
 }
 
-func RemoveService(id string) error {
-	rc, err := RedisConnection()
-	if err != nil {
-		return err
-	}
-	port, err := rc.HGet("i2port", id)
-	if len(port) == 0 { // it isn't there to be deleted
-		log.Print("del:", port)
-		return nil
+// checkIDs returns ErrInvalidID unless every id can be used.
+func checkIDs(ids []string) error {
+	for _, id := range ids {
+		if err := checkID(id); err != nil {
+			return err
+		}
 	}
-	tc, err := rc.Transaction()
-	if err != nil {
-		log.Printf("Failed to start Redis transaction. Error: %v", err)
-		return err
+	return nil
+}
+
+// checkNames returns ErrInvalidName unless every name can be stored.
+func checkNames(names []string) error {
+	for _, name := range names {
+		if len(name) == 0 || strings.ContainsAny(name, "/,") {
+			return ErrInvalidName
+		}
 	}
-	defer tc.Close()
-	// remove from maps
-	tc.Command("HDEL", "i2port", id)
-	tc.Command("HDEL", "port2i", id)
-	//remove from assigned_ports
-	tc.Command("SREM", "assigned_ports", string(port))
-	tc.Exec()
-	rc.SAdd("open_ports", string(port))
 	return nil
 }
+
+// PortStore is implemented by each backend capable of tracking port
+// assignments. The handlers only ever talk to a PortStore, so the backend can
+// be swapped out without them noticing.
+type PortStore interface {
+	// InitializePorts fills the pool with the ports from start up to, but
+	// not including, end, leaving out the excluded ones.
+	InitializePorts(start, end int, excluded []Exclusion) error
+	// Reconcile changes the range of an initialized pool to start up to end
+	// and its exclusions to excluded: free ports outside it or excluded are
+	// dropped, ports inside it which are neither free, assigned nor excluded
+	// are added, and assigned ports outside it or excluded are reported.
+	Reconcile(start, end int, excluded []Exclusion) (ReconcileReport, error)
+	// GetRange returns the range of the pool, both zero if it is unknown.
+	GetRange() (start, end int, err error)
+	// GetExclusions returns the ports of the range the pool never hands
+	// out.
+	GetExclusions() ([]Exclusion, error)
+	// Allocate assigns a port to the given id, or returns the assignment it
+	// already holds. A lease in opts is applied either way.
+	Allocate(id string, opts AllocateOptions) (Assignment, error)
+	// AllocateAll makes every allocation of reqs like Allocate does, all or
+	// nothing: when one fails, the services which held no ports before are
+	// released again, those which did get back the names, lease and labels
+	// they had, and a *BulkError is returned. It returns the assignments by
+	// id.
+	AllocateAll(reqs []AllocateRequest) (map[string]Assignment, error)
+	// GetAssignment returns what id holds, which is Empty when it holds
+	// nothing.
+	GetAssignment(id string) (Assignment, error)
+	// GetInstanceFromPort returns the id holding the port, or an empty string.
+	GetInstanceFromPort(port int) (string, error)
+	GetOpenPortCount() (int64, error)
+	GetOpenPortList() ([]string, error)
+	GetReservedPortCount() (int64, error)
+	GetReservedPortList() ([]string, error)
+	// GetCoolingPortCount and GetCoolingPortList report the released ports
+	// waiting out the cooldown before they can be assigned again.
+	GetCoolingPortCount() (int64, error)
+	GetCoolingPortList() ([]string, error)
+	// ListMappings returns a page of about count ids holding ports, starting
+	// at cursor, which is zero for the first page. When match isn't empty
+	// only the ids matching that glob pattern are listed, so a page may hold
+	// fewer ids, or none, before the listing is done.
+	ListMappings(cursor uint64, match string, count int) (MappingPage, error)
+	// RemoveService releases the ports held by id back into the pool.
+	RemoveService(id string) error
+	// RemoveServices releases each of ids like RemoveService does, and
+	// reports by id whether it held anything.
+	RemoveServices(ids []string) (map[string]bool, error)
+	// RenewLease pushes the expiry of id's lease out by lease, or by the
+	// duration it was last given when lease is zero.
+	RenewLease(id string, lease time.Duration) (Assignment, error)
+	// ReapExpired releases every assignment whose lease ran out before now
+	// and returns the services it released.
+	ReapExpired(now time.Time) ([]Reaped, error)
+	// Check looks for inconsistencies between the structures of the data
+	// model and, if repair is set, fixes them.
+	Check(repair bool) ([]Inconsistency, error)
+	// Quarantine takes a free port out of the pool because something outside
+	// of port-authority uses it. Ports which aren't free are left alone.
+	Quarantine(port int) error
+	// Unquarantine puts a quarantined port back into the pool, provided it
+	// is still within its range.
+	Unquarantine(port int) error
+	// GetQuarantine lists the quarantined ports in numeric order.
+	GetQuarantine() ([]QuarantinedPort, error)
+	// Host returns the store tracking the ports of the named host within
+	// this pool, giving it the pool's range and exclusions on first use. A
+	// port can be assigned once per host. Reconcile and ReapExpired on the
+	// pool's store also cover its hosts, the services reaped from a host
+	// being returned with its name. Only allocations should use it, looking
+	// hosts up with LookupHost otherwise.
+	Host(name string) (PortStore, error)
+	// LookupHost returns the store of a host the pool has seen, or
+	// ErrHostNotFound, without adding the host to the pool.
+	LookupHost(name string) (PortStore, error)
+	// Hosts returns the names of the hosts the pool has seen, sorted.
+	Hosts() ([]string, error)
+}
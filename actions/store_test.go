@@ -0,0 +1,951 @@
+package actions
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The tests in this file check the behaviour every PortStore shares. Each
+// backend runs them against its own store, see memory_test.go and
+// redis_test.go.
+
+// storeFactory returns an empty, uninitialized store.
+type storeFactory func(t *testing.T) PortStore
+
+// initialized returns a store of newStore holding the ports from start up to
+// end.
+func initialized(t *testing.T, newStore storeFactory, start, end int, excluded []Exclusion) PortStore {
+	t.Helper()
+	s := newStore(t)
+	if err := s.InitializePorts(start, end, excluded); err != nil {
+		t.Fatalf("InitializePorts(%d, %d): %v", start, end, err)
+	}
+	return s
+}
+
+// allocate allocates ports for id and fails the test on error.
+func allocate(t *testing.T, s PortStore, id string, opts AllocateOptions) Assignment {
+	t.Helper()
+	a, err := s.Allocate(id, opts)
+	if err != nil {
+		t.Fatalf("Allocate(%q, %+v): %v", id, opts, err)
+	}
+	return a
+}
+
+// checkCounts fails the test unless s holds open free and assigned ports.
+func checkCounts(t *testing.T, s PortStore, open, assigned int64) {
+	t.Helper()
+	if n, err := s.GetOpenPortCount(); err != nil || n != open {
+		t.Errorf("GetOpenPortCount() = %d, %v, want %d", n, err, open)
+	}
+	if n, err := s.GetReservedPortCount(); err != nil || n != assigned {
+		t.Errorf("GetReservedPortCount() = %d, %v, want %d", n, err, assigned)
+	}
+}
+
+// checkOwner fails the test unless port is held by id, or by nobody when id
+// is empty.
+func checkOwner(t *testing.T, s PortStore, port int, id string) {
+	t.Helper()
+	if owner, err := s.GetInstanceFromPort(port); err != nil || owner != id {
+		t.Errorf("GetInstanceFromPort(%d) = %q, %v, want %q", port, owner, err, id)
+	}
+}
+
+// conflictWith reports whether err is a *PortConflictError naming owner.
+func conflictWith(err error, owner string) bool {
+	conflict, ok := err.(*PortConflictError)
+	return ok && conflict.Owner == owner
+}
+
+func testInitializePorts(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	checkCounts(t, s, 10, 0)
+	if start, end, err := s.GetRange(); err != nil || start != 7000 || end != 7010 {
+		t.Errorf("GetRange() = %d, %d, %v, want 7000, 7010", start, end, err)
+	}
+	if err := s.InitializePorts(8000, 8010, nil); err != ErrAlreadyInitialized {
+		t.Errorf("InitializePorts() again = %v, want ErrAlreadyInitialized", err)
+	}
+	checkCounts(t, s, 10, 0)
+}
+
+func testAllocate(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	a := allocate(t, s, "web", AllocateOptions{})
+	if a.ID != "web" || a.Port < 7000 || a.Port >= 7010 || a.Block != 1 {
+		t.Fatalf("Allocate() = %+v, want a single port of the range", a)
+	}
+	checkCounts(t, s, 9, 1)
+	checkOwner(t, s, a.Port, "web")
+	if got, err := s.GetAssignment("web"); err != nil || got.Port != a.Port {
+		t.Errorf("GetAssignment() = %+v, %v, want port %d", got, err, a.Port)
+	}
+
+	seen := map[int]string{a.Port: "web"}
+	for i := 0; i < 9; i++ {
+		id := "svc" + string(rune('a'+i))
+		b := allocate(t, s, id, AllocateOptions{})
+		if other, ok := seen[b.Port]; ok {
+			t.Fatalf("Allocate(%q) = port %d, already held by %q", id, b.Port, other)
+		}
+		seen[b.Port] = id
+	}
+	checkCounts(t, s, 0, 10)
+	if _, err := s.Allocate("extra", AllocateOptions{}); err != ErrPoolExhausted {
+		t.Errorf("Allocate() from an exhausted pool = %v, want ErrPoolExhausted", err)
+	}
+	checkCounts(t, s, 0, 10)
+}
+
+func testAllocateIdempotent(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	first := allocate(t, s, "web", AllocateOptions{})
+	for i := 0; i < 3; i++ {
+		again := allocate(t, s, "web", AllocateOptions{})
+		if again.Port != first.Port {
+			t.Fatalf("Allocate() again = port %d, want %d", again.Port, first.Port)
+		}
+	}
+	checkCounts(t, s, 9, 1)
+
+	// A service holding its port is handed it even with the pool exhausted.
+	for i := 0; i < 9; i++ {
+		allocate(t, s, "filler"+string(rune('a'+i)), AllocateOptions{})
+	}
+	if again := allocate(t, s, "web", AllocateOptions{}); again.Port != first.Port {
+		t.Errorf("Allocate() from an exhausted pool = port %d, want %d", again.Port, first.Port)
+	}
+}
+
+func testRelease(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	a := allocate(t, s, "web", AllocateOptions{})
+	allocate(t, s, "db", AllocateOptions{})
+	checkCounts(t, s, 8, 2)
+
+	if err := s.RemoveService("web"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkCounts(t, s, 9, 1)
+	checkOwner(t, s, a.Port, "")
+	if got, err := s.GetAssignment("web"); err != nil || !got.Empty() {
+		t.Errorf("GetAssignment() after release = %+v, %v, want it empty", got, err)
+	}
+
+	released, err := s.RemoveServices([]string{"db", "web", "nobody"})
+	if err != nil {
+		t.Fatalf("RemoveServices(): %v", err)
+	}
+	if !released["db"] || released["web"] || released["nobody"] {
+		t.Errorf("RemoveServices() = %v, want only db released", released)
+	}
+	checkCounts(t, s, 10, 0)
+
+	if err := s.RemoveService("web"); err != nil {
+		t.Errorf("RemoveService() of a service holding nothing: %v", err)
+	}
+	checkCounts(t, s, 10, 0)
+	if b := allocate(t, s, "web", AllocateOptions{}); b.Port < 7000 || b.Port >= 7010 {
+		t.Errorf("Allocate() after release = port %d, want one of the range", b.Port)
+	}
+	checkCounts(t, s, 9, 1)
+}
+
+func testConcurrentAllocate(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7050, nil)
+	var wg sync.WaitGroup
+	ports := make([]int, 60)
+	errs := make([]error, len(ports))
+	for i := range ports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a, err := s.Allocate("svc"+strconv.Itoa(i), AllocateOptions{})
+			ports[i], errs[i] = a.Port, err
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[int]int)
+	exhausted := 0
+	for i, port := range ports {
+		switch {
+		case errs[i] == ErrPoolExhausted:
+			exhausted++
+		case errs[i] != nil:
+			t.Errorf("Allocate(\"svc%d\"): %v", i, errs[i])
+		case port < 7000 || port >= 7050:
+			t.Errorf("Allocate(\"svc%d\") = port %d, want one of the range", i, port)
+		default:
+			if other, ok := seen[port]; ok {
+				t.Errorf("Port %d handed out to svc%d and svc%d", port, other, i)
+			}
+			seen[port] = i
+		}
+	}
+	if exhausted != 10 {
+		t.Errorf("%d allocations found the pool exhausted, want 10", exhausted)
+	}
+	checkCounts(t, s, 0, 50)
+	checkClean(t, s)
+
+	for i := range ports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := "svc" + strconv.Itoa(i)
+			if err := s.RemoveService(id); err != nil {
+				t.Errorf("RemoveService(%q): %v", id, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	checkCounts(t, s, 50, 0)
+	checkClean(t, s)
+}
+
+func testAllocateWait(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7001, nil)
+	allocate(t, s, "a", AllocateOptions{})
+
+	if _, err := AllocateWait(s, "b", AllocateOptions{}, 0, nil); err != ErrPoolExhausted {
+		t.Errorf("AllocateWait() without a timeout = %v, want ErrPoolExhausted", err)
+	}
+	start := time.Now()
+	if _, err := AllocateWait(s, "b", AllocateOptions{}, 300*time.Millisecond, nil); err != ErrPoolExhausted {
+		t.Errorf("AllocateWait() timing out = %v, want ErrPoolExhausted", err)
+	}
+	if waited := time.Since(start); waited < 300*time.Millisecond {
+		t.Errorf("AllocateWait() gave up after %v, want it to wait 300ms", waited)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	start = time.Now()
+	if _, err := AllocateWait(s, "b", AllocateOptions{}, time.Minute, stop); err != ErrPoolExhausted || time.Since(start) > time.Second {
+		t.Errorf("AllocateWait() once stopped = %v after %v, want ErrPoolExhausted at once", err, time.Since(start))
+	}
+
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		released <- s.RemoveService("a")
+	}()
+	start = time.Now()
+	a, err := AllocateWait(s, "b", AllocateOptions{}, 10*time.Second, nil)
+	if err != nil || a.Port != 7000 {
+		t.Errorf("AllocateWait() with a port released = %+v, %v, want port 7000", a, err)
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Errorf("AllocateWait() took %v to notice the release", waited)
+	}
+	if err := <-released; err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkClean(t, s)
+}
+
+func testLeases(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	before := time.Now()
+	a := allocate(t, s, "web", AllocateOptions{Lease: time.Minute})
+	if a.Expires.Before(before.Add(time.Minute-time.Second)) || a.Expires.After(time.Now().Add(time.Minute+time.Second)) {
+		t.Errorf("Allocate() expires at %v, want a minute from %v", a.Expires, before)
+	}
+	if got, err := s.GetAssignment("web"); err != nil || !got.Expires.Equal(a.Expires) {
+		t.Errorf("GetAssignment() = %+v, %v, want it to expire at %v", got, err, a.Expires)
+	}
+
+	renewed, err := s.RenewLease("web", time.Hour)
+	if err != nil || renewed.Port != a.Port || renewed.Expires.Before(a.Expires.Add(50*time.Minute)) {
+		t.Errorf("RenewLease(1h) = %+v, %v, want port %d expiring in an hour", renewed, err, a.Port)
+	}
+	// A zero lease renews by the duration last given.
+	again, err := s.RenewLease("web", 0)
+	if err != nil || again.Expires.Before(renewed.Expires.Add(-time.Second)) {
+		t.Errorf("RenewLease(0) = %+v, %v, want it to expire in an hour again", again, err)
+	}
+
+	allocate(t, s, "db", AllocateOptions{})
+	if _, err := s.RenewLease("db", 0); err != ErrNoLease {
+		t.Errorf("RenewLease(0) without a lease = %v, want ErrNoLease", err)
+	}
+	if got, err := s.RenewLease("db", time.Minute); err != nil || got.Expires.IsZero() {
+		t.Errorf("RenewLease(1m) without a lease = %+v, %v, want it given one", got, err)
+	}
+	if _, err := s.RenewLease("nobody", time.Minute); err != ErrServiceNotFound {
+		t.Errorf("RenewLease() of an unknown service = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func testReapExpired(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	web := allocate(t, s, "web", AllocateOptions{Lease: time.Minute})
+	db := allocate(t, s, "db", AllocateOptions{Lease: time.Hour})
+	allocate(t, s, "cache", AllocateOptions{})
+	h, err := s.Host("node1")
+	if err != nil {
+		t.Fatalf("Host(): %v", err)
+	}
+	job := allocate(t, h, "job@2", AllocateOptions{Lease: time.Minute})
+
+	if reaped, err := s.ReapExpired(time.Now()); err != nil || len(reaped) != 0 {
+		t.Errorf("ReapExpired(now) = %v, %v, want nothing reaped", reaped, err)
+	}
+	reaped, err := s.ReapExpired(time.Now().Add(2 * time.Minute))
+	if err != nil {
+		t.Fatalf("ReapExpired(): %v", err)
+	}
+	sort.Slice(reaped, func(i, j int) bool { return reaped[i].ID < reaped[j].ID })
+	if want := []Reaped{{ID: "job@2", Host: "node1"}, {ID: "web"}}; !reflect.DeepEqual(reaped, want) {
+		t.Errorf("ReapExpired() = %v, want %v", reaped, want)
+	}
+	checkCounts(t, s, 8, 2)
+	checkOwner(t, s, web.Port, "")
+	checkOwner(t, s, db.Port, "db")
+	checkOwner(t, h, job.Port, "")
+	checkCounts(t, h, 10, 0)
+}
+
+func testStartReaper(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	allocate(t, s, "web", AllocateOptions{Lease: 10 * time.Millisecond})
+	stop := make(chan struct{})
+	defer close(stop)
+	go StartReaper(s, 5*time.Millisecond, stop)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if a, err := s.GetAssignment("web"); err == nil && a.Empty() {
+			checkCounts(t, s, 10, 0)
+			return
+		}
+	}
+	t.Error("StartReaper() didn't release the expired lease")
+}
+
+func testLookupHost(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	if _, err := s.LookupHost("node1"); err != ErrHostNotFound {
+		t.Errorf("LookupHost() of a new host = %v, want ErrHostNotFound", err)
+	}
+	if _, err := s.LookupHost("node 1"); err != ErrInvalidHost {
+		t.Errorf("LookupHost() of an invalid host = %v, want ErrInvalidHost", err)
+	}
+	if hosts, err := s.Hosts(); err != nil || len(hosts) != 0 {
+		t.Errorf("Hosts() after a lookup = %v, %v, want none", hosts, err)
+	}
+
+	h, err := s.Host("node1")
+	if err != nil {
+		t.Fatalf("Host(): %v", err)
+	}
+	job := allocate(t, h, "job", AllocateOptions{})
+	found, err := s.LookupHost("node1")
+	if err != nil {
+		t.Fatalf("LookupHost() of a known host: %v", err)
+	}
+	checkOwner(t, found, job.Port, "job")
+	checkCounts(t, found, 9, 1)
+	if hosts, err := s.Hosts(); err != nil || !reflect.DeepEqual(hosts, []string{"node1"}) {
+		t.Errorf("Hosts() = %v, %v, want node1", hosts, err)
+	}
+}
+
+// corrupter breaks the data model of a store by applying f to it directly.
+type corrupter func(t *testing.T, s PortStore, f fix)
+
+// cooler is implemented by the stores which can hold released ports back.
+type cooler interface {
+	SetCooldown(d time.Duration)
+}
+
+// checkClean fails the test unless Check finds nothing wrong with s.
+func checkClean(t *testing.T, s PortStore) {
+	t.Helper()
+	if found, err := s.Check(false); err != nil || len(found) != 0 {
+		t.Errorf("Check() = %+v, %v, want nothing found", found, err)
+	}
+}
+
+// checkFinds fails the test unless Check finds exactly one inconsistency, of
+// the given kind and port, and returns it.
+func checkFinds(t *testing.T, s PortStore, repair bool, kind, port string) Inconsistency {
+	t.Helper()
+	found, err := s.Check(repair)
+	if err != nil || len(found) != 1 || found[0].Kind != kind || found[0].Port != port {
+		t.Fatalf("Check(%v) = %+v, %v, want %s of port %s", repair, found, err, kind, port)
+	}
+	return found[0]
+}
+
+func testCheck(t *testing.T, newStore storeFactory, corrupt corrupter) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	checkClean(t, s)
+	a := allocate(t, s, "web", AllocateOptions{Lease: time.Minute})
+	checkClean(t, s)
+	port := strconv.Itoa(a.Port)
+
+	corrupt(t, s, fix{op: fixHashDelete, key: "port2i", field: port})
+	checkFinds(t, s, false, MissingReverse, port)
+	checkFinds(t, s, true, MissingReverse, port)
+	checkClean(t, s)
+	checkOwner(t, s, a.Port, "web")
+
+	corrupt(t, s, fix{op: fixSetAdd, key: "open_ports", field: port})
+	checkFinds(t, s, true, OwnedPortOpen, port)
+	checkClean(t, s)
+	checkCounts(t, s, 9, 1)
+
+	corrupt(t, s, fix{op: fixHashDelete, key: "i2port", field: "web"})
+	found, err := s.Check(true)
+	if err != nil || len(found) != 3 {
+		t.Fatalf("Check() of an unmapped port = %+v, %v, want stale reverse, orphan lease and owner", found, err)
+	}
+	checkClean(t, s)
+	checkOwner(t, s, a.Port, "")
+	checkCounts(t, s, 10, 0)
+}
+
+func testCheckAssignedWithoutOwner(t *testing.T, newStore storeFactory, corrupt corrupter) {
+	s := initialized(t, newStore, 7000, 7010, []Exclusion{{Start: 7009, End: 7009}})
+	checkCounts(t, s, 9, 0)
+	orphan := func(port string) Inconsistency {
+		t.Helper()
+		corrupt(t, s, fix{op: fixSetRemove, key: "open_ports", field: port})
+		corrupt(t, s, fix{op: fixSetAdd, key: "assigned_ports", field: port})
+		return checkFinds(t, s, true, AssignedWithoutOwner, port)
+	}
+
+	if i := orphan("7001"); i.Repair != "move it back to open_ports" {
+		t.Errorf("Repair of a free port = %q, want it moved back to open_ports", i.Repair)
+	}
+	checkClean(t, s)
+	checkCounts(t, s, 9, 0)
+
+	// Ports which don't belong in open_ports only leave assigned_ports.
+	for _, port := range []string{"9000", "7009"} {
+		if i := orphan(port); i.Repair != "remove it from assigned_ports" {
+			t.Errorf("Repair of port %s = %q, want it removed from assigned_ports", port, i.Repair)
+		}
+		checkClean(t, s)
+		checkCounts(t, s, 9, 0)
+	}
+
+	if err := s.Quarantine(7002); err != nil {
+		t.Fatalf("Quarantine(): %v", err)
+	}
+	orphan("7002")
+	checkClean(t, s)
+	checkCounts(t, s, 8, 0)
+	if q, err := s.GetQuarantine(); err != nil || len(q) != 1 || q[0].Port != 7002 {
+		t.Errorf("GetQuarantine() = %+v, %v, want port 7002 left quarantined", q, err)
+	}
+
+	s.(cooler).SetCooldown(time.Hour)
+	a := allocate(t, s, "web", AllocateOptions{Port: 7003})
+	if err := s.RemoveService(a.ID); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	orphan("7003")
+	checkClean(t, s)
+	checkCounts(t, s, 7, 0)
+	if n, err := s.GetCoolingPortCount(); err != nil || n != 1 {
+		t.Errorf("GetCoolingPortCount() = %d, %v, want port 7003 left cooling", n, err)
+	}
+}
+
+func testBlocks(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	a := allocate(t, s, "db", AllocateOptions{Block: 4, Port: 7000})
+	if want := []int{7000, 7001, 7002, 7003}; a.Port != 7000 || a.Block != 4 || !reflect.DeepEqual(a.Ports(), want) {
+		t.Fatalf("Allocate(block of 4) = %+v, want ports %v", a, want)
+	}
+	checkCounts(t, s, 6, 4)
+	checkOwner(t, s, 7000, "db")
+	if again := allocate(t, s, "db", AllocateOptions{Block: 4}); again.Port != 7000 || again.Block != 4 {
+		t.Errorf("Allocate() again = %+v, want the same block", again)
+	}
+	checkCounts(t, s, 6, 4)
+
+	if _, err := s.Allocate("web", AllocateOptions{Block: 3, Port: 7002}); !conflictWith(err, "db") {
+		t.Errorf("Allocate() overlapping a block = %v, want a conflict with db", err)
+	}
+	if _, err := s.Allocate("edge", AllocateOptions{Block: 2, Port: 7009}); err != ErrPortUnavailable {
+		t.Errorf("Allocate() past the end of the range = %v, want ErrPortUnavailable", err)
+	}
+	if _, err := s.Allocate("big", AllocateOptions{Block: 7}); err != ErrNoBlock {
+		t.Errorf("Allocate() of a block larger than any run = %v, want ErrNoBlock", err)
+	}
+	checkCounts(t, s, 6, 4)
+
+	b := allocate(t, s, "rest", AllocateOptions{Block: 6})
+	if b.Port != 7004 || b.Block != 6 {
+		t.Errorf("Allocate(block of 6) = %+v, want the ports from 7004 on", b)
+	}
+	checkCounts(t, s, 0, 10)
+
+	if err := s.RemoveService("db"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkCounts(t, s, 4, 6)
+	for port := 7000; port < 7004; port++ {
+		checkOwner(t, s, port, "")
+	}
+	checkClean(t, s)
+}
+
+func testNamedPorts(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	a := allocate(t, s, "app", AllocateOptions{Names: []string{"http", "admin"}})
+	if a.Port != 0 || len(a.Named) != 2 || a.Named["http"] == 0 || a.Named["admin"] == 0 || a.Named["http"] == a.Named["admin"] {
+		t.Fatalf("Allocate(names) = %+v, want two distinct named ports", a)
+	}
+	checkCounts(t, s, 8, 2)
+	checkOwner(t, s, a.Named["http"], NamedID("app", "http"))
+
+	b := allocate(t, s, "app", AllocateOptions{Names: []string{"http", "metrics"}})
+	if len(b.Named) != 3 || b.Named["http"] != a.Named["http"] || b.Named["admin"] != a.Named["admin"] {
+		t.Errorf("Allocate(more names) = %+v, want the ports held kept and metrics added", b)
+	}
+	checkCounts(t, s, 7, 3)
+
+	// Port doesn't apply to named ports.
+	c := allocate(t, s, "app", AllocateOptions{Names: []string{"http"}, Port: 7009})
+	if !reflect.DeepEqual(c.Named, b.Named) {
+		t.Errorf("Allocate(names, port) = %+v, want the named ports held", c)
+	}
+	if _, err := s.Allocate("app", AllocateOptions{Port: 7009}); err != ErrAssignmentKind {
+		t.Errorf("Allocate(port) of named ports = %v, want ErrAssignmentKind", err)
+	}
+	allocate(t, s, "web", AllocateOptions{})
+	if _, err := s.Allocate("web", AllocateOptions{Names: []string{"http"}}); err != ErrAssignmentKind {
+		t.Errorf("Allocate(names) of a single port = %v, want ErrAssignmentKind", err)
+	}
+	if _, err := s.Allocate("bad", AllocateOptions{Names: []string{"a/b"}}); err != ErrInvalidName {
+		t.Errorf("Allocate(names) with a slash = %v, want ErrInvalidName", err)
+	}
+	if _, err := s.Allocate("many", AllocateOptions{Names: []string{"a", "b", "c", "d", "e", "f", "g"}}); err != ErrPoolExhausted {
+		t.Errorf("Allocate(more names than open ports) = %v, want ErrPoolExhausted", err)
+	}
+	checkCounts(t, s, 6, 4)
+	checkClean(t, s)
+
+	if err := s.RemoveService("app"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkCounts(t, s, 9, 1)
+	checkOwner(t, s, a.Named["http"], "")
+	if got, err := s.GetAssignment("app"); err != nil || !got.Empty() {
+		t.Errorf("GetAssignment() after release = %+v, %v, want it empty", got, err)
+	}
+	checkClean(t, s)
+}
+
+func testInvalidIDs(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	a := allocate(t, s, "app", AllocateOptions{Names: []string{"http"}})
+	for _, id := range []string{"", "app/http", "a,b"} {
+		if _, err := s.Allocate(id, AllocateOptions{}); err != ErrInvalidID {
+			t.Errorf("Allocate(%q) = %v, want ErrInvalidID", id, err)
+		}
+		if _, err := s.AllocateAll([]AllocateRequest{{ID: "ok"}, {ID: id}}); KindOf(err) != KindInvalid {
+			t.Errorf("AllocateAll() with %q = %v, want it invalid", id, err)
+		}
+		if _, err := s.GetAssignment(id); err != ErrInvalidID {
+			t.Errorf("GetAssignment(%q) = %v, want ErrInvalidID", id, err)
+		}
+		if _, err := s.RenewLease(id, time.Minute); err != ErrInvalidID {
+			t.Errorf("RenewLease(%q) = %v, want ErrInvalidID", id, err)
+		}
+		if err := s.RemoveService(id); err != ErrInvalidID {
+			t.Errorf("RemoveService(%q) = %v, want ErrInvalidID", id, err)
+		}
+		if _, err := s.RemoveServices([]string{"ok", id}); err != ErrInvalidID {
+			t.Errorf("RemoveServices() with %q = %v, want ErrInvalidID", id, err)
+		}
+	}
+	checkCounts(t, s, 9, 1)
+	checkOwner(t, s, a.Named["http"], NamedID("app", "http"))
+	if got, err := s.GetAssignment("ok"); err != nil || !got.Empty() {
+		t.Errorf("GetAssignment(\"ok\") = %+v, %v, want it empty", got, err)
+	}
+	checkClean(t, s)
+}
+
+func testExclusions(t *testing.T, newStore storeFactory) {
+	excluded := []Exclusion{{Start: 7002, End: 7002}, {Start: 7005, End: 7007}}
+	s := initialized(t, newStore, 7000, 7010, excluded)
+	checkCounts(t, s, 6, 0)
+	if got, err := s.GetExclusions(); err != nil || !reflect.DeepEqual(got, excluded) {
+		t.Errorf("GetExclusions() = %v, %v, want %v", got, err, excluded)
+	}
+	open, err := s.GetOpenPortList()
+	if err != nil {
+		t.Fatalf("GetOpenPortList(): %v", err)
+	}
+	sort.Strings(open)
+	if want := []string{"7000", "7001", "7003", "7004", "7008", "7009"}; !reflect.DeepEqual(open, want) {
+		t.Errorf("GetOpenPortList() = %v, want %v", open, want)
+	}
+	if _, err := s.Allocate("web", AllocateOptions{Port: 7006}); err != ErrPortUnavailable {
+		t.Errorf("Allocate() of an excluded port = %v, want ErrPortUnavailable", err)
+	}
+	if _, err := s.Allocate("db", AllocateOptions{Block: 3}); err != ErrNoBlock {
+		t.Errorf("Allocate() of a block spanning exclusions = %v, want ErrNoBlock", err)
+	}
+
+	// Excluding a held port leaves it with its holder until released.
+	allocate(t, s, "web", AllocateOptions{Port: 7003})
+	report, err := s.Reconcile(7000, 7010, []Exclusion{{Start: 7003, End: 7004}})
+	if err != nil {
+		t.Fatalf("Reconcile(): %v", err)
+	}
+	sort.Strings(report.Added)
+	if want := []string{"7002", "7005", "7006", "7007"}; !reflect.DeepEqual(report.Added, want) {
+		t.Errorf("Reconcile() added %v, want %v", report.Added, want)
+	}
+	if want := []string{"7004"}; !reflect.DeepEqual(report.Removed, want) {
+		t.Errorf("Reconcile() removed %v, want %v", report.Removed, want)
+	}
+	if want := map[string]string{"7003": "web"}; !reflect.DeepEqual(report.Stranded, want) {
+		t.Errorf("Reconcile() stranded %v, want %v", report.Stranded, want)
+	}
+	checkCounts(t, s, 8, 1)
+	checkOwner(t, s, 7003, "web")
+	if err := s.RemoveService("web"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkCounts(t, s, 8, 0)
+	checkClean(t, s)
+}
+
+// checkCooling fails the test unless s holds the cooling ports listed.
+func checkCooling(t *testing.T, s PortStore, ports ...string) {
+	t.Helper()
+	if n, err := s.GetCoolingPortCount(); err != nil || n != int64(len(ports)) {
+		t.Errorf("GetCoolingPortCount() = %d, %v, want %d", n, err, len(ports))
+	}
+	cooling, err := s.GetCoolingPortList()
+	sort.Strings(cooling)
+	if err != nil || strings.Join(cooling, ",") != strings.Join(ports, ",") {
+		t.Errorf("GetCoolingPortList() = %v, %v, want %v", cooling, err, ports)
+	}
+}
+
+func testCooldown(t *testing.T, newStore storeFactory) {
+	s := newStore(t)
+	s.(cooler).SetCooldown(time.Hour)
+	if err := s.InitializePorts(7000, 7002, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	allocate(t, s, "web", AllocateOptions{Port: 7000})
+	allocate(t, s, "db", AllocateOptions{Port: 7001})
+	if err := s.RemoveService("web"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkCounts(t, s, 0, 1)
+	checkCooling(t, s, "7000")
+	if _, err := s.Allocate("cache", AllocateOptions{}); err != ErrPoolExhausted {
+		t.Errorf("Allocate() with the only free port cooling = %v, want ErrPoolExhausted", err)
+	}
+	if _, err := s.Allocate("web", AllocateOptions{Port: 7000}); err != ErrPortUnavailable {
+		t.Errorf("Allocate() of a cooling port = %v, want ErrPortUnavailable", err)
+	}
+	checkClean(t, s)
+
+	if _, err := s.ReapExpired(time.Now().Add(30 * time.Minute)); err != nil {
+		t.Fatalf("ReapExpired(): %v", err)
+	}
+	checkCooling(t, s, "7000")
+	if _, err := s.ReapExpired(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatalf("ReapExpired(): %v", err)
+	}
+	checkCooling(t, s)
+	checkCounts(t, s, 1, 1)
+	if a := allocate(t, s, "cache", AllocateOptions{}); a.Port != 7000 {
+		t.Errorf("Allocate() once cooled down = port %d, want 7000", a.Port)
+	}
+
+	// Hosts inherit the cooldown of their pool.
+	h, err := s.Host("node1")
+	if err != nil {
+		t.Fatalf("Host(): %v", err)
+	}
+	allocate(t, h, "job", AllocateOptions{Port: 7001})
+	if err := h.RemoveService("job"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	checkCooling(t, h, "7001")
+	checkCounts(t, h, 1, 0)
+}
+
+// strategist is implemented by the stores whose strategy can be changed.
+type strategist interface {
+	SetStrategy(st Strategy)
+}
+
+// withStrategy returns a store of newStore picking ports with st, holding the
+// ports from 7000 up to 7010.
+func withStrategy(t *testing.T, newStore storeFactory, st Strategy) PortStore {
+	t.Helper()
+	s := newStore(t)
+	s.(strategist).SetStrategy(st)
+	if err := s.InitializePorts(7000, 7010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	return s
+}
+
+func testStrategyLowest(t *testing.T, newStore storeFactory) {
+	s := withStrategy(t, newStore, StrategyLowest)
+	for i, id := range []string{"a", "b", "c"} {
+		if a := allocate(t, s, id, AllocateOptions{}); a.Port != 7000+i {
+			t.Errorf("Allocate(%q) = port %d, want %d", id, a.Port, 7000+i)
+		}
+	}
+	if err := s.RemoveService("b"); err != nil {
+		t.Fatalf("RemoveService(): %v", err)
+	}
+	if a := allocate(t, s, "d", AllocateOptions{}); a.Port != 7001 {
+		t.Errorf("Allocate() after a release = port %d, want 7001", a.Port)
+	}
+	if a := allocate(t, s, "e", AllocateOptions{Block: 2}); a.Port != 7003 {
+		t.Errorf("Allocate(block of 2) = port %d, want 7003", a.Port)
+	}
+
+	h, err := s.Host("node1")
+	if err != nil {
+		t.Fatalf("Host(): %v", err)
+	}
+	if a := allocate(t, h, "a", AllocateOptions{}); a.Port != 7000 {
+		t.Errorf("Allocate() on a host = port %d, want 7000", a.Port)
+	}
+}
+
+func testStrategyHash(t *testing.T, newStore storeFactory) {
+	want := 7000 + hashOffset("web", 10)
+	for i := 0; i < 2; i++ {
+		s := withStrategy(t, newStore, StrategyHash)
+		if a := allocate(t, s, "web", AllocateOptions{}); a.Port != want {
+			t.Errorf("Allocate() in store %d = port %d, want %d", i, a.Port, want)
+		}
+	}
+
+	// A taken port moves the service on to the next free one, wrapping
+	// around at the end of the range.
+	s := withStrategy(t, newStore, StrategyHash)
+	allocate(t, s, "other", AllocateOptions{Port: want})
+	next := 7000 + (want-7000+1)%10
+	if a := allocate(t, s, "web", AllocateOptions{}); a.Port != next {
+		t.Errorf("Allocate() with port %d taken = port %d, want %d", want, a.Port, next)
+	}
+}
+
+// listAll pages through the mappings of s matching match, count at a time,
+// returning the ports of the ids listed.
+func listAll(t *testing.T, s PortStore, match string, count int) map[string]int {
+	t.Helper()
+	mappings := make(map[string]int)
+	var cursor uint64
+	for pages := 1; ; pages++ {
+		page, err := s.ListMappings(cursor, match, count)
+		if err != nil {
+			t.Fatalf("ListMappings(%d, %q, %d): %v", cursor, match, count, err)
+		}
+		for id, port := range page.Mappings {
+			mappings[id] = port
+		}
+		if page.Cursor == 0 {
+			return mappings
+		}
+		if pages > 100 {
+			t.Fatalf("ListMappings(%q) is still paging after 100 pages", match)
+		}
+		cursor = page.Cursor
+	}
+}
+
+func testListMappings(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7100, nil)
+	want := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		id := fmt.Sprintf("svc%02d", i)
+		want[id] = allocate(t, s, id, AllocateOptions{}).Port
+	}
+	if mappings := listAll(t, s, "", 7); !reflect.DeepEqual(mappings, want) {
+		t.Errorf("ListMappings() over every page = %v, want %v", mappings, want)
+	}
+	if mappings := listAll(t, s, "svc1*", 7); len(mappings) != 10 {
+		t.Errorf("ListMappings(svc1*) = %v, want the 10 ids from svc10 to svc19", mappings)
+	}
+
+	// Characters a glob gives a meaning to are matched literally once
+	// escaped, as the prefix parameter of the API does.
+	for _, id := range []string{"a*b", "a?b", "a[b]", "axb"} {
+		allocate(t, s, id, AllocateOptions{})
+	}
+	for match, ids := range map[string][]string{
+		`a\**`:    {"a*b"},
+		`a\?*`:    {"a?b"},
+		`a\[*`:    {"a[b]"},
+		`a\[b\]*`: {"a[b]"},
+		`a?b`:     {"a*b", "a?b", "axb"},
+	} {
+		mappings := listAll(t, s, match, 7)
+		var got []string
+		for id := range mappings {
+			got = append(got, id)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, ids) {
+			t.Errorf("ListMappings(%q) = %v, want %v", match, got, ids)
+		}
+	}
+}
+
+// selectedIDs returns the ids of assignments, sorted.
+func selectedIDs(assignments []Assignment) []string {
+	ids := make([]string, len(assignments))
+	for i, a := range assignments {
+		ids[i] = a.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func testSelectors(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	allocate(t, s, "pay-api", AllocateOptions{Labels: Labels{"team": "payments", "env": "prod"}})
+	allocate(t, s, "pay-web", AllocateOptions{Names: []string{"http", "admin"}, Labels: Labels{"team": "payments", "env": "staging"}})
+	allocate(t, s, "search", AllocateOptions{Labels: Labels{"team": "search", "env": "prod"}})
+	allocate(t, s, "scratch", AllocateOptions{})
+
+	for spec, want := range map[string][]string{
+		"":                        {"pay-api", "pay-web", "scratch", "search"},
+		"team=payments":           {"pay-api", "pay-web"},
+		"team=payments,env!=prod": {"pay-web"},
+		"env in (prod, staging)":  {"pay-api", "pay-web", "search"},
+		"team notin (payments)":   {"scratch", "search"},
+		"!team":                   {"scratch"},
+		"team=billing":            {},
+	} {
+		sel, err := ParseSelector(spec)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", spec, err)
+		}
+		selected, err := SelectServices(s, sel)
+		if got := selectedIDs(selected); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("SelectServices(%q) = %v, %v, want %v", spec, got, err, want)
+		}
+	}
+
+	sel, _ := ParseSelector("team=payments")
+	released, err := ReleaseServices(s, sel)
+	sort.Strings(released)
+	if want := []string{"pay-api", "pay-web"}; err != nil || !reflect.DeepEqual(released, want) {
+		t.Errorf("ReleaseServices() = %v, %v, want %v", released, err, want)
+	}
+	checkCounts(t, s, 8, 2)
+	if selected, err := SelectServices(s, sel); err != nil || len(selected) != 0 {
+		t.Errorf("SelectServices() after release = %v, %v, want nothing", selectedIDs(selected), err)
+	}
+	checkClean(t, s)
+}
+
+func testAllocateAll(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	old := allocate(t, s, "old", AllocateOptions{Port: 7000})
+	assigned, err := s.AllocateAll([]AllocateRequest{
+		{ID: "a", Options: AllocateOptions{Port: 7009}},
+		{ID: "b", Options: AllocateOptions{Block: 3}},
+		{ID: "old", Options: AllocateOptions{Lease: time.Minute}},
+	})
+	if err != nil {
+		t.Fatalf("AllocateAll(): %v", err)
+	}
+	if len(assigned) != 3 || assigned["a"].Port != 7009 || assigned["b"].Block != 3 || assigned["old"].Port != old.Port || assigned["old"].Expires.IsZero() {
+		t.Errorf("AllocateAll() = %+v, want a on 7009, a block for b and old's port leased", assigned)
+	}
+	checkCounts(t, s, 5, 5)
+	if assigned, err := s.AllocateAll(nil); err != nil || len(assigned) != 0 {
+		t.Errorf("AllocateAll(nil) = %v, %v, want nothing", assigned, err)
+	}
+}
+
+func testAllocateAllRollback(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	allocate(t, s, "old", AllocateOptions{Port: 7000, Lease: time.Hour, Labels: Labels{"team": "search"}})
+	allocate(t, s, "taken", AllocateOptions{Port: 7009})
+	allocate(t, s, "app", AllocateOptions{Names: []string{"http"}})
+	checkCounts(t, s, 7, 3)
+	before := make(map[string]Assignment)
+	for _, id := range []string{"old", "app"} {
+		a, err := s.GetAssignment(id)
+		if err != nil {
+			t.Fatalf("GetAssignment(%q): %v", id, err)
+		}
+		before[id] = a
+	}
+
+	_, err := s.AllocateAll([]AllocateRequest{
+		{ID: "c", Options: AllocateOptions{}},
+		{ID: "old", Options: AllocateOptions{Lease: time.Minute, Labels: Labels{"team": "payments"}}},
+		{ID: "app", Options: AllocateOptions{Names: []string{"http", "admin"}, Lease: time.Minute, Labels: Labels{"team": "web"}}},
+		{ID: "d", Options: AllocateOptions{Port: 7009}},
+		{ID: "e", Options: AllocateOptions{}},
+	})
+	bulk, ok := err.(*BulkError)
+	if !ok || bulk.ID != "d" || !conflictWith(bulk.Err, "taken") || KindOf(err) != KindConflict {
+		t.Fatalf("AllocateAll() with a port taken = %v, want a conflict for d", err)
+	}
+	checkCounts(t, s, 7, 3)
+	for _, id := range []string{"c", "d", "e"} {
+		if a, err := s.GetAssignment(id); err != nil || !a.Empty() {
+			t.Errorf("GetAssignment(%q) after a failed bulk allocation = %+v, %v, want it empty", id, a, err)
+		}
+	}
+	for id, want := range before {
+		if a, err := s.GetAssignment(id); err != nil || !reflect.DeepEqual(a, want) {
+			t.Errorf("GetAssignment(%q) after a failed bulk allocation = %+v, %v, want it unchanged from %+v", id, a, err, want)
+		}
+	}
+	if a, err := s.RenewLease("old", 0); err != nil || a.Expires.Before(time.Now().Add(30*time.Minute)) {
+		t.Errorf("RenewLease(\"old\") = %+v, %v, want its lease of an hour kept", a, err)
+	}
+	checkClean(t, s)
+
+	var reqs []AllocateRequest
+	for i := 0; i < 9; i++ {
+		reqs = append(reqs, AllocateRequest{ID: "svc" + strconv.Itoa(i)})
+	}
+	if _, err := s.AllocateAll(reqs); KindOf(err) != KindExhausted {
+		t.Errorf("AllocateAll() of more services than open ports = %v, want the pool exhausted", err)
+	}
+	if _, err := s.AllocateAll([]AllocateRequest{{ID: "f"}, {ID: "g", Options: AllocateOptions{Names: []string{"a/b"}}}}); KindOf(err) != KindInvalid {
+		t.Errorf("AllocateAll() with an invalid name = %v, want it invalid", err)
+	}
+	checkCounts(t, s, 7, 3)
+	checkClean(t, s)
+}
+
+func testRemoveServices(t *testing.T, newStore storeFactory) {
+	s := initialized(t, newStore, 7000, 7010, nil)
+	allocate(t, s, "a", AllocateOptions{})
+	allocate(t, s, "b", AllocateOptions{Block: 2})
+	allocate(t, s, "c", AllocateOptions{Names: []string{"http"}})
+	released, err := s.RemoveServices([]string{"a", "a", "b", "c", "nobody"})
+	if want := map[string]bool{"a": true, "b": true, "c": true, "nobody": false}; err != nil || !reflect.DeepEqual(released, want) {
+		t.Errorf("RemoveServices() = %v, %v, want %v", released, err, want)
+	}
+	checkCounts(t, s, 10, 0)
+	if released, err := s.RemoveServices(nil); err != nil || len(released) != 0 {
+		t.Errorf("RemoveServices(nil) = %v, %v, want nothing", released, err)
+	}
+	checkClean(t, s)
+}
@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Strategy is how a pool picks the ports of a new assignment when no
+// particular port is asked for.
+type Strategy string
+
+const (
+	// StrategyRandom picks any open port. It is the default.
+	StrategyRandom Strategy = "random"
+	// StrategyLowest picks the lowest open port.
+	StrategyLowest Strategy = "lowest"
+	// StrategyHash picks the first open port from one derived from the
+	// service ID on, so an ID gets the same port in every fresh environment
+	// sharing the pool's range.
+	StrategyHash Strategy = "hash"
+)
+
+// ParseStrategy returns the strategy of the given name. An empty name is the
+// random strategy.
+func ParseStrategy(name string) (Strategy, error) {
+	switch st := Strategy(strings.ToLower(strings.TrimSpace(name))); st {
+	case "":
+		return StrategyRandom, nil
+	case StrategyRandom, StrategyLowest, StrategyHash:
+		return st, nil
+	}
+	return StrategyRandom, fmt.Errorf("Unknown allocation strategy '%s'", name)
+}
+
+// hashOffset returns how far into a range of size ports the hash strategy
+// starts looking for a port for id. It is the first four bytes of the SHA1 of
+// id modulo size, which allocateScript computes the same way.
+func hashOffset(id string, size int) int {
+	if size <= 0 {
+		return 0
+	}
+	sum := sha1.Sum([]byte(id))
+	return int(binary.BigEndian.Uint32(sum[:4]) % uint32(size))
+}
@@ -0,0 +1,27 @@
+package actions
+
+import (
+	"testing"
+)
+
+func TestParseStrategy(t *testing.T) {
+	for name, want := range map[string]Strategy{"": StrategyRandom, "random": StrategyRandom, " Lowest ": StrategyLowest, "HASH": StrategyHash} {
+		if st, err := ParseStrategy(name); err != nil || st != want {
+			t.Errorf("ParseStrategy(%q) = %q, %v, want %q", name, st, err, want)
+		}
+	}
+	if _, err := ParseStrategy("roundrobin"); err == nil {
+		t.Error("ParseStrategy() of an unknown strategy succeeded, want an error")
+	}
+}
+
+func TestHashOffset(t *testing.T) {
+	if n := hashOffset("web", 0); n != 0 {
+		t.Errorf("hashOffset() of an empty range = %d, want 0", n)
+	}
+	for _, id := range []string{"web", "db", "webapp-cars/http"} {
+		if n := hashOffset(id, 10); n < 0 || n >= 10 || n != hashOffset(id, 10) {
+			t.Errorf("hashOffset(%q, 10) = %d, want a stable offset below 10", id, n)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package actions
+
+import "time"
+
+// waitInterval is how often AllocateWait retries an allocation.
+const waitInterval = 250 * time.Millisecond
+
+// AllocateWait allocates like store.Allocate but, while the pool has no port
+// left for the request, retries until one is released, timeout passes or stop
+// is closed. It then returns the error of the last attempt, which is of
+// KindExhausted. A zero timeout makes it the same as store.Allocate.
+func AllocateWait(store PortStore, id string, opts AllocateOptions, timeout time.Duration, stop <-chan struct{}) (Assignment, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		a, err := store.Allocate(id, opts)
+		left := deadline.Sub(time.Now())
+		if err == nil || KindOf(err) != KindExhausted || left <= 0 {
+			return a, err
+		}
+		if left > waitInterval {
+			left = waitInterval
+		}
+		select {
+		case <-stop:
+			return a, err
+		case <-time.After(left):
+		}
+	}
+}
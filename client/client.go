@@ -0,0 +1,406 @@
+// Package client talks to a port-authority server over its /api/v2 HTTP
+// API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/therealbill/port-authority/common"
+)
+
+// Client makes requests to one port-authority server. Its fields may be
+// changed until it is first used.
+type Client struct {
+	// BaseURL is where the server listens, e.g. http://localhost:8080.
+	BaseURL string
+	// Pool is the pool to work on, the default pool when empty.
+	Pool string
+	// Host, when set, works on the ports of that host within the pool.
+	Host string
+	// HTTPClient makes the requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Retries is how many times a request is retried while the server's
+	// backend is unavailable, or a proxy answers with a 503. An exhausted
+	// pool, which answers with a 503 too, isn't retried.
+	Retries int
+	// RetryDelay is how long to wait before the first retry. It doubles
+	// with each retry.
+	RetryDelay time.Duration
+}
+
+// New returns a client for the server at baseURL, retrying requests three
+// times while the server's backend is unavailable.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Retries: 3, RetryDelay: 500 * time.Millisecond}
+}
+
+// AllocateOptions tunes how AllocatePort assigns ports. The zero value asks
+// for a single port picked by the pool.
+type AllocateOptions struct {
+	// Lease, when non-zero, makes the assignment expire unless renewed
+	// within it.
+	Lease time.Duration
+	// Port, when non-zero, is the port required, or preferred with Prefer.
+	Port   int
+	Prefer bool
+	// Block, when above one, asks for that many contiguous ports.
+	Block int
+	// Names, when set, asks for a port for each name.
+	Names []string
+	// Labels, when not nil, replace the labels stored with the service.
+	Labels map[string]string
+	// Wait is how long AllocatePort may wait for a port when the pool is
+	// exhausted. Bulk allocations don't wait.
+	Wait time.Duration
+}
+
+// request returns the body asking for opts for the service id.
+func (o AllocateOptions) request(id string) common.ServiceRequest {
+	req := common.ServiceRequest{ID: id, Port: o.Port, Prefer: o.Prefer, Block: o.Block, Names: o.Names, Labels: o.Labels}
+	if o.Lease != 0 {
+		req.Lease = o.Lease.String()
+	}
+	if o.Wait != 0 {
+		req.Wait = o.Wait.String()
+	}
+	return req
+}
+
+// Allocation asks AllocateServices for the ports of one service.
+type Allocation struct {
+	ID      string
+	Options AllocateOptions
+}
+
+// MappingQuery selects a page of the mapping of ids to ports. Only one of
+// Match, a glob pattern, and Prefix may be set.
+type MappingQuery struct {
+	// Cursor is that of the previous page, zero for the first.
+	Cursor uint64
+	// Count is about how many ids the page holds, 100 when zero.
+	Count    int
+	Match    string
+	Prefix   string
+	Selector string
+}
+
+// AllocatePort assigns ports to the service id, or returns those it holds.
+func (c *Client) AllocatePort(ctx context.Context, id string, opts AllocateOptions) (common.Service, error) {
+	var s common.Service
+	err := c.do(ctx, "PUT", "/services/"+url.PathEscape(id), nil, opts.request(""), &s)
+	return s, err
+}
+
+// AllocateServices assigns ports to every service in allocs, or to none of
+// them, and returns them by id. When one can't be allocated the error's
+// FailedID names it.
+func (c *Client) AllocateServices(ctx context.Context, allocs []Allocation) (map[string]common.Service, error) {
+	body := common.BulkPortRequest{Services: make([]common.ServiceRequest, len(allocs))}
+	for i, a := range allocs {
+		body.Services[i] = a.Options.request(a.ID)
+		body.Services[i].Wait = ""
+	}
+	var services map[string]common.Service
+	err := c.do(ctx, "POST", "/services", nil, body, &services)
+	return services, err
+}
+
+// LookupService returns the service id. It fails with a not found error if
+// it holds no ports.
+func (c *Client) LookupService(ctx context.Context, id string) (common.Service, error) {
+	var s common.Service
+	err := c.do(ctx, "GET", "/services/"+url.PathEscape(id), nil, nil, &s)
+	return s, err
+}
+
+// LookupPort returns the id of the service holding port. It fails with a
+// not found error if none does.
+func (c *Client) LookupPort(ctx context.Context, port int) (string, error) {
+	var owner common.PortOwner
+	err := c.do(ctx, "GET", "/ports/"+strconv.Itoa(port), nil, nil, &owner)
+	return owner.ID, err
+}
+
+// Services returns the services whose labels match selector, such as
+// "team=payments,env!=prod", every service when it is empty.
+func (c *Client) Services(ctx context.Context, selector string) ([]common.Service, error) {
+	var services []common.Service
+	err := c.do(ctx, "GET", "/services", url.Values{"selector": {selector}}, nil, &services)
+	return services, err
+}
+
+// Release releases the ports held by the service id. It fails with a not
+// found error if it held none.
+func (c *Client) Release(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/services/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ReleaseServices releases the services ids at once and reports for each
+// whether it held any ports.
+func (c *Client) ReleaseServices(ctx context.Context, ids []string) (map[string]bool, error) {
+	var released map[string]bool
+	err := c.do(ctx, "POST", "/services/release", nil, common.BulkReleaseRequest{IDs: ids}, &released)
+	return released, err
+}
+
+// ReleaseSelected releases the services whose labels match selector, which
+// can't be empty, and returns their ids.
+func (c *Client) ReleaseSelected(ctx context.Context, selector string) ([]string, error) {
+	var released []string
+	err := c.do(ctx, "DELETE", "/services", url.Values{"selector": {selector}}, nil, &released)
+	return released, err
+}
+
+// Renew extends the lease on the service id by lease, or by the lease it
+// was last given when lease is zero.
+func (c *Client) Renew(ctx context.Context, id string, lease time.Duration) (common.Service, error) {
+	var req common.RenewRequest
+	if lease != 0 {
+		req.Lease = lease.String()
+	}
+	var s common.Service
+	err := c.do(ctx, "POST", "/services/"+url.PathEscape(id)+"/renew", nil, req, &s)
+	return s, err
+}
+
+// Inventory returns the open ports of the pool in ascending order.
+func (c *Client) Inventory(ctx context.Context) ([]int, error) {
+	return c.ports(ctx, "open")
+}
+
+// Assigned returns the assigned ports of the pool in ascending order.
+func (c *Client) Assigned(ctx context.Context) ([]int, error) {
+	return c.ports(ctx, "assigned")
+}
+
+// Cooling returns the ports of the pool cooling down after their release in
+// ascending order.
+func (c *Client) Cooling(ctx context.Context) ([]int, error) {
+	return c.ports(ctx, "cooling")
+}
+
+func (c *Client) ports(ctx context.Context, state string) ([]int, error) {
+	var list common.PortList
+	err := c.do(ctx, "GET", "/ports", url.Values{"state": {state}}, nil, &list)
+	return list.Ports, err
+}
+
+// Mappings returns the page of the mapping of ids to ports q selects.
+func (c *Client) Mappings(ctx context.Context, q MappingQuery) (common.MappingPage, error) {
+	query := url.Values{}
+	if q.Cursor != 0 {
+		query.Set("cursor", strconv.FormatUint(q.Cursor, 10))
+	}
+	if q.Count != 0 {
+		query.Set("count", strconv.Itoa(q.Count))
+	}
+	for name, value := range map[string]string{"match": q.Match, "prefix": q.Prefix, "selector": q.Selector} {
+		if len(value) > 0 {
+			query.Set(name, value)
+		}
+	}
+	var page common.MappingPage
+	err := c.do(ctx, "GET", "/mappings", query, nil, &page)
+	return page, err
+}
+
+// Quarantine returns the ports taken out of the pool because they were
+// found in use outside of port-authority.
+func (c *Client) Quarantine(ctx context.Context) ([]common.QuarantinedPort, error) {
+	var ports []common.QuarantinedPort
+	err := c.do(ctx, "GET", "/quarantine", nil, nil, &ports)
+	return ports, err
+}
+
+// Unquarantine puts a quarantined port back into the pool.
+func (c *Client) Unquarantine(ctx context.Context, port int) error {
+	return c.do(ctx, "DELETE", "/quarantine/"+strconv.Itoa(port), nil, nil, nil)
+}
+
+// Events returns the events after the one whose ID is since, every event
+// the server keeps when it is zero, waiting up to wait for one when there
+// are none yet. Without a Pool on the client they cover every pool.
+func (c *Client) Events(ctx context.Context, since int64, wait time.Duration) ([]common.Event, error) {
+	query := url.Values{}
+	if since != 0 {
+		query.Set("since", strconv.FormatInt(since, 10))
+	}
+	if wait != 0 {
+		query.Set("wait", wait.String())
+	}
+	var events []common.Event
+	err := c.do(ctx, "GET", "/events", query, nil, &events)
+	return events, err
+}
+
+// Range returns the range of ports the pool manages.
+func (c *Client) Range(ctx context.Context) (common.PortRange, error) {
+	var r common.PortRange
+	err := c.do(ctx, "GET", "/range", nil, nil, &r)
+	return r, err
+}
+
+// SetRange changes the range of ports the pool manages to start up to end,
+// keeping its exclusions. A zero start or end is left as it is.
+func (c *Client) SetRange(ctx context.Context, start, end int) (common.ReconcileReport, error) {
+	var report common.ReconcileReport
+	err := c.do(ctx, "PUT", "/range", nil, common.PortRange{Start: start, End: end}, &report)
+	return report, err
+}
+
+// Exclusions returns the ports and START-END runs of ports within the range
+// of the pool which are never handed out.
+func (c *Client) Exclusions(ctx context.Context) ([]string, error) {
+	var list common.ExclusionList
+	err := c.do(ctx, "GET", "/exclusions", nil, nil, &list)
+	return list.Excluded, err
+}
+
+// SetExclusions replaces the exclusions of the pool with excluded, ports
+// and START-END runs of ports, keeping its range.
+func (c *Client) SetExclusions(ctx context.Context, excluded []string) (common.ReconcileReport, error) {
+	if excluded == nil {
+		excluded = []string{}
+	}
+	var report common.ReconcileReport
+	err := c.do(ctx, "PUT", "/exclusions", nil, common.ExclusionList{Excluded: excluded}, &report)
+	return report, err
+}
+
+// Check returns the inconsistencies found in the data of the pool, leaving
+// them be.
+func (c *Client) Check(ctx context.Context) ([]common.Inconsistency, error) {
+	var found []common.Inconsistency
+	err := c.do(ctx, "GET", "/fsck", nil, nil, &found)
+	return found, err
+}
+
+// Repair repairs the inconsistencies found in the data of the pool and
+// returns them.
+func (c *Client) Repair(ctx context.Context) ([]common.Inconsistency, error) {
+	var found []common.Inconsistency
+	err := c.do(ctx, "POST", "/fsck", nil, nil, &found)
+	return found, err
+}
+
+// Hosts returns the hosts the pool has assigned ports on.
+func (c *Client) Hosts(ctx context.Context) ([]string, error) {
+	var hosts []string
+	err := c.do(ctx, "GET", "/hosts", nil, nil, &hosts)
+	return hosts, err
+}
+
+// Pools returns the names of the pools the server serves.
+func (c *Client) Pools(ctx context.Context) ([]string, error) {
+	var pools []string
+	err := c.do(ctx, "GET", "/pools", nil, nil, &pools)
+	return pools, err
+}
+
+// url returns the URL of path within the client's pool.
+func (c *Client) url(path string, query url.Values) string {
+	u := c.BaseURL + "/api/v2"
+	if len(c.Pool) > 0 && path != "/pools" {
+		u += "/pools/" + url.PathEscape(c.Pool)
+	}
+	u += path
+	if len(c.Host) > 0 {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("host", c.Host)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// do sends body, when not nil, as JSON to path and decodes the data of the
+// answer into data, when not nil. Requests are retried while they fail
+// with a retryable error, see Retries.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, data interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	u := c.url(path, query)
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, u, payload, data)
+		if !retryable(err) || attempt >= c.Retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryable reports whether err is the server's backend being unavailable,
+// or a 503 without a code, as a proxy answers while the server is down.
+func retryable(err error) bool {
+	e, ok := err.(*Error)
+	if !ok || e.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	return e.Code == common.CodeUnavailable || len(e.Code) == 0
+}
+
+// response is common.Response with its data left to decode.
+type response struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Code    string          `json:"code"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// send makes a single request, see do.
+func (c *Client) send(ctx context.Context, method, u string, payload []byte, data interface{}) error {
+	req, err := http.NewRequest(method, u, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var r response
+	decodeErr := json.NewDecoder(resp.Body).Decode(&r)
+	if resp.StatusCode != http.StatusOK {
+		if decodeErr != nil || len(r.Message) == 0 {
+			r.Message = http.StatusText(resp.StatusCode)
+		}
+		return &Error{StatusCode: resp.StatusCode, Code: r.Code, Message: r.Message, Data: r.Data}
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("Unexpected answer from port-authority: %v", decodeErr)
+	}
+	if data != nil && len(r.Data) > 0 {
+		return json.Unmarshal(r.Data, data)
+	}
+	return nil
+}
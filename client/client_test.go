@@ -0,0 +1,459 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/therealbill/port-authority/handlers"
+	"github.com/zenazn/goji/web"
+)
+
+// newServer serves the /api/v2 routes over memory stores: the default pool
+// with the ports from 30000 up to 30010, "tiny" with a single port and
+// "cool", whose released ports cool down for an hour. It returns a client
+// talking to it and the store of the default pool.
+func newServer(t *testing.T) (*Client, *actions.MemoryStore) {
+	t.Helper()
+	store := actions.NewMemoryStore()
+	tiny := actions.NewMemoryStore()
+	cool := actions.NewMemoryStore()
+	cool.SetCooldown(time.Hour)
+	for s, size := range map[*actions.MemoryStore]int{store: 10, tiny: 1, cool: 10} {
+		if err := s.InitializePorts(30000, 30000+size, nil); err != nil {
+			t.Fatalf("InitializePorts(): %v", err)
+		}
+	}
+	api := handlers.NewAPI(nil)
+	api.Pools = actions.Pools{
+		actions.DefaultPool: actions.NewEventStore(store, actions.DefaultPool, api.Events),
+		"tiny":              tiny,
+		"cool":              cool,
+	}
+	mux := web.New()
+	handlers.NewV2(api).Route(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	c := New(server.URL)
+	c.RetryDelay = time.Millisecond
+	return c, store
+}
+
+// serviceIDs returns the keys of services, sorted.
+func serviceIDs(services map[string]bool) []string {
+	var ids []string
+	for id := range services {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestAllocatePort(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	s, err := c.AllocatePort(ctx, "web", AllocateOptions{Lease: time.Minute, Labels: map[string]string{"team": "cars"}})
+	if err != nil || s.ID != "web" || s.Port < 30000 || s.Port >= 30010 || s.Expires == nil || s.Labels["team"] != "cars" {
+		t.Fatalf("AllocatePort() = %+v, %v, want a leased and labelled port", s, err)
+	}
+	if again, err := c.AllocatePort(ctx, "web", AllocateOptions{}); err != nil || again.Port != s.Port {
+		t.Errorf("AllocatePort() again = %+v, %v, want port %d", again, err, s.Port)
+	}
+	first := 30008
+	if s.Port >= first {
+		first = 30000
+	}
+	block, err := c.AllocatePort(ctx, "db", AllocateOptions{Block: 2, Port: first})
+	if err != nil || !reflect.DeepEqual(block.Ports, []int{first, first + 1}) {
+		t.Errorf("AllocatePort(block) = %+v, %v, want ports %d and %d", block, err, first, first+1)
+	}
+	named, err := c.AllocatePort(ctx, "app", AllocateOptions{Names: []string{"http", "admin"}})
+	if err != nil || len(named.Named) != 2 {
+		t.Errorf("AllocatePort(names) = %+v, %v, want two named ports", named, err)
+	}
+}
+
+func TestAllocatePortErrors(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{Port: 30005}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	_, err := c.AllocatePort(ctx, "db", AllocateOptions{Port: 30005})
+	if e, ok := err.(*Error); !IsConflict(err) || !ok || e.StatusCode != http.StatusConflict || e.Owner() != "web" {
+		t.Errorf("AllocatePort() of a held port = %v, want a conflict with web", err)
+	}
+	if _, err := c.AllocatePort(ctx, "db", AllocateOptions{Block: -1}); !IsInvalid(err) {
+		t.Errorf("AllocatePort() of a negative block = %v, want it invalid", err)
+	}
+
+	c.Pool = "tiny"
+	if _, err := c.AllocatePort(ctx, "a", AllocateOptions{}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	_, err = c.AllocatePort(ctx, "b", AllocateOptions{})
+	if e, ok := err.(*Error); !IsExhausted(err) || !ok || e.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("AllocatePort() from an exhausted pool = %v, want it exhausted", err)
+	}
+
+	c.Pool = "nope"
+	if _, err := c.AllocatePort(ctx, "a", AllocateOptions{}); !IsNotFound(err) {
+		t.Errorf("AllocatePort() in an unknown pool = %v, want it not found", err)
+	}
+}
+
+func TestAllocateServices(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	services, err := c.AllocateServices(ctx, []Allocation{
+		{ID: "a", Options: AllocateOptions{Port: 30001}},
+		{ID: "b", Options: AllocateOptions{Lease: time.Minute}},
+	})
+	if err != nil || len(services) != 2 || services["a"].Port != 30001 || services["b"].Expires == nil {
+		t.Fatalf("AllocateServices() = %+v, %v, want a on 30001 and b leased", services, err)
+	}
+	_, err = c.AllocateServices(ctx, []Allocation{{ID: "c"}, {ID: "d", Options: AllocateOptions{Port: 30001}}})
+	if e, ok := err.(*Error); !IsConflict(err) || !ok || e.FailedID() != "d" {
+		t.Errorf("AllocateServices() with a held port = %v, want a conflict failing d", err)
+	}
+	if _, err := c.LookupService(ctx, "c"); !IsNotFound(err) {
+		t.Errorf("LookupService() of a rolled back service = %v, want it not found", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	s, err := c.AllocatePort(ctx, "web", AllocateOptions{})
+	if err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	if got, err := c.LookupService(ctx, "web"); err != nil || got.Port != s.Port {
+		t.Errorf("LookupService() = %+v, %v, want port %d", got, err, s.Port)
+	}
+	if _, err := c.LookupService(ctx, "nobody"); !IsNotFound(err) {
+		t.Errorf("LookupService() of an unknown service = %v, want it not found", err)
+	}
+	if id, err := c.LookupPort(ctx, s.Port); err != nil || id != "web" {
+		t.Errorf("LookupPort() = %q, %v, want web", id, err)
+	}
+	if _, err := c.LookupPort(ctx, 39999); !IsNotFound(err) {
+		t.Errorf("LookupPort() of a free port = %v, want it not found", err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := c.AllocatePort(ctx, id, AllocateOptions{}); err != nil {
+			t.Fatalf("AllocatePort(): %v", err)
+		}
+	}
+	if err := c.Release(ctx, "a"); err != nil {
+		t.Errorf("Release(): %v", err)
+	}
+	if err := c.Release(ctx, "a"); !IsNotFound(err) {
+		t.Errorf("Release() again = %v, want it not found", err)
+	}
+	released, err := c.ReleaseServices(ctx, []string{"b", "nobody"})
+	if want := map[string]bool{"b": true, "nobody": false}; err != nil || !reflect.DeepEqual(released, want) {
+		t.Errorf("ReleaseServices() = %v, %v, want %v", released, err, want)
+	}
+	if assigned, err := c.Assigned(ctx); err != nil || len(assigned) != 1 {
+		t.Errorf("Assigned() = %v, %v, want c's port only", assigned, err)
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	for id, team := range map[string]string{"a": "payments", "b": "payments", "c": "search"} {
+		if _, err := c.AllocatePort(ctx, id, AllocateOptions{Labels: map[string]string{"team": team}}); err != nil {
+			t.Fatalf("AllocatePort(): %v", err)
+		}
+	}
+	services, err := c.Services(ctx, "team=payments")
+	selected := make(map[string]bool)
+	for _, s := range services {
+		selected[s.ID] = true
+	}
+	if want := []string{"a", "b"}; err != nil || !reflect.DeepEqual(serviceIDs(selected), want) {
+		t.Errorf("Services() = %v, %v, want %v", serviceIDs(selected), err, want)
+	}
+	if all, err := c.Services(ctx, ""); err != nil || len(all) != 3 {
+		t.Errorf("Services(\"\") = %v, %v, want every service", all, err)
+	}
+	if _, err := c.Services(ctx, "team in (a"); !IsInvalid(err) {
+		t.Errorf("Services() with a bad selector = %v, want it invalid", err)
+	}
+
+	released, err := c.ReleaseSelected(ctx, "team=search")
+	if err != nil || !reflect.DeepEqual(released, []string{"c"}) {
+		t.Errorf("ReleaseSelected() = %v, %v, want c", released, err)
+	}
+	if _, err := c.ReleaseSelected(ctx, ""); !IsInvalid(err) {
+		t.Errorf("ReleaseSelected(\"\") = %v, want it invalid", err)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{Lease: time.Minute}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	s, err := c.Renew(ctx, "web", time.Hour)
+	if err != nil || s.Expires == nil || s.Expires.Before(time.Now().Add(50*time.Minute)) {
+		t.Errorf("Renew(1h) = %+v, %v, want it to expire in an hour", s, err)
+	}
+	if s, err := c.Renew(ctx, "web", 0); err != nil || s.Expires == nil {
+		t.Errorf("Renew(0) = %+v, %v, want it renewed", s, err)
+	}
+	if _, err := c.Renew(ctx, "nobody", time.Minute); !IsNotFound(err) {
+		t.Errorf("Renew() of an unknown service = %v, want it not found", err)
+	}
+}
+
+func TestPorts(t *testing.T) {
+	c, store := newServer(t)
+	ctx := context.Background()
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{Port: 30000}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	if open, err := c.Inventory(ctx); err != nil || len(open) != 9 || open[0] != 30001 {
+		t.Errorf("Inventory() = %v, %v, want the 9 ports from 30001 on", open, err)
+	}
+	if assigned, err := c.Assigned(ctx); err != nil || !reflect.DeepEqual(assigned, []int{30000}) {
+		t.Errorf("Assigned() = %v, %v, want 30000", assigned, err)
+	}
+
+	page, err := c.Mappings(ctx, MappingQuery{Prefix: "we"})
+	if err != nil || !reflect.DeepEqual(page.Mappings, map[string]int{"web": 30000}) || page.Cursor != 0 {
+		t.Errorf("Mappings() = %+v, %v, want web on 30000", page, err)
+	}
+	if _, err := c.Mappings(ctx, MappingQuery{Count: -1}); !IsInvalid(err) {
+		t.Errorf("Mappings() with a negative count = %v, want it invalid", err)
+	}
+
+	if err := store.Quarantine(30005); err != nil {
+		t.Fatalf("Quarantine(): %v", err)
+	}
+	if q, err := c.Quarantine(ctx); err != nil || len(q) != 1 || q[0].Port != 30005 {
+		t.Errorf("Quarantine() = %+v, %v, want 30005", q, err)
+	}
+	if err := c.Unquarantine(ctx, 30005); err != nil {
+		t.Errorf("Unquarantine(): %v", err)
+	}
+	if err := c.Unquarantine(ctx, 30005); !IsNotFound(err) {
+		t.Errorf("Unquarantine() again = %v, want it not found", err)
+	}
+
+	c.Pool = "cool"
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{Port: 30003}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	if err := c.Release(ctx, "web"); err != nil {
+		t.Fatalf("Release(): %v", err)
+	}
+	if cooling, err := c.Cooling(ctx); err != nil || !reflect.DeepEqual(cooling, []int{30003}) {
+		t.Errorf("Cooling() = %v, %v, want 30003", cooling, err)
+	}
+}
+
+func TestPoolsAndHosts(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	if pools, err := c.Pools(ctx); err != nil || !reflect.DeepEqual(pools, []string{"cool", "default", "tiny"}) {
+		t.Errorf("Pools() = %v, %v, want cool, default and tiny", pools, err)
+	}
+	c.Host = "node1"
+	if _, err := c.LookupService(ctx, "web"); !IsNotFound(err) {
+		t.Errorf("LookupService() on a new host = %v, want it not found", err)
+	}
+	c.Host = ""
+	if hosts, err := c.Hosts(ctx); err != nil || len(hosts) != 0 {
+		t.Errorf("Hosts() after a lookup = %v, %v, want none", hosts, err)
+	}
+	c.Host = "node1"
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{Port: 30000}); err != nil {
+		t.Fatalf("AllocatePort() on a host: %v", err)
+	}
+	c.Host = ""
+	if _, err := c.AllocatePort(ctx, "db", AllocateOptions{Port: 30000}); err != nil {
+		t.Errorf("AllocatePort() of a port held on a host: %v", err)
+	}
+	if hosts, err := c.Hosts(ctx); err != nil || !reflect.DeepEqual(hosts, []string{"node1"}) {
+		t.Errorf("Hosts() = %v, %v, want node1", hosts, err)
+	}
+}
+
+func TestRangeAndExclusions(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	if r, err := c.Range(ctx); err != nil || r.Start != 30000 || r.End != 30010 {
+		t.Errorf("Range() = %+v, %v, want 30000 to 30010", r, err)
+	}
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{Port: 30009}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	report, err := c.SetRange(ctx, 0, 30008)
+	if err != nil || report.Start != 30000 || report.End != 30008 || !reflect.DeepEqual(report.Removed, []int{30008}) || report.Stranded["30009"] != "web" {
+		t.Errorf("SetRange(0, 30008) = %+v, %v, want 30008 removed and web stranded on 30009", report, err)
+	}
+	if r, err := c.Range(ctx); err != nil || r.Start != 30000 || r.End != 30008 {
+		t.Errorf("Range() after SetRange() = %+v, %v, want 30000 to 30008", r, err)
+	}
+	if _, err := c.SetRange(ctx, 30008, 30000); !IsInvalid(err) {
+		t.Errorf("SetRange() backwards = %v, want it invalid", err)
+	}
+
+	report, err = c.SetExclusions(ctx, []string{"30001", "30004-30005"})
+	if err != nil || !reflect.DeepEqual(report.Excluded, []string{"30001", "30004-30005"}) || !reflect.DeepEqual(report.Removed, []int{30001, 30004, 30005}) {
+		t.Errorf("SetExclusions() = %+v, %v, want 30001, 30004 and 30005 removed", report, err)
+	}
+	if excluded, err := c.Exclusions(ctx); err != nil || !reflect.DeepEqual(excluded, []string{"30001", "30004-30005"}) {
+		t.Errorf("Exclusions() = %v, %v, want 30001 and 30004-30005", excluded, err)
+	}
+	if inventory, err := c.Inventory(ctx); err != nil || !reflect.DeepEqual(inventory, []int{30000, 30002, 30003, 30006, 30007}) {
+		t.Errorf("Inventory() with exclusions = %v, %v", inventory, err)
+	}
+	if _, err := c.SetExclusions(ctx, []string{"http"}); !IsInvalid(err) {
+		t.Errorf("SetExclusions() of a name = %v, want it invalid", err)
+	}
+	if report, err := c.SetExclusions(ctx, nil); err != nil || len(report.Excluded) != 0 || len(report.Added) != 3 {
+		t.Errorf("SetExclusions(nil) = %+v, %v, want the 3 excluded ports put back", report, err)
+	}
+}
+
+// brokenStore is a PortStore in which Check finds a port assigned to
+// nobody until it is repaired.
+type brokenStore struct {
+	actions.PortStore
+	repaired bool
+}
+
+func (s *brokenStore) Check(repair bool) ([]actions.Inconsistency, error) {
+	if s.repaired {
+		return nil, nil
+	}
+	s.repaired = repair
+	return []actions.Inconsistency{{Kind: "orphan_port", Port: "30001", Detail: "Port 30001 is assigned to nobody", Repair: "Freed it"}}, nil
+}
+
+func TestCheckAndRepair(t *testing.T) {
+	api := handlers.NewAPI(nil)
+	api.Pools = actions.Pools{actions.DefaultPool: &brokenStore{PortStore: actions.NewMemoryStore()}}
+	mux := web.New()
+	handlers.NewV2(api).Route(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := New(server.URL)
+	ctx := context.Background()
+
+	want := []common.Inconsistency{{Kind: "orphan_port", Port: "30001", Detail: "Port 30001 is assigned to nobody", Repair: "Freed it"}}
+	for i := 0; i < 2; i++ {
+		if found, err := c.Check(ctx); err != nil || !reflect.DeepEqual(found, want) {
+			t.Errorf("Check() = %+v, %v, want %+v left unrepaired", found, err, want)
+		}
+	}
+	if found, err := c.Repair(ctx); err != nil || !reflect.DeepEqual(found, want) {
+		t.Errorf("Repair() = %+v, %v, want %+v", found, err, want)
+	}
+	if found, err := c.Check(ctx); err != nil || len(found) != 0 {
+		t.Errorf("Check() after Repair() = %+v, %v, want nothing", found, err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	if _, err := c.AllocatePort(ctx, "web", AllocateOptions{}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	if err := c.Release(ctx, "web"); err != nil {
+		t.Fatalf("Release(): %v", err)
+	}
+	events, err := c.Events(ctx, 0, 0)
+	if err != nil || len(events) != 2 || events[0].Name != actions.EventAllocated || events[1].Name != actions.EventReleased || events[1].Data["id"] != "web" {
+		t.Fatalf("Events() = %+v, %v, want web allocated and released", events, err)
+	}
+	if later, err := c.Events(ctx, events[1].ID, 10*time.Millisecond); err != nil || len(later) != 0 {
+		t.Errorf("Events() after the last one = %+v, %v, want none", later, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `{"status":"Unavailable","message":"Redis is down","code":"unavailable"}`)
+			return
+		}
+		io.WriteString(w, `{"status":"data","message":"success","data":["default"]}`)
+	}))
+	defer server.Close()
+	c := New(server.URL)
+	c.RetryDelay = time.Millisecond
+	if pools, err := c.Pools(context.Background()); err != nil || !reflect.DeepEqual(pools, []string{"default"}) || attempts != 3 {
+		t.Errorf("Pools() = %v, %v after %d attempts, want it to succeed on the third", pools, err, attempts)
+	}
+
+	attempts = 0
+	c.Retries = 1
+	_, err := c.Pools(context.Background())
+	if e, ok := err.(*Error); !IsUnavailable(err) || !ok || e.Message != "Redis is down" || attempts != 2 {
+		t.Errorf("Pools() = %v after %d attempts, want it unavailable after 2", err, attempts)
+	}
+}
+
+func TestNoRetryWhenExhausted(t *testing.T) {
+	c, _ := newServer(t)
+	ctx := context.Background()
+	c.Pool = "tiny"
+	if _, err := c.AllocatePort(ctx, "a", AllocateOptions{}); err != nil {
+		t.Fatalf("AllocatePort(): %v", err)
+	}
+	c.RetryDelay = time.Hour
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.AllocatePort(ctx, "b", AllocateOptions{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !IsExhausted(err) {
+			t.Errorf("AllocatePort() from an exhausted pool = %v, want it exhausted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("AllocatePort() from an exhausted pool is being retried")
+	}
+}
+
+func TestErrorWithoutCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, "<html>Bad Gateway</html>")
+	}))
+	defer server.Close()
+	_, err := New(server.URL).Pools(context.Background())
+	e, ok := err.(*Error)
+	if !ok || e.StatusCode != http.StatusBadGateway || e.Code != "" || e.Message != "Bad Gateway" {
+		t.Fatalf("Pools() = %v, want a 502 without a code", err)
+	}
+	if IsNotFound(err) || IsConflict(err) || IsExhausted(err) || IsInvalid(err) || IsUnavailable(err) {
+		t.Errorf("%v matches a code, want none", err)
+	}
+	if e.Owner() != "" || e.FailedID() != "" {
+		t.Errorf("Owner() = %q, FailedID() = %q, want both empty", e.Owner(), e.FailedID())
+	}
+}
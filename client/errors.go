@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/therealbill/port-authority/common"
+)
+
+// Error is returned for a request the server answered with an error.
+type Error struct {
+	// StatusCode is the HTTP status of the answer.
+	StatusCode int
+	// Code is one of the common Code constants, empty if the server didn't
+	// say, e.g. because a proxy answered.
+	Code    string
+	Message string
+	// Data holds the details some errors come with, such as the id holding
+	// a port for a conflict. See Owner and FailedID.
+	Data json.RawMessage
+}
+
+func (e *Error) Error() string {
+	if len(e.Code) == 0 {
+		return fmt.Sprintf("port-authority answered %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("port-authority answered %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Owner returns the id holding the port a conflicting allocation asked for,
+// if the server named it.
+func (e *Error) Owner() string {
+	return e.dataString()
+}
+
+// FailedID returns the id of the service a bulk allocation failed for, if
+// the server named it.
+func (e *Error) FailedID() string {
+	return e.dataString()
+}
+
+func (e *Error) dataString() string {
+	var s string
+	json.Unmarshal(e.Data, &s)
+	return s
+}
+
+// hasCode reports whether err is an *Error with the given code.
+func hasCode(err error, code string) bool {
+	e, ok := err.(*Error)
+	return ok && e.Code == code
+}
+
+// IsInvalid reports whether err is the server refusing a request which can
+// never succeed.
+func IsInvalid(err error) bool {
+	return hasCode(err, common.CodeInvalid)
+}
+
+// IsNotFound reports whether err is the server not finding a service, port
+// or pool.
+func IsNotFound(err error) bool {
+	return hasCode(err, common.CodeNotFound)
+}
+
+// IsConflict reports whether err is the server refusing a request the state
+// of the pool doesn't allow, e.g. for a port held by another service.
+func IsConflict(err error) bool {
+	return hasCode(err, common.CodeConflict)
+}
+
+// IsExhausted reports whether err is the server having no port left for an
+// allocation.
+func IsExhausted(err error) bool {
+	return hasCode(err, common.CodeExhausted)
+}
+
+// IsUnavailable reports whether err is the server's backend being
+// unavailable.
+func IsUnavailable(err error) bool {
+	return hasCode(err, common.CodeUnavailable)
+}
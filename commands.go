@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/therealbill/port-authority/client"
+	"github.com/therealbill/port-authority/common"
+)
+
+// clientFlags are the flags of every subcommand talking to a running server.
+var clientFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "server,s",
+		Usage:  "URL of the port-authority server",
+		EnvVar: "PA_SERVER",
+		Value:  "http://localhost:8080",
+	},
+	cli.StringFlag{
+		Name:   "pool,p",
+		Usage:  "Pool to work on, the default pool when not set",
+		EnvVar: "PA_POOL",
+	},
+	cli.StringFlag{
+		Name:   "host",
+		Usage:  "Work on the ports of this host within the pool",
+		EnvVar: "PA_HOST",
+	},
+	cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print JSON instead of a table",
+	},
+	cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "How long to wait for the server, retries included",
+		Value: 30 * time.Second,
+	},
+}
+
+// clientCommands returns the subcommands talking to a running server. They
+// exit non-zero when the server answers with an error.
+func clientCommands() []cli.Command {
+	return []cli.Command{
+		{
+			Name:      "allocate",
+			Usage:     "Assign ports to a service, or show those it holds",
+			ArgsUsage: "ID",
+			Action:    allocate,
+			Flags: append([]cli.Flag{
+				cli.DurationFlag{
+					Name:  "lease",
+					Usage: "Make the assignment expire unless renewed within this duration",
+				},
+				cli.IntFlag{
+					Name:  "port",
+					Usage: "Require this port",
+				},
+				cli.BoolFlag{
+					Name:  "prefer",
+					Usage: "Only prefer the port given with --port",
+				},
+				cli.IntFlag{
+					Name:  "block",
+					Usage: "Assign this many contiguous ports",
+					Value: 1,
+				},
+				cli.StringFlag{
+					Name:  "names",
+					Usage: "Assign a port to each of these comma separated names",
+				},
+				cli.StringSliceFlag{
+					Name:  "label,l",
+					Usage: "Store the KEY=VALUE label with the service, may be repeated",
+				},
+				cli.DurationFlag{
+					Name:  "wait",
+					Usage: "Wait up to this long for a port when the pool is exhausted",
+				},
+				cli.BoolFlag{
+					Name:  "quiet,q",
+					Usage: "Only print the ports, NAME=PORT for named ports",
+				},
+			}, clientFlags...),
+		},
+		{
+			Name:      "lookup",
+			Usage:     "Show the ports held by a service",
+			ArgsUsage: "ID",
+			Action:    lookup,
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "quiet,q",
+					Usage: "Only print the ports, NAME=PORT for named ports",
+				},
+			}, clientFlags...),
+		},
+		{
+			Name:      "whois",
+			Usage:     "Show the service holding a port",
+			ArgsUsage: "PORT",
+			Action:    whois,
+			Flags:     clientFlags,
+		},
+		{
+			Name:      "release",
+			Usage:     "Release the ports held by services",
+			ArgsUsage: "ID [ID...]",
+			Action:    release,
+			Flags:     clientFlags,
+		},
+		{
+			Name:   "inventory",
+			Usage:  "List the open ports of the pool",
+			Action: inventory,
+			Flags:  clientFlags,
+		},
+		{
+			Name:   "assigned",
+			Usage:  "List the assigned ports of the pool and the services holding them",
+			Action: assigned,
+			Flags:  clientFlags,
+		},
+	}
+}
+
+// newClient returns a client for the server and pool given by the flags of
+// c, and the context to make requests in.
+func newClient(c *cli.Context, extra time.Duration) (*client.Client, context.Context, context.CancelFunc) {
+	cl := client.New(c.String("server"))
+	cl.Pool = c.String("pool")
+	cl.Host = c.String("host")
+	ctx, cancel := context.WithTimeout(context.Background(), c.Duration("timeout")+extra)
+	return cl, ctx, cancel
+}
+
+// exitOnError prints err and exits non-zero unless err is nil.
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// oneArg returns the only argument of c, exiting with the usage when there
+// isn't exactly one.
+func oneArg(c *cli.Context) string {
+	if len(c.Args()) != 1 {
+		cli.ShowCommandHelp(c, c.Command.Name)
+		os.Exit(1)
+	}
+	return c.Args().First()
+}
+
+// output prints v as JSON with --json, else calls table to print it as a
+// table.
+func output(c *cli.Context, v interface{}, table func(tw *tabwriter.Writer)) {
+	if c.Bool("json") {
+		packed, _ := json.MarshalIndent(v, "", "  ")
+		fmt.Println(string(packed))
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	table(tw)
+	tw.Flush()
+}
+
+// printService prints s, only its ports with --quiet.
+func printService(c *cli.Context, s common.Service) {
+	if c.Bool("quiet") {
+		for _, port := range servicePorts(s) {
+			fmt.Println(port)
+		}
+		return
+	}
+	output(c, s, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tPORTS\tEXPIRES\tLABELS")
+		expires := ""
+		if s.Expires != nil {
+			expires = s.Expires.Local().Format(time.RFC3339)
+		}
+		var labels []string
+		for key, value := range s.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		sort.Strings(labels)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.ID, strings.Join(servicePorts(s), ","), expires, strings.Join(labels, ","))
+	})
+}
+
+// servicePorts lists the ports of s, as NAME=PORT for named ports.
+func servicePorts(s common.Service) []string {
+	var ports []string
+	switch {
+	case len(s.Named) > 0:
+		for name, port := range s.Named {
+			ports = append(ports, fmt.Sprintf("%s=%d", name, port))
+		}
+		sort.Strings(ports)
+	case len(s.Ports) > 0:
+		for _, port := range s.Ports {
+			ports = append(ports, strconv.Itoa(port))
+		}
+	default:
+		ports = append(ports, strconv.Itoa(s.Port))
+	}
+	return ports
+}
+
+func allocate(c *cli.Context) {
+	id := oneArg(c)
+	opts := client.AllocateOptions{
+		Lease:  c.Duration("lease"),
+		Port:   c.Int("port"),
+		Prefer: c.Bool("prefer"),
+		Block:  c.Int("block"),
+		Wait:   c.Duration("wait"),
+	}
+	if names := c.String("names"); len(names) > 0 {
+		opts.Names = strings.Split(names, ",")
+	}
+	for _, label := range c.StringSlice("label") {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 {
+			exitOnError(fmt.Errorf("Invalid label '%s', use KEY=VALUE", label))
+		}
+		if opts.Labels == nil {
+			opts.Labels = make(map[string]string)
+		}
+		opts.Labels[kv[0]] = kv[1]
+	}
+	cl, ctx, cancel := newClient(c, opts.Wait)
+	defer cancel()
+	s, err := cl.AllocatePort(ctx, id, opts)
+	exitOnError(err)
+	printService(c, s)
+}
+
+func lookup(c *cli.Context) {
+	id := oneArg(c)
+	cl, ctx, cancel := newClient(c, 0)
+	defer cancel()
+	s, err := cl.LookupService(ctx, id)
+	exitOnError(err)
+	printService(c, s)
+}
+
+func whois(c *cli.Context) {
+	port, err := strconv.Atoi(oneArg(c))
+	if err != nil {
+		exitOnError(fmt.Errorf("Invalid port '%s'", c.Args().First()))
+	}
+	cl, ctx, cancel := newClient(c, 0)
+	defer cancel()
+	id, err := cl.LookupPort(ctx, port)
+	exitOnError(err)
+	output(c, common.PortOwner{Port: port, ID: id}, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "PORT\tID")
+		fmt.Fprintf(tw, "%d\t%s\n", port, id)
+	})
+}
+
+// release releases every service given and exits non-zero if any of them
+// held no ports.
+func release(c *cli.Context) {
+	ids := []string(c.Args())
+	if len(ids) == 0 {
+		cli.ShowCommandHelp(c, c.Command.Name)
+		os.Exit(1)
+	}
+	cl, ctx, cancel := newClient(c, 0)
+	defer cancel()
+	released, err := cl.ReleaseServices(ctx, ids)
+	exitOnError(err)
+	output(c, released, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tRELEASED")
+		for _, id := range ids {
+			fmt.Fprintf(tw, "%s\t%t\n", id, released[id])
+		}
+	})
+	for _, id := range ids {
+		if !released[id] {
+			exitOnError(fmt.Errorf("No port is assigned to '%s'", id))
+		}
+	}
+}
+
+func inventory(c *cli.Context) {
+	cl, ctx, cancel := newClient(c, 0)
+	defer cancel()
+	ports, err := cl.Inventory(ctx)
+	exitOnError(err)
+	output(c, ports, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "PORT")
+		for _, port := range ports {
+			fmt.Fprintln(tw, port)
+		}
+	})
+}
+
+// assigned lists the ids holding ports along with their port, which is the
+// first one for a block.
+func assigned(c *cli.Context) {
+	cl, ctx, cancel := newClient(c, 0)
+	defer cancel()
+	mappings := make(map[string]int)
+	var cursor uint64
+	for {
+		page, err := cl.Mappings(ctx, client.MappingQuery{Cursor: cursor, Count: 1000})
+		exitOnError(err)
+		for id, port := range page.Mappings {
+			mappings[id] = port
+		}
+		if cursor = page.Cursor; cursor == 0 {
+			break
+		}
+	}
+	output(c, mappings, func(tw *tabwriter.Writer) {
+		ids := make([]string, 0, len(mappings))
+		for id := range mappings {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return mappings[ids[i]] < mappings[ids[j]] })
+		fmt.Fprintln(tw, "PORT\tID")
+		for _, id := range ids {
+			fmt.Fprintf(tw, "%d\t%s\n", mappings[id], id)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codegangsta/cli"
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/therealbill/port-authority/handlers"
+	"github.com/zenazn/goji/web"
+)
+
+// commandEnv holds the arguments of the client command the test binary runs
+// instead of the tests, one per line, see runCommand.
+const commandEnv = "PA_TEST_COMMAND"
+
+func TestMain(m *testing.M) {
+	if args := os.Getenv(commandEnv); len(args) > 0 {
+		app := cli.NewApp()
+		app.Commands = clientCommands()
+		app.Run(append([]string{"port-authority"}, strings.Split(args, "\n")...))
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newCommandServer serves the /api/v2 routes over a memory store holding the
+// ports from 30000 up to 30010 and returns its URL.
+func newCommandServer(t *testing.T) string {
+	t.Helper()
+	store := actions.NewMemoryStore()
+	store.SetStrategy(actions.StrategyLowest)
+	if err := store.InitializePorts(30000, 30010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	api := handlers.NewAPI(nil)
+	api.Pools = actions.Pools{actions.DefaultPool: store}
+	mux := web.New()
+	handlers.NewV2(api).Route(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// runCommand runs the client command args against the server in a process
+// of its own, as the commands exit on errors, and returns what it printed
+// on stdout and stderr and its exit code.
+func runCommand(t *testing.T, server string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), commandEnv+"="+strings.Join(args, "\n"), "PA_SERVER="+server, "PA_POOL=", "PA_HOST=")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if e, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), stderr.String(), e.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("Running %v: %v", args, err)
+	}
+	return stdout.String(), stderr.String(), 0
+}
+
+// table returns the rows of a table printed by a command, split into
+// columns.
+func table(out string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		rows = append(rows, strings.Fields(line))
+	}
+	return rows
+}
+
+// checkFails fails the test unless the command args exits with 1 and an
+// error containing message, printing nothing else.
+func checkFails(t *testing.T, server, message string, args ...string) {
+	t.Helper()
+	stdout, stderr, code := runCommand(t, server, args...)
+	if code != 1 || !strings.Contains(stderr, message) || len(stdout) > 0 {
+		t.Errorf("%v = exit %d, stdout %q, stderr %q, want exit 1 and an error containing %q", args, code, stdout, stderr, message)
+	}
+}
+
+func TestAllocateCommand(t *testing.T) {
+	server := newCommandServer(t)
+	stdout, _, code := runCommand(t, server, "allocate", "--port", "30005", "--label", "team=cars", "web")
+	want := [][]string{{"ID", "PORTS", "EXPIRES", "LABELS"}, {"web", "30005", "team=cars"}}
+	if got := table(stdout); code != 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("allocate = exit %d, %v, want %v", code, got, want)
+	}
+
+	stdout, _, code = runCommand(t, server, "allocate", "--json", "web")
+	var s common.Service
+	if err := json.Unmarshal([]byte(stdout), &s); code != 0 || err != nil || s.ID != "web" || s.Port != 30005 || s.Labels["team"] != "cars" {
+		t.Errorf("allocate --json = exit %d, %q, want web on 30005", code, stdout)
+	}
+
+	stdout, _, code = runCommand(t, server, "allocate", "--names", "http,admin", "-q", "app")
+	if code != 0 || stdout != "admin=30001\nhttp=30000\n" {
+		t.Errorf("allocate --names -q = exit %d, %q, want the named ports", code, stdout)
+	}
+
+	checkFails(t, server, "'web'", "allocate", "--port", "30005", "db")
+	checkFails(t, server, "Invalid label", "allocate", "--label", "team", "db")
+}
+
+func TestLookupCommand(t *testing.T) {
+	server := newCommandServer(t)
+	if _, _, code := runCommand(t, server, "allocate", "--block", "2", "db"); code != 0 {
+		t.Fatalf("allocate exited with %d", code)
+	}
+	stdout, _, code := runCommand(t, server, "lookup", "db")
+	if got := table(stdout); code != 0 || len(got) != 2 || !reflect.DeepEqual(got[1], []string{"db", "30000,30001"}) {
+		t.Errorf("lookup = exit %d, %v, want db on 30000 and 30001", code, got)
+	}
+	stdout, _, code = runCommand(t, server, "lookup", "--json", "db")
+	var s common.Service
+	if err := json.Unmarshal([]byte(stdout), &s); code != 0 || err != nil || !reflect.DeepEqual(s.Ports, []int{30000, 30001}) {
+		t.Errorf("lookup --json = exit %d, %q, want db on 30000 and 30001", code, stdout)
+	}
+	checkFails(t, server, "Error:", "lookup", "nobody")
+}
+
+func TestWhoisCommand(t *testing.T) {
+	server := newCommandServer(t)
+	if _, _, code := runCommand(t, server, "allocate", "web"); code != 0 {
+		t.Fatalf("allocate exited with %d", code)
+	}
+	stdout, _, code := runCommand(t, server, "whois", "30000")
+	if want := [][]string{{"PORT", "ID"}, {"30000", "web"}}; code != 0 || !reflect.DeepEqual(table(stdout), want) {
+		t.Errorf("whois = exit %d, %q, want web", code, stdout)
+	}
+	stdout, _, code = runCommand(t, server, "whois", "--json", "30000")
+	var owner common.PortOwner
+	if err := json.Unmarshal([]byte(stdout), &owner); code != 0 || err != nil || owner.Port != 30000 || owner.ID != "web" {
+		t.Errorf("whois --json = exit %d, %q, want web", code, stdout)
+	}
+	checkFails(t, server, "Error:", "whois", "30009")
+	checkFails(t, server, "Invalid port", "whois", "http")
+}
+
+func TestReleaseCommand(t *testing.T) {
+	server := newCommandServer(t)
+	for _, id := range []string{"web", "db"} {
+		if _, _, code := runCommand(t, server, "allocate", id); code != 0 {
+			t.Fatalf("allocate %s exited with %d", id, code)
+		}
+	}
+	stdout, _, code := runCommand(t, server, "release", "web")
+	if want := [][]string{{"ID", "RELEASED"}, {"web", "true"}}; code != 0 || !reflect.DeepEqual(table(stdout), want) {
+		t.Errorf("release = exit %d, %q, want web released", code, stdout)
+	}
+
+	stdout, stderr, code := runCommand(t, server, "release", "--json", "db", "nobody")
+	var released map[string]bool
+	if err := json.Unmarshal([]byte(stdout), &released); err != nil || !reflect.DeepEqual(released, map[string]bool{"db": true, "nobody": false}) {
+		t.Errorf("release --json = %q, want db released and nobody not", stdout)
+	}
+	if code != 1 || !strings.Contains(stderr, "'nobody'") {
+		t.Errorf("release of a service holding nothing = exit %d, %q, want exit 1 naming it", code, stderr)
+	}
+}
+
+func TestInventoryAndAssignedCommands(t *testing.T) {
+	server := newCommandServer(t)
+	for _, args := range [][]string{{"allocate", "--port", "30003", "web"}, {"allocate", "--port", "30001", "db"}} {
+		if _, _, code := runCommand(t, server, args...); code != 0 {
+			t.Fatalf("%v exited with %d", args, code)
+		}
+	}
+
+	stdout, _, code := runCommand(t, server, "inventory")
+	rows := table(stdout)
+	if code != 0 || len(rows) != 9 || rows[0][0] != "PORT" || rows[1][0] != "30000" || rows[2][0] != "30002" {
+		t.Errorf("inventory = exit %d, %q, want the 8 open ports", code, stdout)
+	}
+	stdout, _, code = runCommand(t, server, "inventory", "--json")
+	var open []int
+	if err := json.Unmarshal([]byte(stdout), &open); code != 0 || err != nil || len(open) != 8 {
+		t.Errorf("inventory --json = exit %d, %q, want the 8 open ports", code, stdout)
+	}
+
+	stdout, _, code = runCommand(t, server, "assigned")
+	if want := [][]string{{"PORT", "ID"}, {"30001", "db"}, {"30003", "web"}}; code != 0 || !reflect.DeepEqual(table(stdout), want) {
+		t.Errorf("assigned = exit %d, %q, want db and web by port", code, stdout)
+	}
+	stdout, _, code = runCommand(t, server, "assigned", "--json")
+	var mappings map[string]int
+	if err := json.Unmarshal([]byte(stdout), &mappings); code != 0 || err != nil || !reflect.DeepEqual(mappings, map[string]int{"db": 30001, "web": 30003}) {
+		t.Errorf("assigned --json = exit %d, %q, want db and web", code, stdout)
+	}
+
+	checkFails(t, server, "Error:", "inventory", "--pool", "nope")
+	checkFails(t, server, "Error:", "assigned", "--pool", "nope")
+}
@@ -2,20 +2,177 @@ package common
 
 import "time"
 
+// Event records a change of hands of ports, such as an allocation or a
+// release. IDs increase with each event. Data holds the pool, host and id
+// of the service concerned along with details depending on Name.
 type Event struct {
-	ID    int64
-	Name  string
-	Stamp time.Time
-	Data  map[string]string
+	ID    int64             `json:"id"`
+	Name  string            `json:"name"`
+	Stamp time.Time         `json:"stamp"`
+	Data  map[string]string `json:"data"`
 }
 
+// NewPortRequest is the optional JSON body of a port request.
 type NewPortRequest struct {
 	Instancename string
+	// Labels are stored with the assignment, replacing any it had.
+	Labels map[string]string
 }
 
+// ServiceRequest asks for the ports of one service in a BulkPortRequest, or
+// of the service in the URL in the body of an /api/v2 allocation. It takes
+// the same options as the parameters of a single port request.
+type ServiceRequest struct {
+	ID string `json:"id,omitempty"`
+	// Lease is a duration such as 90s or 5m, no lease when empty.
+	Lease  string            `json:"lease,omitempty"`
+	Port   int               `json:"port,omitempty"`
+	Prefer bool              `json:"prefer,omitempty"`
+	Block  int               `json:"block,omitempty"`
+	Names  []string          `json:"names,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Wait is how long a single allocation may wait for a port when the
+	// pool is exhausted. Bulk allocations don't wait.
+	Wait string `json:"wait,omitempty"`
+}
+
+// BulkPortRequest is the JSON body of a bulk allocation.
+type BulkPortRequest struct {
+	Services []ServiceRequest `json:"services"`
+}
+
+// BulkReleaseRequest is the JSON body of a bulk release.
+type BulkReleaseRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// RenewRequest is the optional JSON body of an /api/v2 lease renewal.
+type RenewRequest struct {
+	// Lease is the length of the new lease, that of the last one when
+	// empty.
+	Lease string `json:"lease,omitempty"`
+}
+
+// The codes of a failed InfoResponse, each with the HTTP status it comes
+// with.
+const (
+	// CodeInvalid is for a request which can never succeed, 400.
+	CodeInvalid = "invalid"
+	// CodeNotFound is for a service or port which isn't there, 404.
+	CodeNotFound = "not_found"
+	// CodeConflict is for a request the state of the pool doesn't allow,
+	// e.g. for a port held by another service, 409.
+	CodeConflict = "conflict"
+	// CodeExhausted is for a pool without a port left for the request, 503.
+	CodeExhausted = "exhausted"
+	// CodeUnavailable is for a backend which can't be reached, 503.
+	CodeUnavailable = "unavailable"
+	// CodeInternal is for anything else, 500.
+	CodeInternal = "internal"
+)
+
 // InfoResponse represents the information returned in an API call
 type InfoResponse struct {
 	Status        string
 	StatusMessage string
-	Data          interface{}
+	// Code is set when the call failed, to one of the Code constants.
+	Code string `json:",omitempty"`
+	Data interface{}
+	// Expires is set when the service in the response holds a lease.
+	Expires *time.Time `json:",omitempty"`
+	// Labels are those of the service in the response, if it has any.
+	Labels map[string]string `json:",omitempty"`
+}
+
+// Response is the body of every /api/v2 reply. Unlike InfoResponse its keys
+// are lower case.
+type Response struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// Code is set when the call failed, to one of the Code constants.
+	Code string      `json:"code,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Service is a service and the ports it holds, as /api/v2 returns it.
+type Service struct {
+	ID string `json:"id"`
+	// Port is the port held, or the first of a block. It is zero for named
+	// ports.
+	Port int `json:"port,omitempty"`
+	// Ports lists the ports of a block.
+	Ports []int `json:"ports,omitempty"`
+	// Named maps each name of named ports to its port.
+	Named map[string]int `json:"named,omitempty"`
+	// Expires is set when the service holds a lease.
+	Expires *time.Time        `json:"expires,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// PortOwner is a port and the id holding it.
+type PortOwner struct {
+	Port int    `json:"port"`
+	ID   string `json:"id"`
+}
+
+// PortList is a list of ports such as those open in a pool.
+type PortList struct {
+	Count int   `json:"count"`
+	Ports []int `json:"ports"`
+}
+
+// PortRange is the range of ports a pool manages, from Start up to, but not
+// including, End.
+type PortRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ExclusionList lists the ports within the range of a pool it never hands
+// out, each a PORT or an inclusive START-END range.
+type ExclusionList struct {
+	Excluded []string `json:"excluded"`
+}
+
+// ReconcileReport describes what changing the range or exclusions of a pool
+// did.
+type ReconcileReport struct {
+	PortRange
+	Excluded []string `json:"excluded"`
+	// Added are the ports newly put in the pool, Removed the free ports
+	// taken out of it.
+	Added   []int `json:"added"`
+	Removed []int `json:"removed"`
+	// Stranded maps the assigned ports now outside of the range, or
+	// excluded, to the id holding them. They leave the pool once released.
+	Stranded map[string]string `json:"stranded"`
+}
+
+// Inconsistency is a problem found in the data model of a pool, along with
+// what repairing it does.
+type Inconsistency struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id,omitempty"`
+	Port   string `json:"port,omitempty"`
+	Detail string `json:"detail"`
+	Repair string `json:"repair"`
+}
+
+// QuarantinedPort is a port taken out of a pool because it was found in use
+// outside of port-authority.
+type QuarantinedPort struct {
+	Port  int       `json:"port"`
+	Since time.Time `json:"since"`
+}
+
+// MappingPage is a page of the mapping of ids to ports, see the Cursor.
+type MappingPage struct {
+	// Mappings maps each id to its port, or the first port of its block.
+	// Named ports are listed under their ID/NAME.
+	Mappings map[string]int `json:"mappings"`
+	// Labels holds the labels of the listed ids which have any.
+	Labels map[string]map[string]string `json:"labels,omitempty"`
+	// Cursor is passed to get the next page, zero once everything has been
+	// listed.
+	Cursor uint64 `json:"cursor"`
 }
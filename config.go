@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/libkv"
+	"github.com/docker/libkv/store"
+	"github.com/therealbill/port-authority/actions"
+)
+
+// configPrefix is the base path of every key in the config store.
+const configPrefix = "app/port-authority/config"
+
+// newConfigStore connects to the Consul config store at address.
+func newConfigStore(address string) (store.Store, error) {
+	return libkv.NewStore(
+		store.CONSUL, // or "consul"
+		[]string{address},
+		&store.Config{
+			ConnectionTimeout: 10 * time.Second,
+		},
+	)
+}
+
+// instanceKey returns the config path for the instance with the given name,
+// which is prefix itself for an unnamed instance.
+func instanceKey(prefix, name string) string {
+	if name != "" {
+		return fmt.Sprintf("%s/%s", prefix, name)
+	}
+	return prefix
+}
+
+// poolConfig is the port range of a single pool and how its ports are
+// picked.
+type poolConfig struct {
+	Name     string
+	Start    int
+	End      int
+	Excluded []actions.Exclusion
+	Strategy actions.Strategy
+}
+
+// readPoolConfigs reads the additional pools listed, comma separated, in the
+// "pools" key under base. Each pool's range is read from
+// pools/NAME/ports_begin and pools/NAME/ports_end, and its exclusions from
+// pools/NAME/excluded_ports, and its allocation strategy from
+// pools/NAME/strategy. Pools without a valid range are skipped.
+func readPoolConfigs(kv store.Store, base string) []poolConfig {
+	var pools []poolConfig
+	key_pools := fmt.Sprintf("%s/pools", base)
+	tmp, err := kv.Get(key_pools)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			log.Printf("key not found in config store: %s", key_pools)
+		} else {
+			log.Printf("Error on connection: %v", err)
+		}
+		return pools
+	}
+	for _, name := range strings.Split(string(tmp.Value), ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 || name == actions.DefaultPool {
+			continue
+		}
+		start, ok := readIntKey(kv, fmt.Sprintf("%s/%s/ports_begin", key_pools, name))
+		if !ok {
+			continue
+		}
+		end, ok := readIntKey(kv, fmt.Sprintf("%s/%s/ports_end", key_pools, name))
+		if !ok {
+			continue
+		}
+		if end <= start {
+			log.Printf("Pool '%s' ends at %d before it begins at %d, skipping it", name, end, start)
+			continue
+		}
+		excluded := readExclusions(kv, fmt.Sprintf("%s/%s/excluded_ports", key_pools, name))
+		strategy := readStrategy(kv, fmt.Sprintf("%s/%s/strategy", key_pools, name))
+		pools = append(pools, poolConfig{Name: name, Start: start, End: end, Excluded: excluded, Strategy: strategy})
+	}
+	return pools
+}
+
+// readIntKey reads an integer from the config store, logging why when it
+// can't.
+func readIntKey(kv store.Store, key string) (int, bool) {
+	tmp, err := kv.Get(key)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			log.Printf("key not found in config store: %s", key)
+		} else {
+			log.Printf("Error on connection: %v", err)
+		}
+		return 0, false
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(tmp.Value)))
+	if err != nil {
+		log.Printf("Invalid integer in config store at %s: %v", key, err)
+		return 0, false
+	}
+	return value, true
+}
+
+// readExclusions reads a list of excluded ports and port ranges, see
+// actions.ParseExclusions, from the config store. A missing or invalid list
+// excludes nothing.
+func readExclusions(kv store.Store, key string) []actions.Exclusion {
+	tmp, err := kv.Get(key)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			log.Printf("key not found in config store: %s", key)
+		} else {
+			log.Printf("Error on connection: %v", err)
+		}
+		return nil
+	}
+	excluded, err := actions.ParseExclusions(string(tmp.Value))
+	if err != nil {
+		log.Printf("Invalid exclusion list in config store at %s: %v", key, err)
+		return nil
+	}
+	return excluded
+}
+
+// readStrategy reads an allocation strategy, see actions.ParseStrategy, from
+// the config store. A missing or unknown strategy is the random one.
+func readStrategy(kv store.Store, key string) actions.Strategy {
+	tmp, err := kv.Get(key)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			log.Printf("key not found in config store: %s", key)
+		} else {
+			log.Printf("Error on connection: %v", err)
+		}
+		return actions.StrategyRandom
+	}
+	strategy, err := actions.ParseStrategy(string(tmp.Value))
+	if err != nil {
+		log.Printf("Invalid allocation strategy in config store at %s: %v", key, err)
+	}
+	return strategy
+}
+
+// logReconcileReport logs what reconciling a pool's range changed.
+func logReconcileReport(pool string, report actions.ReconcileReport) {
+	log.Printf("Pool '%s' now covers ports %d to %d: %d added, %d removed",
+		pool, report.Start, report.End, len(report.Added), len(report.Removed))
+	if len(report.Excluded) > 0 {
+		log.Printf("Pool '%s' excludes ports %s", pool, actions.FormatExclusions(report.Excluded))
+	}
+	for port, id := range report.Stranded {
+		log.Printf("Pool '%s': port %s is outside the range or excluded but still assigned to '%s', it will leave the pool once released", pool, port, id)
+	}
+}
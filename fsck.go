@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/therealbill/port-authority/actions"
+)
+
+// fsck checks the data model of every configured pool, or the one given with
+// --pool, along with each of their hosts, and repairs it when --repair is
+// passed. It exits non-zero when it found problems it did not repair.
+func fsck(c *cli.Context) {
+	names := []string{actions.DefaultPool}
+	kv, err := newConfigStore(c.GlobalString("consuladdress"))
+	if err != nil {
+		log.Printf("Cannot create store consul, only checking the default pool: %v", err)
+	} else {
+		for _, pool := range readPoolConfigs(kv, instanceKey(configPrefix, c.GlobalString("name"))) {
+			names = append(names, pool.Name)
+		}
+	}
+	if pool := c.String("pool"); pool != "" {
+		names = []string{pool}
+	}
+
+	rs, err := actions.NewRedisStore(c.GlobalString("redis"), "")
+	if err != nil {
+		log.Fatal("Can not connect to Redis!")
+	}
+	repair := c.Bool("repair")
+	unrepaired := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "POOL\tHOST\tKIND\tDETAIL\tREPAIR")
+	for _, name := range names {
+		pool := rs.Pool(name)
+		events := actions.NewRedisEvents(pool)
+		hosts, err := pool.Hosts()
+		if err != nil {
+			log.Fatalf("Error listing the hosts of pool '%s': %v", name, err)
+		}
+		for _, host := range append([]string{""}, hosts...) {
+			var store actions.PortStore = actions.NewEventStore(pool, name, events)
+			if host != "" {
+				if store, err = store.LookupHost(host); err != nil {
+					log.Fatalf("Error opening host '%s' of pool '%s': %v", host, name, err)
+				}
+			}
+			found, err := store.Check(repair)
+			if err != nil {
+				log.Fatalf("Error checking pool '%s': %v", name, err)
+			}
+			for _, i := range found {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", name, host, i.Kind, i.Detail, i.Repair)
+			}
+			if !repair {
+				unrepaired += len(found)
+			}
+		}
+	}
+	tw.Flush()
+	if unrepaired > 0 {
+		fmt.Printf("\n%d inconsistencies found, run again with --repair to fix them\n", unrepaired)
+		os.Exit(1)
+	}
+}
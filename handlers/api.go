@@ -2,83 +2,370 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/therealbill/port-authority/actions"
 	"github.com/therealbill/port-authority/common"
 	"github.com/zenazn/goji/web"
 )
 
-func APIGetPortFromInstance(c web.C, w http.ResponseWriter, r *http.Request) {
+// maxPageSize is the most ids a page of GetMappings may be asked to hold.
+const maxPageSize = 1000
+
+// maxWait is the longest an allocation may be asked to wait for a port, or
+// a poll for events.
+const maxWait = 5 * time.Minute
+
+// globEscaper escapes the characters a glob pattern gives a meaning to.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// API serves the HTTP API on top of the stores of each pool.
+type API struct {
+	Pools actions.Pools
+	// Events holds the events served by V2.ListEvents and V2.StreamEvents.
+	Events *actions.EventLog
+}
+
+// NewAPI returns an API serving requests from pools, with an empty event
+// log.
+func NewAPI(pools actions.Pools) *API {
+	return &API{Pools: pools, Events: actions.NewEventLog()}
+}
+
+// store returns the store of the pool named in the URL, or of the default
+// pool when there is none. When the request has a host parameter it returns
+// the store of that host within the pool. If the pool or the host doesn't
+// exist, or the host is invalid, it replies with an error and returns false.
+func (a *API) store(c web.C, w http.ResponseWriter, r *http.Request) (actions.PortStore, bool) {
+	store, err := a.pool(c, r, false)
+	stop := returnError(err, &w)
+	return store, !stop
+}
+
+// allocationStore is store for the requests allocating ports, which add the
+// host to the pool when it is new.
+func (a *API) allocationStore(c web.C, w http.ResponseWriter, r *http.Request) (actions.PortStore, bool) {
+	store, err := a.pool(c, r, true)
+	stop := returnError(err, &w)
+	return store, !stop
+}
+
+// pool is store, or allocationStore when create is set, without the reply.
+func (a *API) pool(c web.C, r *http.Request, create bool) (actions.PortStore, error) {
+	return a.lookup(c.URLParams["pool"], r.URL.Query().Get("host"), create)
+}
+
+// lookup returns the store of the named pool, the default pool when name is
+// empty, or of the host within it when host isn't empty. Unless create is
+// set the host must have been seen by the pool already.
+func (a *API) lookup(name, host string, create bool) (actions.PortStore, error) {
+	if len(name) == 0 {
+		name = actions.DefaultPool
+	}
+	store, ok := a.Pools[name]
+	if !ok {
+		return nil, notFoundf("No pool named '%s'", name)
+	}
+	if len(host) == 0 {
+		return store, nil
+	}
+	if create {
+		return store.Host(host)
+	}
+	return store.LookupHost(host)
+}
+
+// ListPools returns the names of the pools served by this instance.
+func (a *API) ListPools(c web.C, w http.ResponseWriter, r *http.Request) {
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: a.Pools.Names()}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// ListHosts returns the hosts the pool has assigned ports on.
+func (a *API) ListHosts(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	hosts, err := store.Hosts()
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: hosts}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+func (a *API) GetPortFromInstance(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
 	id := c.URLParams["id"]
 	resp := common.InfoResponse{Status: "data"}
-	port, err := actions.GetPortFromInstance(id)
-	stop := returnUnhandledError(err, &w)
+	assignment, err := store.GetAssignment(id)
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
-	log.Printf("gat '%s' for port from call for '%s'", port, id)
-	if len(port) > 0 {
-		iport, err := strconv.Atoi(port)
-		if err != nil {
-			resp.Status = "Error"
-			resp.StatusMessage = "Invalid port integer received from data store. Someone needs to investigate this RIGHT NOW."
-			log.Printf("port: '%s'", port)
-		} else {
-			resp.Data = iport
-			resp.StatusMessage = "Port found"
-		}
-	} else {
-		resp.Data = 0
-		resp.StatusMessage = "No Port found"
+	log.Printf("gat '%d' for port from call for '%s'", assignment.Port, id)
+	if assignment.Empty() {
+		replyNotFound(w, "No Port found", nil)
+		return
 	}
+	resp.StatusMessage = "Port found"
+	resp.Data = assignmentData(assignment)
+	resp.Expires = leaseExpiry(assignment)
+	resp.Labels = assignment.Labels
 	packed, _ := json.Marshal(resp)
 	w.Write(packed)
 }
 
-func APIGetInstanceFromPort(c web.C, w http.ResponseWriter, r *http.Request) {
+func (a *API) GetInstanceFromPort(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
 	port := c.URLParams["port"]
 	iport, err := strconv.Atoi(port)
-	resp := common.InfoResponse{Status: "data"}
 	if err != nil {
-		resp.Status = "Client Error"
-		resp.StatusMessage = "Invalid port integer passed. Use a valid port number"
-	} else {
-		name, err := actions.GetInstanceFromPort(iport)
-		stop := returnUnhandledError(err, &w)
-		if stop {
-			return
+		replyInvalid(w, "Invalid port integer passed. Use a valid port number")
+		return
+	}
+	name, err := store.GetInstanceFromPort(iport)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	sname := string(name)
+	if len(sname) == 0 {
+		replyNotFound(w, "No mapping found", "")
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "Mapping found", Data: sname}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+func (a *API) GetOpenPort(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.allocationStore(c, w, r)
+	if !ok {
+		return
+	}
+	id := c.URLParams["id"]
+	opts, err := allocateOptions(r)
+	if err != nil {
+		replyInvalid(w, err.Error())
+		return
+	}
+	wait, err := waitParam(r)
+	if err != nil {
+		replyInvalid(w, err.Error())
+		return
+	}
+	assignment, err := actions.AllocateWait(store, id, opts, wait, r.Context().Done())
+	if conflict, ok := err.(*actions.PortConflictError); ok {
+		replyError(w, err, conflict.Owner)
+		return
+	}
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "open port found", Data: assignmentData(assignment), Expires: leaseExpiry(assignment), Labels: assignment.Labels}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// allocateOptions reads the allocation options from the request parameters:
+// lease, and either names or block and one of port or prefer, and the labels
+// from the optional JSON body, see common.NewPortRequest. The error is meant
+// for the client.
+func allocateOptions(r *http.Request) (opts actions.AllocateOptions, err error) {
+	opts.Lease, err = parseLease(r)
+	if err != nil {
+		return opts, fmt.Errorf("Invalid lease passed. Use a duration such as 90s or 5m")
+	}
+	port, prefer := r.URL.Query().Get("port"), r.URL.Query().Get("prefer")
+	switch {
+	case len(port) > 0 && len(prefer) > 0:
+		return opts, fmt.Errorf("Pass either port or prefer, not both")
+	case len(prefer) > 0:
+		port = prefer
+		opts.Prefer = true
+	}
+	if len(port) > 0 {
+		opts.Port, err = strconv.Atoi(port)
+		if err != nil || opts.Port <= 0 {
+			return opts, fmt.Errorf("Invalid port integer passed. Use a valid port number")
 		}
-		sname := string(name)
-		if len(sname) > 0 {
-			resp.Data = sname
-			resp.StatusMessage = "Mapping found"
-		} else {
-			resp.Data = ""
-			resp.StatusMessage = "No mapping found"
+	}
+	opts.Block, err = intParam(r, "block", 1)
+	if err != nil {
+		return opts, fmt.Errorf("Invalid block size passed. Use a number of ports above zero")
+	}
+	if names := r.URL.Query().Get("names"); len(names) > 0 {
+		opts.Names = strings.Split(names, ",")
+	}
+	if err := checkOptions(opts); err != nil {
+		return opts, err
+	}
+	var body common.NewPortRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return opts, fmt.Errorf("Invalid request body. Send a JSON object such as {\"Labels\": {\"team\": \"payments\"}}")
+	}
+	opts.Labels = body.Labels
+	return opts, nil
+}
+
+// checkOptions returns the error, meant for the client, for allocation
+// options which can't go together.
+func checkOptions(opts actions.AllocateOptions) error {
+	switch {
+	case opts.Port < 0 || (opts.Prefer && opts.Port == 0):
+		return fmt.Errorf("Invalid port integer passed. Use a valid port number")
+	case opts.Block < 1:
+		return fmt.Errorf("Invalid block size passed. Use a number of ports above zero")
+	case len(opts.Names) > 0 && (opts.Port != 0 || opts.Block > 1):
+		return fmt.Errorf("Named ports can't be combined with port, prefer or block")
+	}
+	return nil
+}
+
+// serviceOptions reads the allocation options of a service in a bulk
+// request. The error is meant for the client.
+func serviceOptions(req common.ServiceRequest) (opts actions.AllocateOptions, err error) {
+	if len(req.ID) == 0 {
+		return opts, invalidf("Every service needs an ID")
+	}
+	if len(req.Lease) > 0 {
+		opts.Lease, err = leaseDuration(req.Lease)
+		if err != nil {
+			return opts, invalidf("Invalid lease passed for '%s'. Use a duration such as 90s or 5m", req.ID)
 		}
 	}
+	opts.Port, opts.Prefer, opts.Block = req.Port, req.Prefer, req.Block
+	if opts.Block == 0 {
+		opts.Block = 1
+	}
+	opts.Names, opts.Labels = req.Names, req.Labels
+	if err := checkOptions(opts); err != nil {
+		return opts, invalidf("%s for '%s'", err, req.ID)
+	}
+	return opts, nil
+}
+
+// bulkRequests reads the allocations asked for by the body of a bulk
+// allocation, a common.BulkPortRequest. The error is meant for the client.
+func bulkRequests(r *http.Request) ([]actions.AllocateRequest, error) {
+	var body common.BulkPortRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Services) == 0 {
+		return nil, invalidf("Invalid request body. Send a JSON object such as {\"services\": [{\"id\": \"web\"}]}")
+	}
+	return allocateRequests(body.Services)
+}
+
+// allocateRequests returns the allocations asked for by services, which
+// must not list a service twice. The error is meant for the client.
+func allocateRequests(services []common.ServiceRequest) ([]actions.AllocateRequest, error) {
+	reqs := make([]actions.AllocateRequest, len(services))
+	seen := make(map[string]bool)
+	for i, service := range services {
+		if seen[service.ID] {
+			return nil, invalidf("Service '%s' is listed more than once", service.ID)
+		}
+		seen[service.ID] = true
+		opts, err := serviceOptions(service)
+		if err != nil {
+			return nil, err
+		}
+		reqs[i] = actions.AllocateRequest{ID: service.ID, Options: opts}
+	}
+	return reqs, nil
+}
+
+// releaseIDs reads the ids listed in the body of a bulk release, a
+// common.BulkReleaseRequest. The error is meant for the client.
+func releaseIDs(r *http.Request) ([]string, error) {
+	var body common.BulkReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.IDs) == 0 {
+		return nil, invalidf("Invalid request body. Send a JSON object such as {\"ids\": [\"web\"]}")
+	}
+	return body.IDs, nil
+}
+
+// RenewLease extends the lease on a service, either by the duration passed
+// in the lease parameter or by the one it was last given.
+func (a *API) RenewLease(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	id := c.URLParams["id"]
+	resp := common.InfoResponse{Status: "data"}
+	lease, err := parseLease(r)
+	if err != nil {
+		replyInvalid(w, "Invalid lease passed. Use a duration such as 90s or 5m")
+		return
+	}
+	assignment, err := store.RenewLease(id, lease)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp.StatusMessage = "Lease renewed"
+	resp.Data = assignmentData(assignment)
+	resp.Expires = leaseExpiry(assignment)
+	resp.Labels = assignment.Labels
 	packed, _ := json.Marshal(resp)
 	w.Write(packed)
 }
 
-func APIGetOpenPort(c web.C, w http.ResponseWriter, r *http.Request) {
-	id := c.URLParams["id"]
-	port, err := actions.GetOpenPort(id)
-	stop := returnUnhandledError(err, &w)
+func (a *API) GetPortCapacity(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	count, err := store.GetOpenPortCount()
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
-	resp := common.InfoResponse{Status: "data", StatusMessage: "open port found", Data: port}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: count}
 	packed, _ := json.Marshal(resp)
 	w.Write(packed)
 }
 
-func APIGetPortCapacity(c web.C, w http.ResponseWriter, r *http.Request) {
-	count, err := actions.GetOpenPortCount()
-	stop := returnUnhandledError(err, &w)
+func (a *API) GetAvailableInventory(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	ports, err := store.GetOpenPortList()
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: ports}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+func (a *API) GetAssignedCount(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	count, err := store.GetReservedPortCount()
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
@@ -87,9 +374,13 @@ func APIGetPortCapacity(c web.C, w http.ResponseWriter, r *http.Request) {
 	w.Write(packed)
 }
 
-func APIGetAvailableInventory(c web.C, w http.ResponseWriter, r *http.Request) {
-	ports, err := actions.GetOpenPortList()
-	stop := returnUnhandledError(err, &w)
+func (a *API) GetAssignedList(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	ports, err := store.GetReservedPortList()
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
@@ -98,9 +389,192 @@ func APIGetAvailableInventory(c web.C, w http.ResponseWriter, r *http.Request) {
 	w.Write(packed)
 }
 
-func APIGetAssignedCount(c web.C, w http.ResponseWriter, r *http.Request) {
-	count, err := actions.GetReservedPortCount()
-	stop := returnUnhandledError(err, &w)
+// GetMappings pages through the ids holding ports along with their ports.
+// The cursor parameter is the Cursor returned with the previous page, count
+// about how many ids a page holds. Either match, a glob pattern, or prefix
+// limits the listing to the matching ids, and a selector, see
+// actions.ParseSelector, or label parameters, given as KEY=VALUE, to the ids
+// whose service has matching labels.
+func (a *API) GetMappings(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	page, err := listMappings(store, r)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: page}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// listMappings returns the page of the mapping GetMappings asks for.
+func listMappings(store actions.PortStore, r *http.Request) (actions.MappingPage, error) {
+	var page actions.MappingPage
+	var cursor uint64
+	if value := r.URL.Query().Get("cursor"); len(value) > 0 {
+		var err error
+		cursor, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return page, invalidf("Invalid cursor passed. Use the cursor of the previous page")
+		}
+	}
+	count, err := intParam(r, "count", 100)
+	if err != nil || count < 1 || count > maxPageSize {
+		return page, invalidf("Invalid count passed. Use a number from 1 to %d", maxPageSize)
+	}
+	match, prefix := r.URL.Query().Get("match"), r.URL.Query().Get("prefix")
+	switch {
+	case len(match) > 0 && len(prefix) > 0:
+		return page, invalidf("Pass either match or prefix, not both")
+	case len(prefix) > 0:
+		match = globEscaper.Replace(prefix) + "*"
+	}
+	sel, err := selector(r)
+	if err != nil {
+		return page, invalidf("%s", err)
+	}
+	for _, label := range r.URL.Query()["label"] {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return page, invalidf("Invalid label passed. Use KEY=VALUE")
+		}
+		sel = append(sel, actions.Requirement{Key: kv[0], Op: "=", Values: []string{kv[1]}})
+	}
+	return selectMappings(store, cursor, match, count, sel)
+}
+
+// selectMappings returns the page of the mapping at cursor, keeping only the
+// ids whose labels match sel.
+func selectMappings(store actions.PortStore, cursor uint64, match string, count int, sel actions.Selector) (actions.MappingPage, error) {
+	page, err := store.ListMappings(cursor, match, count)
+	if err != nil {
+		return page, err
+	}
+	for id := range page.Mappings {
+		if !sel.Matches(page.Labels[id]) {
+			delete(page.Mappings, id)
+			delete(page.Labels, id)
+		}
+	}
+	return page, nil
+}
+
+// FindServices returns every service whose labels match the selector
+// parameter, see actions.ParseSelector, with its ports and labels.
+func (a *API) FindServices(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	sel, err := selector(r)
+	if err != nil {
+		replyInvalid(w, err.Error())
+		return
+	}
+	selected, err := actions.SelectServices(store, sel)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	services := make([]serviceData, len(selected))
+	for i, assignment := range selected {
+		services[i] = newServiceData(assignment)
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: fmt.Sprintf("%d services found", len(services)), Data: services}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// ReleaseServices releases every service whose labels match the selector
+// parameter and returns their ids. The selector is required, so a mistake
+// can't release the whole pool.
+func (a *API) ReleaseServices(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	sel, err := selector(r)
+	if err == nil && len(sel) == 0 {
+		err = fmt.Errorf("Pass a selector naming the services to release")
+	}
+	if err != nil {
+		replyInvalid(w, err.Error())
+		return
+	}
+	released, err := actions.ReleaseServices(store, sel)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: fmt.Sprintf("%d services released", len(released)), Data: released}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// AllocateServices allocates ports for every service in the JSON body, a
+// common.BulkPortRequest, or for none of them. Data maps each ID to its
+// ports. When a service can't be allocated, Data is its ID.
+func (a *API) AllocateServices(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.allocationStore(c, w, r)
+	if !ok {
+		return
+	}
+	reqs, err := bulkRequests(r)
+	if err != nil {
+		replyInvalid(w, err.Error())
+		return
+	}
+	assignments, err := store.AllocateAll(reqs)
+	if bulk, ok := err.(*actions.BulkError); ok {
+		replyError(w, err, bulk.ID)
+		return
+	}
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	services := make(map[string]serviceData, len(assignments))
+	for id, assignment := range assignments {
+		services[id] = newServiceData(assignment)
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: fmt.Sprintf("%d services allocated", len(services)), Data: services}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// ReleaseServiceList releases the services listed in the JSON body, a
+// common.BulkReleaseRequest, at once. Data maps each ID to whether it held
+// any ports.
+func (a *API) ReleaseServiceList(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	ids, err := releaseIDs(r)
+	if err != nil {
+		replyInvalid(w, err.Error())
+		return
+	}
+	released, err := store.RemoveServices(ids)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: released}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+func (a *API) GetCoolingCount(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	count, err := store.GetCoolingPortCount()
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
@@ -109,9 +583,13 @@ func APIGetAssignedCount(c web.C, w http.ResponseWriter, r *http.Request) {
 	w.Write(packed)
 }
 
-func APIGetAssignedList(c web.C, w http.ResponseWriter, r *http.Request) {
-	ports, err := actions.GetReservedPortList()
-	stop := returnUnhandledError(err, &w)
+func (a *API) GetCoolingList(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	ports, err := store.GetCoolingPortList()
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
@@ -120,10 +598,14 @@ func APIGetAssignedList(c web.C, w http.ResponseWriter, r *http.Request) {
 	w.Write(packed)
 }
 
-func APIRemoveService(c web.C, w http.ResponseWriter, r *http.Request) {
+func (a *API) RemoveService(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
 	id := c.URLParams["id"]
-	err := actions.RemoveService(id)
-	stop := returnUnhandledError(err, &w)
+	err := store.RemoveService(id)
+	stop := returnError(err, &w)
 	if stop {
 		return
 	}
@@ -131,3 +613,258 @@ func APIRemoveService(c web.C, w http.ResponseWriter, r *http.Request) {
 	packed, _ := json.Marshal(resp)
 	w.Write(packed)
 }
+
+// CheckConsistency reports the inconsistencies in the pool's data model
+// without touching anything.
+func (a *API) CheckConsistency(c web.C, w http.ResponseWriter, r *http.Request) {
+	a.check(c, w, r, false)
+}
+
+// RepairConsistency repairs the inconsistencies in the pool's data model and
+// reports what it repaired.
+func (a *API) RepairConsistency(c web.C, w http.ResponseWriter, r *http.Request) {
+	a.check(c, w, r, true)
+}
+
+func (a *API) check(c web.C, w http.ResponseWriter, r *http.Request, repair bool) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	found, err := store.Check(repair)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	if found == nil {
+		found = []actions.Inconsistency{}
+	}
+	resp := common.InfoResponse{Status: "data", Data: found}
+	switch {
+	case len(found) == 0:
+		resp.StatusMessage = "No inconsistencies found"
+	case repair:
+		resp.StatusMessage = fmt.Sprintf("Repaired %d inconsistencies", len(found))
+	default:
+		resp.StatusMessage = fmt.Sprintf("Found %d inconsistencies, POST to repair them", len(found))
+	}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// GetRange returns the range of ports the pool manages.
+func (a *API) GetRange(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	start, end, err := store.GetRange()
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: map[string]int{"Start": start, "End": end}}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// ReconcileRange changes the range of ports the pool manages to the one given
+// by the begin and end parameters, and its exclusions to the excluded
+// parameter. Each defaults to the current setting, so posting none puts back
+// ports which went missing from the pool.
+func (a *API) ReconcileRange(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	resp := common.InfoResponse{Status: "data"}
+	start, end, err := store.GetRange()
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	excluded, err := store.GetExclusions()
+	stop = returnError(err, &w)
+	if stop {
+		return
+	}
+	start, err = intParam(r, "begin", start)
+	if err == nil {
+		end, err = intParam(r, "end", end)
+	}
+	if err != nil {
+		replyInvalid(w, "Invalid port integer passed. Use a valid port number")
+		return
+	}
+	if spec, ok := r.URL.Query()["excluded"]; ok {
+		excluded, err = actions.ParseExclusions(spec[0])
+		if err != nil {
+			replyInvalid(w, err.Error())
+			return
+		}
+	}
+	report, err := store.Reconcile(start, end, excluded)
+	stop = returnError(err, &w)
+	if stop {
+		return
+	}
+	resp.StatusMessage = fmt.Sprintf("Range is now %d to %d", report.Start, report.End)
+	if len(report.Stranded) > 0 {
+		resp.StatusMessage += fmt.Sprintf(", %d assigned ports are outside of it", len(report.Stranded))
+	}
+	resp.Data = report
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// GetExclusions lists the ports and port ranges within the pool's range which
+// are never handed out.
+func (a *API) GetExclusions(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	excluded, err := store.GetExclusions()
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	if excluded == nil {
+		excluded = []actions.Exclusion{}
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: excluded}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// GetQuarantine lists the ports taken out of the pool because they were found
+// in use outside of port-authority, along with when that happened.
+func (a *API) GetQuarantine(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	quarantined, err := store.GetQuarantine()
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	if quarantined == nil {
+		quarantined = []actions.QuarantinedPort{}
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "success", Data: quarantined}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// ReleaseQuarantine puts a quarantined port back into the pool, for when
+// whatever was using it is gone.
+func (a *API) ReleaseQuarantine(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := a.store(c, w, r)
+	if !ok {
+		return
+	}
+	port, err := strconv.Atoi(c.URLParams["port"])
+	if err != nil {
+		replyInvalid(w, "Invalid port integer passed. Use a valid port number")
+		return
+	}
+	err = store.Unquarantine(port)
+	stop := returnError(err, &w)
+	if stop {
+		return
+	}
+	resp := common.InfoResponse{Status: "data", StatusMessage: "Port released from quarantine"}
+	packed, _ := json.Marshal(resp)
+	w.Write(packed)
+}
+
+// waitParam reads the optional wait parameter, how long an allocation may
+// wait for a port when the pool is exhausted. The error is meant for the
+// client.
+func waitParam(r *http.Request) (time.Duration, error) {
+	return waitDuration(r.URL.Query().Get("wait"))
+}
+
+// waitDuration parses how long an allocation may wait, zero when value is
+// empty.
+func waitDuration(value string) (time.Duration, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 || wait > maxWait {
+		return 0, invalidf("Invalid wait passed. Use a duration up to %s such as 30s", maxWait)
+	}
+	return wait, nil
+}
+
+// selector reads the optional selector parameter. The error is meant for
+// the client.
+func selector(r *http.Request) (actions.Selector, error) {
+	return actions.ParseSelector(r.URL.Query().Get("selector"))
+}
+
+// intParam reads an integer query parameter, returning def when it is
+// missing.
+func intParam(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if len(value) == 0 {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseLease reads the optional lease duration from the request. A missing
+// lease is returned as zero.
+func parseLease(r *http.Request) (time.Duration, error) {
+	lease := r.URL.Query().Get("lease")
+	if len(lease) == 0 {
+		return 0, nil
+	}
+	return leaseDuration(lease)
+}
+
+// leaseDuration parses a lease duration such as 90s or 5m.
+func leaseDuration(lease string) (time.Duration, error) {
+	d, err := time.ParseDuration(lease)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("negative lease %s", lease)
+	}
+	return d, err
+}
+
+// assignmentData returns what an InfoResponse carries for an assignment: the
+// port, the list of ports for a block, or a map of name to port for named
+// ports.
+func assignmentData(a actions.Assignment) interface{} {
+	if len(a.Named) > 0 {
+		return a.Named
+	}
+	if a.Block > 1 {
+		return a.Ports()
+	}
+	return a.Port
+}
+
+// serviceData is how a service is listed by FindServices.
+type serviceData struct {
+	ID      string
+	Port    interface{}
+	Expires *time.Time        `json:",omitempty"`
+	Labels  map[string]string `json:",omitempty"`
+}
+
+// newServiceData lists the service holding a, see assignmentData for Port.
+func newServiceData(a actions.Assignment) serviceData {
+	return serviceData{ID: a.ID, Port: assignmentData(a), Expires: leaseExpiry(a), Labels: a.Labels}
+}
+
+// leaseExpiry returns the expiry of the assignment's lease for use in an
+// InfoResponse, nil when it has none.
+func leaseExpiry(a actions.Assignment) *time.Time {
+	if a.Expires.IsZero() {
+		return nil
+	}
+	return &a.Expires
+}
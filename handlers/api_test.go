@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/therealbill/port-authority/actions"
+)
+
+func TestListMappingsPrefix(t *testing.T) {
+	store := actions.NewMemoryStore()
+	if err := store.InitializePorts(7000, 7010, nil); err != nil {
+		t.Fatalf("InitializePorts(): %v", err)
+	}
+	for _, id := range []string{"a*b", "a?b", "a[b]", "axb", `a\b`} {
+		if _, err := store.Allocate(id, actions.AllocateOptions{}); err != nil {
+			t.Fatalf("Allocate(%q): %v", id, err)
+		}
+	}
+	for prefix, want := range map[string][]string{
+		"a*":   {"a*b"},
+		"a?":   {"a?b"},
+		"a[":   {"a[b]"},
+		"a[b]": {"a[b]"},
+		`a\`:   {`a\b`},
+		"a":    {"a*b", "a?b", "a[b]", `a\b`, "axb"},
+	} {
+		r := httptest.NewRequest("GET", "/api/ports/assigned/map?prefix="+url.QueryEscape(prefix), nil)
+		page, err := listMappings(store, r)
+		if err != nil {
+			t.Fatalf("listMappings(%q): %v", prefix, err)
+		}
+		var got []string
+		for id := range page.Mappings {
+			got = append(got, id)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("listMappings(prefix %q) = %v, want %v", prefix, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+)
+
+// errorReply is how the API answers errors of a kind.
+type errorReply struct {
+	HTTPStatus int
+	Status     string
+	Code       string
+}
+
+// errorReplies holds the errorReply of each actions.ErrorKind.
+var errorReplies = map[actions.ErrorKind]errorReply{
+	actions.KindInternal:    {http.StatusInternalServerError, "Server Error", common.CodeInternal},
+	actions.KindInvalid:     {http.StatusBadRequest, "Client Error", common.CodeInvalid},
+	actions.KindNotFound:    {http.StatusNotFound, "Not Found", common.CodeNotFound},
+	actions.KindConflict:    {http.StatusConflict, "Conflict", common.CodeConflict},
+	actions.KindExhausted:   {http.StatusServiceUnavailable, "Exhausted", common.CodeExhausted},
+	actions.KindUnavailable: {http.StatusServiceUnavailable, "Unavailable", common.CodeUnavailable},
+}
+
+// requestError is an error found by the handlers rather than the stores.
+type requestError struct {
+	kind    actions.ErrorKind
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// invalidf returns a requestError of actions.KindInvalid.
+func invalidf(format string, args ...interface{}) error {
+	return &requestError{actions.KindInvalid, fmt.Sprintf(format, args...)}
+}
+
+// notFoundf returns a requestError of actions.KindNotFound.
+func notFoundf(format string, args ...interface{}) error {
+	return &requestError{actions.KindNotFound, fmt.Sprintf(format, args...)}
+}
+
+// describeError returns how to answer err and the message to answer it
+// with. Internal errors and unavailable backends are logged, and the message
+// of internal errors is kept from the client.
+func describeError(err error) (errorReply, string) {
+	kind := actions.KindOf(err)
+	if e, ok := err.(*requestError); ok {
+		kind = e.kind
+	}
+	message := err.Error()
+	switch kind {
+	case actions.KindInternal:
+		log.Printf("Unhandled error: %s", message)
+		message = "Error not handled. See server log for details"
+	case actions.KindUnavailable:
+		log.Printf("Backend unavailable: %s", message)
+	}
+	return errorReplies[kind], message
+}
+
+// replyError answers with err and the HTTP status of its kind, see
+// actions.KindOf, putting data in the response's Data.
+func replyError(w http.ResponseWriter, err error, data interface{}) {
+	reply, message := describeError(err)
+	writeError(w, reply, message, data)
+}
+
+// replyInvalid answers a request the client got wrong with a 400 and
+// message.
+func replyInvalid(w http.ResponseWriter, message string) {
+	writeError(w, errorReplies[actions.KindInvalid], message, nil)
+}
+
+// replyNotFound answers with a 404 and message, for lookups which found
+// nothing.
+func replyNotFound(w http.ResponseWriter, message string, data interface{}) {
+	writeError(w, errorReplies[actions.KindNotFound], message, data)
+}
+
+func writeError(w http.ResponseWriter, reply errorReply, message string, data interface{}) {
+	resp := common.InfoResponse{Status: reply.Status, StatusMessage: message, Code: reply.Code, Data: data}
+	packed, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(reply.HTTPStatus)
+	w.Write(packed)
+}
+
+// returnError replies with err, see replyError, unless it is nil and reports
+// whether it did.
+func returnError(err error, w *http.ResponseWriter) (doReturn bool) {
+	if err != nil {
+		replyError(*w, err, nil)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/zenazn/goji/web"
+)
+
+// failingStore is a PortStore whose lookups of a service fail with err.
+type failingStore struct {
+	actions.PortStore
+	err error
+}
+
+func (s failingStore) GetAssignment(id string) (actions.Assignment, error) {
+	return actions.Assignment{}, s.err
+}
+
+// newTestAPI serves the /api routes over memory stores: the default pool
+// with the ports from 30000 up to 30010, "tiny" with a single port, and
+// "down" and "broken" whose lookups fail like an unreachable backend and a
+// bug do.
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	store := actions.NewMemoryStore()
+	tiny := actions.NewMemoryStore()
+	for s, size := range map[*actions.MemoryStore]int{store: 10, tiny: 1} {
+		if err := s.InitializePorts(30000, 30000+size, nil); err != nil {
+			t.Fatalf("InitializePorts(): %v", err)
+		}
+	}
+	api := NewAPI(nil)
+	api.Pools = actions.Pools{
+		actions.DefaultPool: store,
+		"tiny":              tiny,
+		"down":              failingStore{store, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}},
+		"broken":            failingStore{store, errors.New("the data model is broken")},
+	}
+	mux := web.New()
+	mux.Put("/api/service/:id", api.GetOpenPort)
+	mux.Get("/api/service/:id", api.GetPortFromInstance)
+	mux.Get("/api/port/:port", api.GetInstanceFromPort)
+	mux.Put("/api/pools/:pool/service/:id", api.GetOpenPort)
+	mux.Get("/api/pools/:pool/service/:id", api.GetPortFromInstance)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// call makes the request and returns its status and decoded body.
+func call(t *testing.T, server *httptest.Server, method, path string) (int, common.InfoResponse) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("NewRequest(): %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	var body common.InfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("%s %s answered no JSON: %v", method, path, err)
+	}
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode >= 400 && ct != "application/json" {
+		t.Errorf("%s %s answered Content-Type %q, want application/json", method, path, ct)
+	}
+	return resp.StatusCode, body
+}
+
+func TestErrorReplies(t *testing.T) {
+	server := newTestAPI(t)
+	if status, _ := call(t, server, "PUT", "/api/service/web?port=30005"); status != http.StatusOK {
+		t.Fatalf("PUT /api/service/web = %d, want 200", status)
+	}
+	if status, _ := call(t, server, "PUT", "/api/pools/tiny/service/a"); status != http.StatusOK {
+		t.Fatalf("PUT /api/pools/tiny/service/a = %d, want 200", status)
+	}
+
+	for _, c := range []struct {
+		method, path string
+		status       int
+		reply        string
+		code         string
+		message      string
+	}{
+		{"GET", "/api/service/nobody", 404, "Not Found", common.CodeNotFound, "No Port found"},
+		{"GET", "/api/port/30001", 404, "Not Found", common.CodeNotFound, "No mapping found"},
+		{"GET", "/api/pools/nope/service/web", 404, "Not Found", common.CodeNotFound, "No pool named 'nope'"},
+		{"GET", "/api/service/web?host=node9", 404, "Not Found", common.CodeNotFound, actions.ErrHostNotFound.Error()},
+		{"GET", "/api/port/http", 400, "Client Error", common.CodeInvalid, "Invalid port integer"},
+		{"GET", "/api/service/a,b", 400, "Client Error", common.CodeInvalid, actions.ErrInvalidID.Error()},
+		{"PUT", "/api/service/db?block=-1", 400, "Client Error", common.CodeInvalid, ""},
+		{"PUT", "/api/service/db?port=30005", 409, "Conflict", common.CodeConflict, "web"},
+		{"PUT", "/api/pools/tiny/service/b", 503, "Exhausted", common.CodeExhausted, ""},
+		{"GET", "/api/pools/down/service/web", 503, "Unavailable", common.CodeUnavailable, "connection refused"},
+		{"GET", "/api/pools/broken/service/web", 500, "Server Error", common.CodeInternal, "See server log"},
+	} {
+		status, body := call(t, server, c.method, c.path)
+		if status != c.status || body.Status != c.reply || body.Code != c.code || !strings.Contains(body.StatusMessage, c.message) {
+			t.Errorf("%s %s = %d %+v, want %d, %q and code %q with a message containing %q", c.method, c.path, status, body, c.status, c.reply, c.code, c.message)
+		}
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	for _, c := range []struct {
+		err     error
+		kind    actions.ErrorKind
+		message string
+	}{
+		{invalidf("Bad %s", "count"), actions.KindInvalid, "Bad count"},
+		{notFoundf("No pool named '%s'", "x"), actions.KindNotFound, "No pool named 'x'"},
+		{actions.ErrServiceNotFound, actions.KindNotFound, actions.ErrServiceNotFound.Error()},
+		{&actions.PortConflictError{ID: "db", Port: 30005, Owner: "web"}, actions.KindConflict, "web"},
+		{&actions.BulkError{ID: "db", Err: actions.ErrPoolExhausted}, actions.KindExhausted, "db"},
+		{errors.New("secret detail"), actions.KindInternal, "Error not handled"},
+	} {
+		reply, message := describeError(c.err)
+		if reply != errorReplies[c.kind] || !strings.Contains(message, c.message) {
+			t.Errorf("describeError(%v) = %+v, %q, want %+v and a message containing %q", c.err, reply, message, errorReplies[c.kind], c.message)
+		}
+	}
+	if _, message := describeError(errors.New("secret detail")); strings.Contains(message, "secret") {
+		t.Errorf("describeError() of an internal error = %q, want its message kept from the client", message)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/zenazn/goji/web"
+)
+
+// keepaliveInterval is how often StreamEvents writes a comment to keep
+// idle connections from being closed by proxies.
+const keepaliveInterval = 30 * time.Second
+
+// ListEvents returns the events after the one whose ID is the since
+// parameter, every event kept when there is none. With the wait parameter
+// it waits up to that long for one if there are none yet, for long polling.
+func (v *V2) ListEvents(c web.C, w http.ResponseWriter, r *http.Request) {
+	match, err := v.api.eventFilter(c.URLParams["pool"], r.URL.Query().Get("host"))
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	since, err := eventID(r.URL.Query().Get("since"))
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	wait, err := waitParam(r)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	events := pollEvents(v.api.Events, since, wait, match, r.Context().Done())
+	v.reply(w, fmt.Sprintf("%d events found", len(events)), events)
+}
+
+// StreamEvents streams the events as Server-Sent Events, starting after the
+// one named by the Last-Event-ID header or the since parameter, or with the
+// next one recorded when neither is given.
+func (v *V2) StreamEvents(c web.C, w http.ResponseWriter, r *http.Request) {
+	match, err := v.api.eventFilter(c.URLParams["pool"], r.URL.Query().Get("host"))
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	last := r.Header.Get("Last-Event-ID")
+	if len(last) == 0 {
+		last = r.URL.Query().Get("since")
+	}
+	since, err := eventID(last)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		v.fail(w, fmt.Errorf("The response writer can't stream"), nil)
+		return
+	}
+	events, unsubscribe := v.api.Events.Subscribe()
+	defer unsubscribe()
+	var backlog []common.Event
+	if len(last) > 0 {
+		backlog = v.api.Events.Since(since)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	sent := make(map[int64]bool)
+	for _, e := range backlog {
+		sent[e.ID] = true
+		if match(e) {
+			writeEvent(w, e)
+		}
+	}
+	flusher.Flush()
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				// Dropped for falling behind, the client reconnects
+				// with the ID of the last event it got.
+				return
+			}
+			if sent[e.ID] || !match(e) {
+				continue
+			}
+			writeEvent(w, e)
+		case <-keepalive.C:
+			io.WriteString(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes e as a Server-Sent Event.
+func writeEvent(w io.Writer, e common.Event) {
+	packed, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Name, packed)
+}
+
+// eventFilter returns the function telling whether an event concerns the
+// named pool and host, any pool or host for an empty name.
+func (a *API) eventFilter(pool, host string) (func(common.Event) bool, error) {
+	if len(pool) > 0 {
+		if _, err := a.lookup(pool, "", false); err != nil {
+			return nil, err
+		}
+	}
+	return func(e common.Event) bool {
+		return (len(pool) == 0 || e.Data["pool"] == pool) && (len(host) == 0 || e.Data["host"] == host)
+	}, nil
+}
+
+// pollEvents returns the events of log after since which match, waiting up
+// to wait for one when there are none yet, or until stop is closed.
+func pollEvents(log *actions.EventLog, since int64, wait time.Duration, match func(common.Event) bool, stop <-chan struct{}) []common.Event {
+	recorded, unsubscribe := log.Subscribe()
+	defer unsubscribe()
+	events := matchingEvents(log.Since(since), match)
+	if len(events) > 0 || wait == 0 {
+		return events
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case e, ok := <-recorded:
+			if !ok {
+				return matchingEvents(log.Since(since), match)
+			}
+			if e.ID > since && match(e) {
+				return []common.Event{e}
+			}
+		case <-timer.C:
+			return events
+		case <-stop:
+			return events
+		}
+	}
+}
+
+// matchingEvents returns the events which match, never nil.
+func matchingEvents(events []common.Event, match func(common.Event) bool) []common.Event {
+	matching := []common.Event{}
+	for _, e := range events {
+		if match(e) {
+			matching = append(matching, e)
+		}
+	}
+	return matching
+}
+
+// eventID parses the ID of the last event a client got, zero when value is
+// empty. The error is meant for the client.
+func eventID(value string) (int64, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, invalidf("Invalid event ID passed. Use the ID of the last event received")
+	}
+	return id, nil
+}
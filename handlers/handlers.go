@@ -137,14 +137,3 @@ func checkContextError(err error, w *http.ResponseWriter) (retcode int, userMess
 	}
 	return 200, ""
 }
-
-//returnUnhandledError is used to valiate we received a valid PageContext back
-//from the call, returning an error i, re, reqqf not
-func returnUnhandledError(err error, w *http.ResponseWriter) (doReturn bool) {
-	if err != nil {
-		log.Printf("Unhandled error: %s", err.Error())
-		http.Error(*w, "Error not handled. See server log for details", http.StatusInternalServerError)
-		return true
-	}
-	return false
-}
@@ -0,0 +1,1732 @@
+package handlers
+
+// openAPISpec is the OpenAPI document describing /api/v2, served by
+// V2.OpenAPI.
+const openAPISpec = `{
+	"openapi": "3.0.3",
+	"info": {
+		"title": "port-authority",
+		"version": "2",
+		"description": "Manages, assigns and tracks port assignments. Every path is also served under /api/v2/pools/{pool} to work on a pool other than the default one."
+	},
+	"servers": [
+		{
+			"url": "/api/v2"
+		}
+	],
+	"paths": {
+		"/pools": {
+			"get": {
+				"operationId": "listPools",
+				"summary": "List the pools",
+				"responses": {
+					"200": {
+						"description": "The pool names",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"type": "string"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				}
+			}
+		},
+		"/hosts": {
+			"get": {
+				"operationId": "listHosts",
+				"summary": "List the hosts with ports assigned on them",
+				"responses": {
+					"200": {
+						"description": "The host names",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"type": "string"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				}
+			}
+		},
+		"/services": {
+			"get": {
+				"operationId": "listServices",
+				"summary": "List the services whose labels match a selector",
+				"responses": {
+					"200": {
+						"description": "The services",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"$ref": "#/components/schemas/Service"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "selector",
+						"in": "query",
+						"required": false,
+						"description": "Label selector such as team=payments,env!=prod.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			},
+			"post": {
+				"operationId": "allocateServices",
+				"summary": "Allocate ports for every service listed, or for none of them",
+				"responses": {
+					"200": {
+						"description": "The services by ID; on failure data is the ID which failed",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "object",
+													"additionalProperties": {
+														"$ref": "#/components/schemas/Service"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"409": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/BulkPortRequest"
+							}
+						}
+					}
+				}
+			},
+			"delete": {
+				"operationId": "releaseSelected",
+				"summary": "Release the services whose labels match a selector",
+				"responses": {
+					"200": {
+						"description": "The IDs released",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"type": "string"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "selector",
+						"in": "query",
+						"required": true,
+						"description": "Label selector such as team=payments,env!=prod.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/services/release": {
+			"post": {
+				"operationId": "releaseServices",
+				"summary": "Release the services listed",
+				"responses": {
+					"200": {
+						"description": "Whether each ID held any ports",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "object",
+													"additionalProperties": {
+														"type": "boolean"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/BulkReleaseRequest"
+							}
+						}
+					}
+				}
+			}
+		},
+		"/services/{id}": {
+			"put": {
+				"operationId": "allocate",
+				"summary": "Assign ports to a service, or return those it holds",
+				"responses": {
+					"200": {
+						"description": "The service",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/Service"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"409": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"description": "The service ID.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"requestBody": {
+					"required": false,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/ServiceRequest"
+							}
+						}
+					}
+				}
+			},
+			"get": {
+				"operationId": "lookup",
+				"summary": "Return the ports held by a service",
+				"responses": {
+					"200": {
+						"description": "The service",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/Service"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"description": "The service ID.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			},
+			"delete": {
+				"operationId": "release",
+				"summary": "Release the ports held by a service",
+				"responses": {
+					"200": {
+						"description": "The service was released",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Response"
+								}
+							}
+						}
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"description": "The service ID.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/services/{id}/renew": {
+			"post": {
+				"operationId": "renew",
+				"summary": "Extend the lease on a service",
+				"responses": {
+					"200": {
+						"description": "The service",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/Service"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"409": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"description": "The service ID.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"requestBody": {
+					"required": false,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/RenewRequest"
+							}
+						}
+					}
+				}
+			}
+		},
+		"/ports": {
+			"get": {
+				"operationId": "listPorts",
+				"summary": "List and count the ports in a state",
+				"responses": {
+					"200": {
+						"description": "The ports and their count",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/PortList"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "state",
+						"in": "query",
+						"required": true,
+						"description": "Which ports to list: open, assigned, or cooling for the released ports waiting out the cooldown.",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"open",
+								"assigned",
+								"cooling"
+							]
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/ports/{port}": {
+			"get": {
+				"operationId": "whois",
+				"summary": "Return the ID holding a port",
+				"responses": {
+					"200": {
+						"description": "The port and its owner",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/PortOwner"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "port",
+						"in": "path",
+						"required": true,
+						"description": "The port.",
+						"schema": {
+							"type": "integer"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/quarantine": {
+			"get": {
+				"operationId": "listQuarantine",
+				"summary": "List the ports found in use outside of port-authority",
+				"responses": {
+					"200": {
+						"description": "The quarantined ports",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"$ref": "#/components/schemas/QuarantinedPort"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/quarantine/{port}": {
+			"delete": {
+				"operationId": "releaseQuarantine",
+				"summary": "Put a quarantined port back into the pool",
+				"responses": {
+					"200": {
+						"description": "The port was released",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Response"
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "port",
+						"in": "path",
+						"required": true,
+						"description": "The port.",
+						"schema": {
+							"type": "integer"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/mappings": {
+			"get": {
+				"operationId": "listMappings",
+				"summary": "Page through the IDs holding ports",
+				"responses": {
+					"200": {
+						"description": "A page of the mapping",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/MappingPage"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "cursor",
+						"in": "query",
+						"required": false,
+						"description": "The cursor of the previous page.",
+						"schema": {
+							"type": "integer",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "count",
+						"in": "query",
+						"required": false,
+						"description": "About how many IDs the page holds.",
+						"schema": {
+							"type": "integer",
+							"minimum": 1,
+							"maximum": 1000,
+							"default": 100
+						}
+					},
+					{
+						"name": "match",
+						"in": "query",
+						"required": false,
+						"description": "Glob pattern the IDs must match.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "prefix",
+						"in": "query",
+						"required": false,
+						"description": "Prefix the IDs must start with.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "selector",
+						"in": "query",
+						"required": false,
+						"description": "Label selector such as team=payments,env!=prod.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "label",
+						"in": "query",
+						"description": "KEY=VALUE label the IDs must have.",
+						"schema": {
+							"type": "array",
+							"items": {
+								"type": "string"
+							}
+						},
+						"explode": true
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/range": {
+			"get": {
+				"operationId": "getRange",
+				"summary": "Return the range of ports the pool manages",
+				"responses": {
+					"200": {
+						"description": "The range",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/PortRange"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			},
+			"put": {
+				"operationId": "setRange",
+				"summary": "Change the range of the pool, putting back ports which went missing from it",
+				"responses": {
+					"200": {
+						"description": "What changed",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/ReconcileReport"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"409": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"requestBody": {
+					"required": false,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/PortRange"
+							}
+						}
+					}
+				}
+			}
+		},
+		"/exclusions": {
+			"get": {
+				"operationId": "listExclusions",
+				"summary": "List the ports of the range the pool never hands out",
+				"responses": {
+					"200": {
+						"description": "The exclusions",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/ExclusionList"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			},
+			"put": {
+				"operationId": "setExclusions",
+				"summary": "Replace the exclusions of the pool",
+				"responses": {
+					"200": {
+						"description": "What changed",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"$ref": "#/components/schemas/ReconcileReport"
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"409": {
+						"$ref": "#/components/responses/Error"
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/ExclusionList"
+							}
+						}
+					}
+				}
+			}
+		},
+		"/fsck": {
+			"get": {
+				"operationId": "check",
+				"summary": "Look for inconsistencies in the data model of the pool",
+				"responses": {
+					"200": {
+						"description": "The inconsistencies found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"$ref": "#/components/schemas/Inconsistency"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			},
+			"post": {
+				"operationId": "repair",
+				"summary": "Repair the inconsistencies in the data model of the pool",
+				"responses": {
+					"200": {
+						"description": "The inconsistencies repaired",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"$ref": "#/components/schemas/Inconsistency"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"503": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Work on the ports of this host within the pool.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/events": {
+			"get": {
+				"operationId": "listEvents",
+				"summary": "List the events after an ID, waiting for one with wait. Without a pool in the path they cover every pool.",
+				"responses": {
+					"200": {
+						"description": "The events, oldest first",
+						"content": {
+							"application/json": {
+								"schema": {
+									"allOf": [
+										{
+											"$ref": "#/components/schemas/Response"
+										},
+										{
+											"type": "object",
+											"properties": {
+												"data": {
+													"type": "array",
+													"items": {
+														"$ref": "#/components/schemas/Event"
+													}
+												}
+											}
+										}
+									]
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				},
+				"parameters": [
+					{
+						"name": "since",
+						"in": "query",
+						"required": false,
+						"description": "The ID of the last event received; every event kept when missing.",
+						"schema": {
+							"type": "integer",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "wait",
+						"in": "query",
+						"required": false,
+						"description": "How long to wait, up to 5m, for an event when there is none yet.",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Only list the events of this host.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				]
+			}
+		},
+		"/events/stream": {
+			"get": {
+				"operationId": "streamEvents",
+				"summary": "Stream the events as Server-Sent Events, each with its ID, name and the Event as data. Without a pool in the path they cover every pool.",
+				"parameters": [
+					{
+						"name": "Last-Event-ID",
+						"in": "header",
+						"description": "The ID of the last event received, to resume after it.",
+						"schema": {
+							"type": "integer"
+						}
+					},
+					{
+						"name": "since",
+						"in": "query",
+						"required": false,
+						"description": "Like Last-Event-ID; without either the stream starts with the next event.",
+						"schema": {
+							"type": "integer",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "host",
+						"in": "query",
+						"required": false,
+						"description": "Only stream the events of this host.",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "The event stream",
+						"content": {
+							"text/event-stream": {
+								"schema": {
+									"type": "string"
+								}
+							}
+						}
+					},
+					"400": {
+						"$ref": "#/components/responses/Error"
+					},
+					"404": {
+						"$ref": "#/components/responses/Error"
+					},
+					"500": {
+						"$ref": "#/components/responses/Error"
+					}
+				}
+			}
+		},
+		"/openapi.json": {
+			"get": {
+				"operationId": "openAPI",
+				"summary": "This document",
+				"responses": {
+					"200": {
+						"description": "The OpenAPI document",
+						"content": {
+							"application/json": {}
+						}
+					}
+				}
+			}
+		}
+	},
+	"components": {
+		"responses": {
+			"Error": {
+				"description": "The call failed, see code",
+				"content": {
+					"application/json": {
+						"schema": {
+							"$ref": "#/components/schemas/Response"
+						}
+					}
+				}
+			}
+		},
+		"schemas": {
+			"Response": {
+				"type": "object",
+				"required": [
+					"status",
+					"message"
+				],
+				"properties": {
+					"status": {
+						"type": "string"
+					},
+					"message": {
+						"type": "string"
+					},
+					"code": {
+						"type": "string",
+						"enum": [
+							"invalid",
+							"not_found",
+							"conflict",
+							"exhausted",
+							"unavailable",
+							"internal"
+						],
+						"description": "Set when the call failed: invalid is a 400, not_found a 404, conflict a 409, exhausted and unavailable a 503 and internal a 500."
+					},
+					"data": {}
+				}
+			},
+			"Service": {
+				"type": "object",
+				"required": [
+					"id"
+				],
+				"properties": {
+					"id": {
+						"type": "string"
+					},
+					"port": {
+						"type": "integer",
+						"description": "The port held, or the first of a block."
+					},
+					"ports": {
+						"type": "array",
+						"items": {
+							"type": "integer"
+						},
+						"description": "The ports of a block."
+					},
+					"named": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "integer"
+						},
+						"description": "The port of each name."
+					},
+					"expires": {
+						"type": "string",
+						"format": "date-time"
+					},
+					"labels": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "string"
+						}
+					}
+				}
+			},
+			"ServiceRequest": {
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "string",
+						"description": "The service ID, only used in bulk allocations."
+					},
+					"lease": {
+						"type": "string",
+						"description": "Lease duration such as 90s or 5m."
+					},
+					"port": {
+						"type": "integer",
+						"description": "The port required, or preferred with prefer."
+					},
+					"prefer": {
+						"type": "boolean"
+					},
+					"block": {
+						"type": "integer",
+						"minimum": 1,
+						"description": "Number of contiguous ports."
+					},
+					"names": {
+						"type": "array",
+						"items": {
+							"type": "string"
+						},
+						"description": "Names of the ports to assign."
+					},
+					"labels": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "string"
+						}
+					},
+					"wait": {
+						"type": "string",
+						"description": "How long to wait for a port when the pool is exhausted, up to 5m. Bulk allocations don't wait."
+					}
+				}
+			},
+			"BulkPortRequest": {
+				"type": "object",
+				"required": [
+					"services"
+				],
+				"properties": {
+					"services": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/ServiceRequest"
+						}
+					}
+				}
+			},
+			"BulkReleaseRequest": {
+				"type": "object",
+				"required": [
+					"ids"
+				],
+				"properties": {
+					"ids": {
+						"type": "array",
+						"items": {
+							"type": "string"
+						}
+					}
+				}
+			},
+			"RenewRequest": {
+				"type": "object",
+				"properties": {
+					"lease": {
+						"type": "string",
+						"description": "Length of the new lease, that of the last one when empty."
+					}
+				}
+			},
+			"PortList": {
+				"type": "object",
+				"properties": {
+					"count": {
+						"type": "integer"
+					},
+					"ports": {
+						"type": "array",
+						"items": {
+							"type": "integer"
+						}
+					}
+				}
+			},
+			"PortOwner": {
+				"type": "object",
+				"properties": {
+					"port": {
+						"type": "integer"
+					},
+					"id": {
+						"type": "string"
+					}
+				}
+			},
+			"PortRange": {
+				"type": "object",
+				"properties": {
+					"start": {
+						"type": "integer",
+						"description": "The first port; left as it is when missing."
+					},
+					"end": {
+						"type": "integer",
+						"description": "The port after the last one; left as it is when missing."
+					}
+				}
+			},
+			"ExclusionList": {
+				"type": "object",
+				"required": [
+					"excluded"
+				],
+				"properties": {
+					"excluded": {
+						"type": "array",
+						"items": {
+							"type": "string"
+						},
+						"description": "Each a PORT or an inclusive START-END range."
+					}
+				}
+			},
+			"ReconcileReport": {
+				"type": "object",
+				"properties": {
+					"start": {
+						"type": "integer"
+					},
+					"end": {
+						"type": "integer"
+					},
+					"excluded": {
+						"type": "array",
+						"items": {
+							"type": "string"
+						}
+					},
+					"added": {
+						"type": "array",
+						"items": {
+							"type": "integer"
+						},
+						"description": "The ports newly put in the pool."
+					},
+					"removed": {
+						"type": "array",
+						"items": {
+							"type": "integer"
+						},
+						"description": "The free ports taken out of the pool."
+					},
+					"stranded": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "string"
+						},
+						"description": "The ID holding each assigned port now outside of the range or excluded."
+					}
+				}
+			},
+			"Inconsistency": {
+				"type": "object",
+				"properties": {
+					"kind": {
+						"type": "string"
+					},
+					"id": {
+						"type": "string"
+					},
+					"port": {
+						"type": "string"
+					},
+					"detail": {
+						"type": "string"
+					},
+					"repair": {
+						"type": "string",
+						"description": "What repairing it does."
+					}
+				}
+			},
+			"QuarantinedPort": {
+				"type": "object",
+				"properties": {
+					"port": {
+						"type": "integer"
+					},
+					"since": {
+						"type": "string",
+						"format": "date-time"
+					}
+				}
+			},
+			"Event": {
+				"type": "object",
+				"properties": {
+					"id": {
+						"type": "integer",
+						"description": "Increases with each event."
+					},
+					"name": {
+						"type": "string",
+						"enum": [
+							"allocated",
+							"released",
+							"expired",
+							"renewed",
+							"repaired"
+						]
+					},
+					"stamp": {
+						"type": "string",
+						"format": "date-time"
+					},
+					"data": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "string"
+						},
+						"description": "pool, host, id and, depending on the event, ports, named, expires, or kind, port and repair for a repair."
+					}
+				}
+			},
+			"MappingPage": {
+				"type": "object",
+				"properties": {
+					"mappings": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "integer"
+						}
+					},
+					"labels": {
+						"type": "object",
+						"additionalProperties": {
+							"type": "object",
+							"additionalProperties": {
+								"type": "string"
+							}
+						}
+					},
+					"cursor": {
+						"type": "integer",
+						"description": "Pass as cursor for the next page, 0 once everything was listed."
+					}
+				}
+			}
+		}
+	}
+}
+`
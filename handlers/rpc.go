@@ -0,0 +1,607 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/therealbill/port-authority/rpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rpcErrorDomain is the domain of the ErrorInfo detail of failed RPC calls.
+const rpcErrorDomain = "port-authority"
+
+// rpcCodes holds the gRPC code of each common Code constant.
+var rpcCodes = map[string]codes.Code{
+	common.CodeInvalid:     codes.InvalidArgument,
+	common.CodeNotFound:    codes.NotFound,
+	common.CodeConflict:    codes.AlreadyExists,
+	common.CodeExhausted:   codes.ResourceExhausted,
+	common.CodeUnavailable: codes.Unavailable,
+	common.CodeInternal:    codes.Internal,
+}
+
+// RPC serves the PortAuthority gRPC service of rpc/portauthority.proto,
+// which offers the operations of V2. A failed call returns the gRPC code
+// of its kind, see rpcCodes, with an ErrorInfo detail whose reason is the
+// common Code constant, see RPCErrorCode.
+type RPC struct {
+	rpc.UnimplementedPortAuthorityServer
+	api *API
+}
+
+// NewRPC returns the RPC service serving the pools of api.
+func NewRPC(api *API) *RPC {
+	return &RPC{api: api}
+}
+
+// ServeRPC serves the RPC service of api over gRPC on the connections
+// accepted by listener. It only returns when it can't accept connections
+// anymore, e.g. once listener is closed.
+func ServeRPC(api *API, listener net.Listener) error {
+	server := grpc.NewServer()
+	rpc.RegisterPortAuthorityServer(server, NewRPC(api))
+	log.Printf("Serving RPC on %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+// RPCErrorCode returns the common Code constant an RPC call failed with,
+// or an empty string if err didn't come from the server.
+func RPCErrorCode(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == rpcErrorDomain {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+// fail returns err as an RPC call reports it, see RPC. The ErrorInfo
+// detail names the holder of the port of a conflict as "owner" and the
+// service a bulk allocation failed on as "id".
+func (s *RPC) fail(err error) error {
+	reply, message := describeError(err)
+	info := &errdetails.ErrorInfo{Reason: reply.Code, Domain: rpcErrorDomain, Metadata: map[string]string{}}
+	if bulk, ok := err.(*actions.BulkError); ok {
+		info.Metadata["id"] = bulk.ID
+		err = bulk.Err
+	}
+	if conflict, ok := err.(*actions.PortConflictError); ok {
+		info.Metadata["owner"] = conflict.Owner
+	}
+	st, detailErr := status.New(rpcCodes[reply.Code], message).WithDetails(info)
+	if detailErr != nil {
+		return status.Error(rpcCodes[reply.Code], message)
+	}
+	return st.Err()
+}
+
+// store returns the store of the pool t picks.
+func (s *RPC) store(t *rpc.Target) (actions.PortStore, error) {
+	return s.open(s.api.lookup(t.GetPool(), t.GetHost(), false))
+}
+
+// allocationStore is store for the calls allocating ports, which add the
+// host to the pool when it is new.
+func (s *RPC) allocationStore(t *rpc.Target) (actions.PortStore, error) {
+	return s.open(s.api.lookup(t.GetPool(), t.GetHost(), true))
+}
+
+// open returns store, or err as an RPC call reports it.
+func (s *RPC) open(store actions.PortStore, err error) (actions.PortStore, error) {
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return store, nil
+}
+
+// Pools lists the names of the pools served by this instance.
+func (s *RPC) Pools(ctx context.Context, req *emptypb.Empty) (*rpc.NameList, error) {
+	return &rpc.NameList{Names: s.api.Pools.Names()}, nil
+}
+
+// Hosts lists the hosts the pool has assigned ports on.
+func (s *RPC) Hosts(ctx context.Context, req *rpc.Target) (*rpc.NameList, error) {
+	store, err := s.store(req)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := store.Hosts()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.NameList{Names: hosts}, nil
+}
+
+// Allocate assigns ports to a service, or returns those it holds, see
+// V2.Allocate.
+func (s *RPC) Allocate(ctx context.Context, req *rpc.AllocateRequest) (*rpc.Service, error) {
+	store, err := s.allocationStore(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	service := serviceRequest(req.Service)
+	opts, err := serviceOptions(service)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	wait, err := waitDuration(durationString(req.Wait))
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	assignment, err := actions.AllocateWait(store, service.ID, opts, wait, ctx.Done())
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return rpcService(assignment), nil
+}
+
+// AllocateServices assigns ports to every service listed, or to none of
+// them, see API.AllocateServices.
+func (s *RPC) AllocateServices(ctx context.Context, req *rpc.AllocateServicesRequest) (*rpc.ServiceMap, error) {
+	store, err := s.allocationStore(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Services) == 0 {
+		return nil, s.fail(invalidf("List the services to allocate ports for"))
+	}
+	services := make([]common.ServiceRequest, len(req.Services))
+	for i, service := range req.Services {
+		services[i] = serviceRequest(service)
+	}
+	reqs, err := allocateRequests(services)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	assignments, err := store.AllocateAll(reqs)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	allocated := &rpc.ServiceMap{Services: make(map[string]*rpc.Service, len(assignments))}
+	for id, assignment := range assignments {
+		allocated.Services[id] = rpcService(assignment)
+	}
+	return allocated, nil
+}
+
+// Lookup returns a service, failing with NotFound if it holds no ports.
+func (s *RPC) Lookup(ctx context.Context, req *rpc.ServiceRef) (*rpc.Service, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := store.GetAssignment(req.Id)
+	if err == nil && assignment.Empty() {
+		err = notFoundf("No port is assigned to '%s'", req.Id)
+	}
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return rpcService(assignment), nil
+}
+
+// Services lists the services whose labels match the selector, every
+// service when it is empty.
+func (s *RPC) Services(ctx context.Context, req *rpc.SelectRequest) (*rpc.ServiceList, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := actions.ParseSelector(req.Selector)
+	if err != nil {
+		return nil, s.fail(invalidf("%s", err))
+	}
+	selected, err := actions.SelectServices(store, sel)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	services := make([]*rpc.Service, len(selected))
+	for i, assignment := range selected {
+		services[i] = rpcService(assignment)
+	}
+	return &rpc.ServiceList{Services: services}, nil
+}
+
+// Release releases a service, failing with NotFound if it held no ports.
+func (s *RPC) Release(ctx context.Context, req *rpc.ServiceRef) (*emptypb.Empty, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	released, err := store.RemoveServices([]string{req.Id})
+	if err == nil && !released[req.Id] {
+		err = notFoundf("No port is assigned to '%s'", req.Id)
+	}
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ReleaseServices releases the services listed at once and reports for
+// each whether it held any ports.
+func (s *RPC) ReleaseServices(ctx context.Context, req *rpc.ReleaseServicesRequest) (*rpc.ReleasedMap, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Ids) == 0 {
+		return nil, s.fail(invalidf("List the ids of the services to release"))
+	}
+	released, err := store.RemoveServices(req.Ids)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.ReleasedMap{Released: released}, nil
+}
+
+// ReleaseSelected releases the services whose labels match the selector,
+// which is required, and lists their ids.
+func (s *RPC) ReleaseSelected(ctx context.Context, req *rpc.SelectRequest) (*rpc.IDList, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := actions.ParseSelector(req.Selector)
+	if err == nil && len(sel) == 0 {
+		err = errors.New("Pass a selector naming the services to release")
+	}
+	if err != nil {
+		return nil, s.fail(invalidf("%s", err))
+	}
+	released, err := actions.ReleaseServices(store, sel)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.IDList{Ids: released}, nil
+}
+
+// Renew extends the lease on a service, see V2.Renew.
+func (s *RPC) Renew(ctx context.Context, req *rpc.RenewRequest) (*rpc.Service, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	lease := req.Lease.AsDuration()
+	if req.Lease != nil && (req.Lease.CheckValid() != nil || lease < 0) {
+		return nil, s.fail(invalidf("Invalid lease passed. Use a duration such as 90s or 5m"))
+	}
+	assignment, err := store.RenewLease(req.Id, lease)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return rpcService(assignment), nil
+}
+
+// Whois returns the id holding a port, failing with NotFound if none does.
+func (s *RPC) Whois(ctx context.Context, req *rpc.PortRequest) (*rpc.PortOwner, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	id, err := store.GetInstanceFromPort(int(req.Port))
+	if err == nil && len(id) == 0 {
+		err = notFoundf("Port %d is not assigned", req.Port)
+	}
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.PortOwner{Port: req.Port, Id: id}, nil
+}
+
+// Ports lists the ports in a state, in numeric order.
+func (s *RPC) Ports(ctx context.Context, req *rpc.PortsRequest) (*rpc.PortList, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	var list func() ([]string, error)
+	switch req.State {
+	case rpc.PortState_OPEN:
+		list = store.GetOpenPortList
+	case rpc.PortState_ASSIGNED:
+		list = store.GetReservedPortList
+	case rpc.PortState_COOLING:
+		list = store.GetCoolingPortList
+	default:
+		return nil, s.fail(invalidf("Invalid state passed. Use OPEN, ASSIGNED or COOLING"))
+	}
+	listed, err := list()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.PortList{Ports: rpcPorts(portNumbers(listed))}, nil
+}
+
+// Counts counts the open, assigned and cooling ports of the pool.
+func (s *RPC) Counts(ctx context.Context, req *rpc.Target) (*rpc.PortCounts, error) {
+	store, err := s.store(req)
+	if err != nil {
+		return nil, err
+	}
+	counts := &rpc.PortCounts{}
+	for _, c := range []struct {
+		count *int64
+		get   func() (int64, error)
+	}{
+		{&counts.Open, store.GetOpenPortCount},
+		{&counts.Assigned, store.GetReservedPortCount},
+		{&counts.Cooling, store.GetCoolingPortCount},
+	} {
+		if *c.count, err = c.get(); err != nil {
+			return nil, s.fail(err)
+		}
+	}
+	return counts, nil
+}
+
+// Mappings returns a page of the mapping of ids to ports, see
+// API.GetMappings.
+func (s *RPC) Mappings(ctx context.Context, req *rpc.MappingsRequest) (*rpc.MappingPage, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	count := int(req.Count)
+	if count == 0 {
+		count = 100
+	}
+	if count < 1 || count > maxPageSize {
+		return nil, s.fail(invalidf("Invalid count passed. Use a number from 1 to %d", maxPageSize))
+	}
+	sel, err := actions.ParseSelector(req.Selector)
+	if err != nil {
+		return nil, s.fail(invalidf("%s", err))
+	}
+	page, err := selectMappings(store, req.Cursor, req.Match, count, sel)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	reply := &rpc.MappingPage{
+		Mappings: make(map[string]int32, len(page.Mappings)),
+		Labels:   make(map[string]*rpc.Labels, len(page.Labels)),
+		Cursor:   page.Cursor,
+	}
+	for id, port := range page.Mappings {
+		reply.Mappings[id] = int32(port)
+	}
+	for id, labels := range page.Labels {
+		reply.Labels[id] = &rpc.Labels{Labels: labels}
+	}
+	return reply, nil
+}
+
+// Events returns the events after req.Since, see V2.ListEvents.
+func (s *RPC) Events(ctx context.Context, req *rpc.EventsRequest) (*rpc.EventList, error) {
+	match, err := s.api.eventFilter(req.Target.GetPool(), req.Target.GetHost())
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	wait, err := waitDuration(durationString(req.Wait))
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	if req.Since < 0 {
+		return nil, s.fail(invalidf("Invalid event ID passed. Use the ID of the last event received"))
+	}
+	polled := pollEvents(s.api.Events, req.Since, wait, match, ctx.Done())
+	events := make([]*rpc.Event, len(polled))
+	for i, e := range polled {
+		events[i] = &rpc.Event{Id: e.ID, Name: e.Name, Stamp: timestamppb.New(e.Stamp), Data: e.Data}
+	}
+	return &rpc.EventList{Events: events}, nil
+}
+
+// Quarantine lists the quarantined ports, see API.GetQuarantine.
+func (s *RPC) Quarantine(ctx context.Context, req *rpc.Target) (*rpc.QuarantineList, error) {
+	store, err := s.store(req)
+	if err != nil {
+		return nil, err
+	}
+	quarantined, err := store.GetQuarantine()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	ports := make([]*rpc.QuarantinedPort, len(quarantined))
+	for i, q := range quarantined {
+		ports[i] = &rpc.QuarantinedPort{Port: int32(q.Port), Since: timestamppb.New(q.Since)}
+	}
+	return &rpc.QuarantineList{Ports: ports}, nil
+}
+
+// Unquarantine puts a quarantined port back into the pool.
+func (s *RPC) Unquarantine(ctx context.Context, req *rpc.PortRequest) (*emptypb.Empty, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Unquarantine(int(req.Port)); err != nil {
+		return nil, s.fail(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// Range returns the range of ports the pool manages.
+func (s *RPC) Range(ctx context.Context, req *rpc.Target) (*rpc.PortRange, error) {
+	store, err := s.store(req)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := store.GetRange()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.PortRange{Start: int32(start), End: int32(end)}, nil
+}
+
+// SetRange changes the range of ports the pool manages, keeping the bounds
+// left at zero, see V2.SetRange.
+func (s *RPC) SetRange(ctx context.Context, req *rpc.SetRangeRequest) (*rpc.ReconcileReport, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := store.GetRange()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	if req.Range.GetStart() != 0 {
+		start = int(req.Range.GetStart())
+	}
+	if req.Range.GetEnd() != 0 {
+		end = int(req.Range.GetEnd())
+	}
+	excluded, err := store.GetExclusions()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return s.reconcile(store, start, end, excluded)
+}
+
+// Exclusions lists the ports and port ranges within the range of the pool
+// which are never handed out.
+func (s *RPC) Exclusions(ctx context.Context, req *rpc.Target) (*rpc.ExclusionList, error) {
+	store, err := s.store(req)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := store.GetExclusions()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return &rpc.ExclusionList{Excluded: exclusionStrings(excluded)}, nil
+}
+
+// SetExclusions replaces the exclusions of the pool, keeping its range.
+// An empty list removes them all.
+func (s *RPC) SetExclusions(ctx context.Context, req *rpc.SetExclusionsRequest) (*rpc.ReconcileReport, error) {
+	store, err := s.store(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := actions.ParseExclusions(strings.Join(req.Excluded, ","))
+	if err != nil {
+		return nil, s.fail(invalidf("%s", err))
+	}
+	start, end, err := store.GetRange()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return s.reconcile(store, start, end, excluded)
+}
+
+// reconcile reconciles store with the range and exclusions given and
+// reports what changed.
+func (s *RPC) reconcile(store actions.PortStore, start, end int, excluded []actions.Exclusion) (*rpc.ReconcileReport, error) {
+	report, err := store.Reconcile(start, end, excluded)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	stranded := make(map[int32]string, len(report.Stranded))
+	for port, id := range report.Stranded {
+		if p, err := strconv.Atoi(port); err == nil {
+			stranded[int32(p)] = id
+		}
+	}
+	return &rpc.ReconcileReport{
+		Range:    &rpc.PortRange{Start: int32(report.Start), End: int32(report.End)},
+		Excluded: exclusionStrings(report.Excluded),
+		Added:    rpcPorts(portNumbers(report.Added)),
+		Removed:  rpcPorts(portNumbers(report.Removed)),
+		Stranded: stranded,
+	}, nil
+}
+
+// Check reports the inconsistencies in the data model of the pool.
+func (s *RPC) Check(ctx context.Context, req *rpc.Target) (*rpc.InconsistencyList, error) {
+	return s.check(req, false)
+}
+
+// Repair repairs the inconsistencies in the data model of the pool and
+// reports what it repaired.
+func (s *RPC) Repair(ctx context.Context, req *rpc.Target) (*rpc.InconsistencyList, error) {
+	return s.check(req, true)
+}
+
+func (s *RPC) check(t *rpc.Target, repair bool) (*rpc.InconsistencyList, error) {
+	store, err := s.store(t)
+	if err != nil {
+		return nil, err
+	}
+	found, err := store.Check(repair)
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	inconsistencies := make([]*rpc.Inconsistency, len(found))
+	for i, f := range found {
+		inconsistencies[i] = &rpc.Inconsistency{Kind: f.Kind, Id: f.ID, Port: f.Port, Detail: f.Detail, Repair: f.Repair}
+	}
+	return &rpc.InconsistencyList{Inconsistencies: inconsistencies}, nil
+}
+
+// serviceRequest returns req as the handlers of /api/v2 read it, so that
+// serviceOptions checks it.
+func serviceRequest(req *rpc.ServiceRequest) common.ServiceRequest {
+	service := common.ServiceRequest{
+		ID:     req.GetId(),
+		Lease:  durationString(req.GetLease()),
+		Port:   int(req.GetPort()),
+		Prefer: req.GetPrefer(),
+		Block:  int(req.GetBlock()),
+		Names:  req.GetNames(),
+	}
+	if req.GetLabels() != nil {
+		service.Labels = make(map[string]string, len(req.Labels.Labels))
+		for key, value := range req.Labels.Labels {
+			service.Labels[key] = value
+		}
+	}
+	return service
+}
+
+// durationString returns d as a duration such as 5m0s, an empty string
+// when it is unset.
+func durationString(d *durationpb.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.AsDuration().String()
+}
+
+// rpcService returns a as the RPC service shows it, see serviceResource.
+func rpcService(a actions.Assignment) *rpc.Service {
+	resource := serviceResource(a)
+	s := &rpc.Service{Id: resource.ID, Port: int32(resource.Port), Ports: rpcPorts(resource.Ports), Labels: resource.Labels}
+	if resource.Expires != nil {
+		s.Expires = timestamppb.New(*resource.Expires)
+	}
+	if len(resource.Named) > 0 {
+		s.Named = make(map[string]int32, len(resource.Named))
+		for name, port := range resource.Named {
+			s.Named[name] = int32(port)
+		}
+	}
+	return s
+}
+
+// rpcPorts returns ports as the RPC service lists them.
+func rpcPorts(ports []int) []int32 {
+	listed := make([]int32, len(ports))
+	for i, port := range ports {
+		listed[i] = int32(port)
+	}
+	return listed
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/therealbill/port-authority/rpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// newRPCClient serves the RPC service over memory stores, as newTestAPI
+// does the /api routes, handing out the lowest free port first, and
+// returns a client calling it.
+func newRPCClient(t *testing.T) rpc.PortAuthorityClient {
+	t.Helper()
+	store := actions.NewMemoryStore()
+	store.SetStrategy(actions.StrategyLowest)
+	tiny := actions.NewMemoryStore()
+	for s, size := range map[*actions.MemoryStore]int{store: 10, tiny: 1} {
+		if err := s.InitializePorts(30000, 30000+size, nil); err != nil {
+			t.Fatalf("InitializePorts(): %v", err)
+		}
+	}
+	api := NewAPI(nil)
+	api.Pools = actions.Pools{
+		actions.DefaultPool: store,
+		"tiny":              tiny,
+		"down":              failingStore{store, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}},
+	}
+	listener := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- ServeRPC(api, listener) }()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient(): %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		listener.Close()
+		if err := <-served; err == nil {
+			t.Errorf("ServeRPC() returned no error once its listener was closed")
+		}
+	})
+	return rpc.NewPortAuthorityClient(conn)
+}
+
+// checkRPCError fails the test unless err is a failed RPC call with code
+// and the common Code constant reason.
+func checkRPCError(t *testing.T, call string, err error, code codes.Code, reason string) {
+	t.Helper()
+	if status.Code(err) != code || RPCErrorCode(err) != reason {
+		t.Errorf("%s = %v, want %v with reason %q", call, err, code, reason)
+	}
+}
+
+func TestRPCAllocate(t *testing.T) {
+	client := newRPCClient(t)
+	ctx := context.Background()
+	labels := &rpc.Labels{Labels: map[string]string{"team": "cars"}}
+	web, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "web", Port: 30005, Lease: durationpb.New(time.Minute), Labels: labels}})
+	if err != nil {
+		t.Fatalf("Allocate(): %v", err)
+	}
+	if web.Id != "web" || web.Port != 30005 || web.Expires == nil || web.Labels["team"] != "cars" {
+		t.Errorf("Allocate() = %v, want web on 30005 with a lease and its labels", web)
+	}
+	db, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "db", Block: 2}})
+	if err != nil || !reflect.DeepEqual(db.Ports, []int32{30000, 30001}) {
+		t.Errorf("Allocate() of a block = %v, %v, want 30000 and 30001", db, err)
+	}
+	app, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "app", Names: []string{"http", "admin"}}})
+	if err != nil || len(app.Named) != 2 {
+		t.Errorf("Allocate() of named ports = %v, %v, want two", app, err)
+	}
+
+	if got, err := client.Lookup(ctx, &rpc.ServiceRef{Id: "web"}); err != nil || got.Port != 30005 {
+		t.Errorf("Lookup() = %v, %v, want web on 30005", got, err)
+	}
+	if owner, err := client.Whois(ctx, &rpc.PortRequest{Port: 30005}); err != nil || owner.Id != "web" {
+		t.Errorf("Whois() = %v, %v, want web", owner, err)
+	}
+	if list, err := client.Services(ctx, &rpc.SelectRequest{Selector: "team=cars"}); err != nil || len(list.Services) != 1 || list.Services[0].Id != "web" {
+		t.Errorf("Services(team=cars) = %v, %v, want web", list, err)
+	}
+	if assigned, err := client.Ports(ctx, &rpc.PortsRequest{State: rpc.PortState_ASSIGNED}); err != nil || len(assigned.Ports) != 5 {
+		t.Errorf("Ports(ASSIGNED) = %v, %v, want 5 ports", assigned, err)
+	}
+	page, err := client.Mappings(ctx, &rpc.MappingsRequest{Match: "w*"})
+	if err != nil || !reflect.DeepEqual(page.Mappings, map[string]int32{"web": 30005}) || page.Labels["web"].GetLabels()["team"] != "cars" {
+		t.Errorf("Mappings(w*) = %v, %v, want web and its labels", page, err)
+	}
+
+	// Unset labels are left alone, an empty set clears them.
+	if again, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "web"}}); err != nil || again.Labels["team"] != "cars" {
+		t.Errorf("Allocate() again without labels = %v, %v, want the labels kept", again, err)
+	}
+	if again, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "web", Labels: &rpc.Labels{}}}); err != nil || len(again.Labels) != 0 {
+		t.Errorf("Allocate() again with no labels = %v, %v, want them cleared", again, err)
+	}
+
+	if _, err := client.Release(ctx, &rpc.ServiceRef{Id: "web"}); err != nil {
+		t.Errorf("Release(): %v", err)
+	}
+	counts, err := client.Counts(ctx, &rpc.Target{})
+	if want := (&rpc.PortCounts{Open: 6, Assigned: 4}); err != nil || counts.Open != want.Open || counts.Assigned != want.Assigned || counts.Cooling != want.Cooling {
+		t.Errorf("Counts() = %v, %v, want %v", counts, err, want)
+	}
+	released, err := client.ReleaseServices(ctx, &rpc.ReleaseServicesRequest{Ids: []string{"db", "nobody"}})
+	if err != nil || !reflect.DeepEqual(released.Released, map[string]bool{"db": true, "nobody": false}) {
+		t.Errorf("ReleaseServices() = %v, %v, want db released and nobody not", released, err)
+	}
+}
+
+func TestRPCAllocateServices(t *testing.T) {
+	client := newRPCClient(t)
+	ctx := context.Background()
+	allocated, err := client.AllocateServices(ctx, &rpc.AllocateServicesRequest{Services: []*rpc.ServiceRequest{{Id: "web"}, {Id: "db", Port: 30007}}})
+	if err != nil || len(allocated.Services) != 2 || allocated.Services["db"].Port != 30007 {
+		t.Fatalf("AllocateServices() = %v, %v, want web and db on 30007", allocated, err)
+	}
+	_, err = client.AllocateServices(ctx, &rpc.AllocateServicesRequest{Services: []*rpc.ServiceRequest{{Id: "cache"}, {Id: "queue", Port: 30007}}})
+	checkRPCError(t, "AllocateServices() of a taken port", err, codes.AlreadyExists, common.CodeConflict)
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && (info.Metadata["id"] != "queue" || info.Metadata["owner"] != "db") {
+			t.Errorf("AllocateServices() of a taken port = metadata %v, want queue and its owner db", info.Metadata)
+		}
+	}
+	if _, err := client.Lookup(ctx, &rpc.ServiceRef{Id: "cache"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Lookup() of a service of a failed bulk allocation = %v, want NotFound", err)
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	client := newRPCClient(t)
+	ctx := context.Background()
+	if _, err := client.Allocate(ctx, &rpc.AllocateRequest{Target: &rpc.Target{Pool: "tiny"}, Service: &rpc.ServiceRequest{Id: "a"}}); err != nil {
+		t.Fatalf("Allocate(): %v", err)
+	}
+	if _, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "web", Port: 30005}}); err != nil {
+		t.Fatalf("Allocate(): %v", err)
+	}
+
+	_, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "db", Port: 30005}})
+	checkRPCError(t, "Allocate() of a taken port", err, codes.AlreadyExists, common.CodeConflict)
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Metadata["owner"] != "web" {
+			t.Errorf("Allocate() of a taken port = metadata %v, want its owner web", info.Metadata)
+		}
+	}
+	_, err = client.Allocate(ctx, &rpc.AllocateRequest{Target: &rpc.Target{Pool: "tiny"}, Service: &rpc.ServiceRequest{Id: "b"}})
+	checkRPCError(t, "Allocate() in an exhausted pool", err, codes.ResourceExhausted, common.CodeExhausted)
+	_, err = client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "db", Lease: durationpb.New(-time.Minute)}})
+	checkRPCError(t, "Allocate() with a negative lease", err, codes.InvalidArgument, common.CodeInvalid)
+	_, err = client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{}})
+	checkRPCError(t, "Allocate() without an ID", err, codes.InvalidArgument, common.CodeInvalid)
+	_, err = client.Lookup(ctx, &rpc.ServiceRef{Id: "nobody"})
+	checkRPCError(t, "Lookup() of nobody", err, codes.NotFound, common.CodeNotFound)
+	_, err = client.Lookup(ctx, &rpc.ServiceRef{Target: &rpc.Target{Pool: "nope"}, Id: "web"})
+	checkRPCError(t, "Lookup() in an unknown pool", err, codes.NotFound, common.CodeNotFound)
+	_, err = client.Lookup(ctx, &rpc.ServiceRef{Target: &rpc.Target{Pool: "down"}, Id: "web"})
+	checkRPCError(t, "Lookup() in an unreachable pool", err, codes.Unavailable, common.CodeUnavailable)
+	_, err = client.Release(ctx, &rpc.ServiceRef{Id: "nobody"})
+	checkRPCError(t, "Release() of nobody", err, codes.NotFound, common.CodeNotFound)
+	_, err = client.ReleaseSelected(ctx, &rpc.SelectRequest{})
+	checkRPCError(t, "ReleaseSelected() without a selector", err, codes.InvalidArgument, common.CodeInvalid)
+	_, err = client.Ports(ctx, &rpc.PortsRequest{State: rpc.PortState(7)})
+	checkRPCError(t, "Ports() in an unknown state", err, codes.InvalidArgument, common.CodeInvalid)
+	_, err = client.Mappings(ctx, &rpc.MappingsRequest{Count: maxPageSize + 1})
+	checkRPCError(t, "Mappings() of too many ids", err, codes.InvalidArgument, common.CodeInvalid)
+	_, err = client.Events(ctx, &rpc.EventsRequest{Wait: durationpb.New(time.Hour)})
+	checkRPCError(t, "Events() waiting an hour", err, codes.InvalidArgument, common.CodeInvalid)
+
+	if RPCErrorCode(errors.New("not from the server")) != "" {
+		t.Errorf("RPCErrorCode() of an error which didn't come from the server isn't empty")
+	}
+}
+
+func TestRPCRangeAndExclusions(t *testing.T) {
+	client := newRPCClient(t)
+	ctx := context.Background()
+	if _, err := client.Allocate(ctx, &rpc.AllocateRequest{Service: &rpc.ServiceRequest{Id: "web", Port: 30009}}); err != nil {
+		t.Fatalf("Allocate(): %v", err)
+	}
+	if r, err := client.Range(ctx, &rpc.Target{}); err != nil || r.Start != 30000 || r.End != 30010 {
+		t.Errorf("Range() = %v, %v, want 30000 to 30010", r, err)
+	}
+
+	report, err := client.SetRange(ctx, &rpc.SetRangeRequest{Range: &rpc.PortRange{End: 30008}})
+	if err != nil {
+		t.Fatalf("SetRange(): %v", err)
+	}
+	if report.Range.Start != 30000 || report.Range.End != 30008 || !reflect.DeepEqual(report.Removed, []int32{30008}) || report.Stranded[30009] != "web" {
+		t.Errorf("SetRange() = %v, want 30000 to 30008, 30008 removed and web stranded on 30009", report)
+	}
+
+	report, err = client.SetExclusions(ctx, &rpc.SetExclusionsRequest{Excluded: []string{"30001-30002"}})
+	if err != nil || !reflect.DeepEqual(report.Excluded, []string{"30001-30002"}) || !reflect.DeepEqual(report.Removed, []int32{30001, 30002}) {
+		t.Errorf("SetExclusions() = %v, %v, want 30001 and 30002 removed", report, err)
+	}
+	if list, err := client.Exclusions(ctx, &rpc.Target{}); err != nil || !reflect.DeepEqual(list.Excluded, []string{"30001-30002"}) {
+		t.Errorf("Exclusions() = %v, %v, want 30001-30002", list, err)
+	}
+	if report, err = client.SetExclusions(ctx, &rpc.SetExclusionsRequest{}); err != nil || !reflect.DeepEqual(report.Added, []int32{30001, 30002}) {
+		t.Errorf("SetExclusions() of none = %v, %v, want 30001 and 30002 added back", report, err)
+	}
+	_, err = client.SetExclusions(ctx, &rpc.SetExclusionsRequest{Excluded: []string{"http"}})
+	checkRPCError(t, "SetExclusions() of a name", err, codes.InvalidArgument, common.CodeInvalid)
+}
+
+func TestRPCCheck(t *testing.T) {
+	client := newRPCClient(t)
+	ctx := context.Background()
+	for _, call := range []func(context.Context, *rpc.Target, ...grpc.CallOption) (*rpc.InconsistencyList, error){client.Check, client.Repair} {
+		if list, err := call(ctx, &rpc.Target{}); err != nil || len(list.Inconsistencies) != 0 {
+			t.Errorf("Check() of a clean pool = %v, %v, want nothing", list, err)
+		}
+	}
+	_, err := client.Check(ctx, &rpc.Target{Pool: "nope"})
+	checkRPCError(t, "Check() of an unknown pool", err, codes.NotFound, common.CodeNotFound)
+}
+
+func TestRPCPoolsAndHosts(t *testing.T) {
+	client := newRPCClient(t)
+	ctx := context.Background()
+	if _, err := client.Allocate(ctx, &rpc.AllocateRequest{Target: &rpc.Target{Host: "node1"}, Service: &rpc.ServiceRequest{Id: "web"}}); err != nil {
+		t.Fatalf("Allocate() on node1: %v", err)
+	}
+	if pools, err := client.Pools(ctx, &emptypb.Empty{}); err != nil || len(pools.Names) != 3 {
+		t.Errorf("Pools() = %v, %v, want 3 pools", pools, err)
+	}
+	if hosts, err := client.Hosts(ctx, &rpc.Target{}); err != nil || !reflect.DeepEqual(hosts.Names, []string{"node1"}) {
+		t.Errorf("Hosts() = %v, %v, want node1", hosts, err)
+	}
+	_, err := client.Lookup(ctx, &rpc.ServiceRef{Target: &rpc.Target{Host: "node2"}, Id: "web"})
+	checkRPCError(t, "Lookup() on an unseen host", err, codes.NotFound, common.CodeNotFound)
+}
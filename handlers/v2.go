@@ -0,0 +1,622 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/therealbill/port-authority/actions"
+	"github.com/therealbill/port-authority/common"
+	"github.com/zenazn/goji/web"
+)
+
+// V2 serves /api/v2. It offers what API does, but organized around services
+// and ports, taking allocation options as JSON bodies and replying with a
+// common.Response whose keys are lower case.
+type V2 struct {
+	api *API
+}
+
+// NewV2 returns the /api/v2 handlers serving the pools of api.
+func NewV2(api *API) *V2 {
+	return &V2{api: api}
+}
+
+// Route registers the handlers of /api/v2 with m. Every path but the list of
+// pools is also served under /api/v2/pools/:pool for the other pools.
+func (v *V2) Route(m *web.Mux) {
+	m.Get("/api/v2/openapi.json", v.OpenAPI)
+	m.Get("/api/v2/pools", v.ListPools)
+	for _, prefix := range []string{"/api/v2", "/api/v2/pools/:pool"} {
+		m.Get(prefix+"/services", v.ListServices)
+		m.Post(prefix+"/services", v.AllocateServices)
+		m.Delete(prefix+"/services", v.ReleaseSelected)
+		m.Post(prefix+"/services/release", v.ReleaseServiceList)
+		m.Put(prefix+"/services/:id", v.Allocate)
+		m.Get(prefix+"/services/:id", v.Lookup)
+		m.Delete(prefix+"/services/:id", v.Release)
+		m.Post(prefix+"/services/:id/renew", v.Renew)
+		m.Get(prefix+"/ports", v.ListPorts)
+		m.Get(prefix+"/ports/:port", v.Whois)
+		m.Get(prefix+"/quarantine", v.ListQuarantine)
+		m.Delete(prefix+"/quarantine/:port", v.ReleaseQuarantine)
+		m.Get(prefix+"/mappings", v.ListMappings)
+		m.Get(prefix+"/range", v.GetRange)
+		m.Put(prefix+"/range", v.SetRange)
+		m.Get(prefix+"/exclusions", v.ListExclusions)
+		m.Put(prefix+"/exclusions", v.SetExclusions)
+		m.Get(prefix+"/fsck", v.Check)
+		m.Post(prefix+"/fsck", v.Repair)
+		m.Get(prefix+"/hosts", v.ListHosts)
+		m.Get(prefix+"/events", v.ListEvents)
+		m.Get(prefix+"/events/stream", v.StreamEvents)
+	}
+}
+
+// reply answers a successful request with message and data.
+func (v *V2) reply(w http.ResponseWriter, message string, data interface{}) {
+	resp := common.Response{Status: "data", Message: message, Data: data}
+	packed, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(packed)
+}
+
+// fail answers with err, see replyError, putting data in the response.
+func (v *V2) fail(w http.ResponseWriter, err error, data interface{}) {
+	reply, message := describeError(err)
+	resp := common.Response{Status: reply.Status, Message: message, Code: reply.Code, Data: data}
+	packed, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(reply.HTTPStatus)
+	w.Write(packed)
+}
+
+// store is API.store answering with a common.Response.
+func (v *V2) store(c web.C, w http.ResponseWriter, r *http.Request) (actions.PortStore, bool) {
+	return v.open(c, w, r, false)
+}
+
+// allocationStore is API.allocationStore answering with a common.Response.
+func (v *V2) allocationStore(c web.C, w http.ResponseWriter, r *http.Request) (actions.PortStore, bool) {
+	return v.open(c, w, r, true)
+}
+
+// open is store, or allocationStore when create is set.
+func (v *V2) open(c web.C, w http.ResponseWriter, r *http.Request, create bool) (actions.PortStore, bool) {
+	store, err := v.api.pool(c, r, create)
+	if err != nil {
+		v.fail(w, err, nil)
+		return nil, false
+	}
+	return store, true
+}
+
+// OpenAPI serves the OpenAPI document describing /api/v2.
+func (v *V2) OpenAPI(c web.C, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, openAPISpec)
+}
+
+// ListPools returns the names of the pools served by this instance.
+func (v *V2) ListPools(c web.C, w http.ResponseWriter, r *http.Request) {
+	v.reply(w, "success", v.api.Pools.Names())
+}
+
+// ListHosts returns the hosts the pool has assigned ports on.
+func (v *V2) ListHosts(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	hosts, err := store.Hosts()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	if hosts == nil {
+		hosts = []string{}
+	}
+	v.reply(w, "success", hosts)
+}
+
+// ListServices returns every service whose labels match the selector
+// parameter, every service when there is none.
+func (v *V2) ListServices(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	sel, err := selector(r)
+	if err != nil {
+		v.fail(w, invalidf("%s", err), nil)
+		return
+	}
+	selected, err := actions.SelectServices(store, sel)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	services := make([]common.Service, len(selected))
+	for i, assignment := range selected {
+		services[i] = serviceResource(assignment)
+	}
+	v.reply(w, fmt.Sprintf("%d services found", len(services)), services)
+}
+
+// AllocateServices allocates ports for every service in the body, a
+// common.BulkPortRequest, or for none of them, see API.AllocateServices. Data
+// maps each id to its common.Service.
+func (v *V2) AllocateServices(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.allocationStore(c, w, r)
+	if !ok {
+		return
+	}
+	reqs, err := bulkRequests(r)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	assignments, err := store.AllocateAll(reqs)
+	if bulk, ok := err.(*actions.BulkError); ok {
+		v.fail(w, err, bulk.ID)
+		return
+	}
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	services := make(map[string]common.Service, len(assignments))
+	for id, assignment := range assignments {
+		services[id] = serviceResource(assignment)
+	}
+	v.reply(w, fmt.Sprintf("%d services allocated", len(services)), services)
+}
+
+// ReleaseSelected releases every service whose labels match the selector
+// parameter, which is required, and returns their ids.
+func (v *V2) ReleaseSelected(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	sel, err := selector(r)
+	if err == nil && len(sel) == 0 {
+		err = fmt.Errorf("Pass a selector naming the services to release")
+	}
+	if err != nil {
+		v.fail(w, invalidf("%s", err), nil)
+		return
+	}
+	released, err := actions.ReleaseServices(store, sel)
+	if err != nil {
+		v.fail(w, err, released)
+		return
+	}
+	if released == nil {
+		released = []string{}
+	}
+	v.reply(w, fmt.Sprintf("%d services released", len(released)), released)
+}
+
+// ReleaseServiceList releases the services listed in the body, a
+// common.BulkReleaseRequest, at once. Data maps each id to whether it held
+// any ports.
+func (v *V2) ReleaseServiceList(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	ids, err := releaseIDs(r)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	released, err := store.RemoveServices(ids)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "success", released)
+}
+
+// Allocate assigns ports to the service in the URL, or returns those it
+// holds. The optional body, a common.ServiceRequest, holds the options
+// which API.GetOpenPort takes as parameters.
+func (v *V2) Allocate(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.allocationStore(c, w, r)
+	if !ok {
+		return
+	}
+	var req common.ServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		v.fail(w, invalidf("Invalid request body. Send a JSON object such as {\"lease\": \"5m\"}"), nil)
+		return
+	}
+	req.ID = c.URLParams["id"]
+	opts, err := serviceOptions(req)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	wait, err := waitDuration(req.Wait)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	assignment, err := actions.AllocateWait(store, req.ID, opts, wait, r.Context().Done())
+	if conflict, ok := err.(*actions.PortConflictError); ok {
+		v.fail(w, err, conflict.Owner)
+		return
+	}
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "open port found", serviceResource(assignment))
+}
+
+// Lookup returns the service in the URL, or a 404 if it holds no ports.
+func (v *V2) Lookup(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	id := c.URLParams["id"]
+	assignment, err := store.GetAssignment(id)
+	if err == nil && assignment.Empty() {
+		err = notFoundf("No port is assigned to '%s'", id)
+	}
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "Port found", serviceResource(assignment))
+}
+
+// Release releases the service in the URL, or answers with a 404 if it held
+// no ports.
+func (v *V2) Release(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	id := c.URLParams["id"]
+	released, err := store.RemoveServices([]string{id})
+	if err == nil && !released[id] {
+		err = notFoundf("No port is assigned to '%s'", id)
+	}
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "Service released", nil)
+}
+
+// Renew extends the lease on the service in the URL by the lease in the
+// optional body, a common.RenewRequest, or else by the one it was last
+// given.
+func (v *V2) Renew(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	var req common.RenewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		v.fail(w, invalidf("Invalid request body. Send a JSON object such as {\"lease\": \"5m\"}"), nil)
+		return
+	}
+	var lease time.Duration
+	if len(req.Lease) > 0 {
+		var err error
+		lease, err = leaseDuration(req.Lease)
+		if err != nil {
+			v.fail(w, invalidf("Invalid lease passed. Use a duration such as 90s or 5m"), nil)
+			return
+		}
+	}
+	assignment, err := store.RenewLease(c.URLParams["id"], lease)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "Lease renewed", serviceResource(assignment))
+}
+
+// ListPorts lists and counts the ports in the state given by the state
+// parameter: open, assigned or cooling, which are the released ports waiting
+// out the cooldown.
+func (v *V2) ListPorts(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	var list func() ([]string, error)
+	switch state := r.URL.Query().Get("state"); state {
+	case "open":
+		list = store.GetOpenPortList
+	case "assigned":
+		list = store.GetReservedPortList
+	case "cooling":
+		list = store.GetCoolingPortList
+	default:
+		v.fail(w, invalidf("Invalid state passed. Use open, assigned or cooling"), nil)
+		return
+	}
+	listed, err := list()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	ports := portNumbers(listed)
+	v.reply(w, "success", common.PortList{Count: len(ports), Ports: ports})
+}
+
+// Whois returns the id holding the port in the URL, or a 404 if none does.
+func (v *V2) Whois(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	port, err := strconv.Atoi(c.URLParams["port"])
+	if err != nil {
+		v.fail(w, invalidf("Invalid port integer passed. Use a valid port number"), nil)
+		return
+	}
+	id, err := store.GetInstanceFromPort(port)
+	if err == nil && len(id) == 0 {
+		err = notFoundf("Port %d is not assigned", port)
+	}
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "Mapping found", common.PortOwner{Port: port, ID: id})
+}
+
+// ListQuarantine lists the quarantined ports, see API.GetQuarantine.
+func (v *V2) ListQuarantine(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	quarantined, err := store.GetQuarantine()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	ports := make([]common.QuarantinedPort, len(quarantined))
+	for i, q := range quarantined {
+		ports[i] = common.QuarantinedPort{Port: q.Port, Since: q.Since}
+	}
+	v.reply(w, "success", ports)
+}
+
+// ReleaseQuarantine puts the quarantined port in the URL back into the pool.
+func (v *V2) ReleaseQuarantine(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	port, err := strconv.Atoi(c.URLParams["port"])
+	if err != nil {
+		v.fail(w, invalidf("Invalid port integer passed. Use a valid port number"), nil)
+		return
+	}
+	if err := store.Unquarantine(port); err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "Port released from quarantine", nil)
+}
+
+// ListMappings pages through the ids holding ports, taking the parameters
+// of API.GetMappings.
+func (v *V2) ListMappings(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	page, err := listMappings(store, r)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	if page.Mappings == nil {
+		page.Mappings = map[string]int{}
+	}
+	labels := make(map[string]map[string]string, len(page.Labels))
+	for id, l := range page.Labels {
+		labels[id] = l
+	}
+	v.reply(w, "success", common.MappingPage{Mappings: page.Mappings, Labels: labels, Cursor: page.Cursor})
+}
+
+// Check reports the inconsistencies in the data model of the pool, see
+// API.CheckConsistency.
+func (v *V2) Check(c web.C, w http.ResponseWriter, r *http.Request) {
+	v.check(c, w, r, false)
+}
+
+// Repair repairs the inconsistencies in the data model of the pool and
+// reports what it repaired.
+func (v *V2) Repair(c web.C, w http.ResponseWriter, r *http.Request) {
+	v.check(c, w, r, true)
+}
+
+func (v *V2) check(c web.C, w http.ResponseWriter, r *http.Request, repair bool) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	found, err := store.Check(repair)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	inconsistencies := make([]common.Inconsistency, len(found))
+	for i, f := range found {
+		inconsistencies[i] = common.Inconsistency{Kind: f.Kind, ID: f.ID, Port: f.Port, Detail: f.Detail, Repair: f.Repair}
+	}
+	var message string
+	switch {
+	case len(found) == 0:
+		message = "No inconsistencies found"
+	case repair:
+		message = fmt.Sprintf("Repaired %d inconsistencies", len(found))
+	default:
+		message = fmt.Sprintf("Found %d inconsistencies, POST to repair them", len(found))
+	}
+	v.reply(w, message, inconsistencies)
+}
+
+// GetRange returns the range of ports the pool manages.
+func (v *V2) GetRange(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	start, end, err := store.GetRange()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "success", common.PortRange{Start: start, End: end})
+}
+
+// SetRange changes the range of ports the pool manages to the one in the
+// body, a common.PortRange whose missing bounds are left as they are, so a
+// request without a body puts back ports which went missing from the pool.
+func (v *V2) SetRange(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	var req common.PortRange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		v.fail(w, invalidf("Invalid request body. Send a JSON object such as {\"start\": 30000, \"end\": 31000}"), nil)
+		return
+	}
+	start, end, err := store.GetRange()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	if req.Start != 0 {
+		start = req.Start
+	}
+	if req.End != 0 {
+		end = req.End
+	}
+	excluded, err := store.GetExclusions()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reconcile(w, store, start, end, excluded)
+}
+
+// ListExclusions lists the ports and port ranges within the range of the
+// pool which are never handed out.
+func (v *V2) ListExclusions(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	excluded, err := store.GetExclusions()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reply(w, "success", common.ExclusionList{Excluded: exclusionStrings(excluded)})
+}
+
+// SetExclusions replaces the exclusions of the pool with those in the body,
+// a common.ExclusionList, keeping its range.
+func (v *V2) SetExclusions(c web.C, w http.ResponseWriter, r *http.Request) {
+	store, ok := v.store(c, w, r)
+	if !ok {
+		return
+	}
+	var req common.ExclusionList
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Excluded == nil {
+		v.fail(w, invalidf("Invalid request body. Send a JSON object such as {\"excluded\": [\"32400\", \"31000-31010\"]}"), nil)
+		return
+	}
+	excluded, err := actions.ParseExclusions(strings.Join(req.Excluded, ","))
+	if err != nil {
+		v.fail(w, invalidf("%s", err), nil)
+		return
+	}
+	start, end, err := store.GetRange()
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	v.reconcile(w, store, start, end, excluded)
+}
+
+// reconcile reconciles store with the range and exclusions given and replies
+// with what changed.
+func (v *V2) reconcile(w http.ResponseWriter, store actions.PortStore, start, end int, excluded []actions.Exclusion) {
+	report, err := store.Reconcile(start, end, excluded)
+	if err != nil {
+		v.fail(w, err, nil)
+		return
+	}
+	message := fmt.Sprintf("Range is now %d to %d", report.Start, report.End)
+	if len(report.Stranded) > 0 {
+		message += fmt.Sprintf(", %d assigned ports are outside of it", len(report.Stranded))
+	}
+	stranded := report.Stranded
+	if stranded == nil {
+		stranded = map[string]string{}
+	}
+	v.reply(w, message, common.ReconcileReport{
+		PortRange: common.PortRange{Start: report.Start, End: report.End},
+		Excluded:  exclusionStrings(report.Excluded),
+		Added:     portNumbers(report.Added),
+		Removed:   portNumbers(report.Removed),
+		Stranded:  stranded,
+	})
+}
+
+// exclusionStrings returns excluded as /api/v2 shows it, never nil.
+func exclusionStrings(excluded []actions.Exclusion) []string {
+	specs := make([]string, len(excluded))
+	for i, e := range excluded {
+		specs[i] = e.String()
+	}
+	return specs
+}
+
+// portNumbers returns the ports listed in numeric order, skipping anything
+// which isn't a number. It never returns nil.
+func portNumbers(listed []string) []int {
+	ports := make([]int, 0, len(listed))
+	for _, port := range listed {
+		if p, err := strconv.Atoi(port); err == nil {
+			ports = append(ports, p)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// serviceResource returns a as /api/v2 shows it.
+func serviceResource(a actions.Assignment) common.Service {
+	s := common.Service{ID: a.ID, Expires: leaseExpiry(a), Labels: a.Labels}
+	switch {
+	case len(a.Named) > 0:
+		s.Named = a.Named
+	case a.Block > 1:
+		s.Port, s.Ports = a.Port, a.Ports()
+	default:
+		s.Port = a.Port
+	}
+	return s
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -12,8 +13,6 @@ import (
 
 	"github.com/codegangsta/cli"
 
-	"github.com/docker/libkv"
-	"github.com/docker/libkv/store"
 	"github.com/docker/libkv/store/consul"
 	"github.com/therealbill/airbrake-go"
 	"github.com/therealbill/port-authority/actions"
@@ -47,19 +46,12 @@ func serve(c *cli.Context) {
 	client := c.String("consuladdress")
 	name := c.String("name")
 
-	// Initialize a new store with consul
-	kv, err := libkv.NewStore(
-		store.CONSUL, // or "consul"
-		[]string{client},
-		&store.Config{
-			ConnectionTimeout: 10 * time.Second,
-		},
-	)
+	kv, err := newConfigStore(client)
 	if err != nil {
 		log.Fatal("Cannot create store consul")
 	}
 
-	prefix := "app/port-authority/config"
+	prefix := configPrefix
 	key_apiport := fmt.Sprintf("%s/api_port", prefix)
 	//key_uiport := fmt.Sprintf("%s/ui_port", prefix)
 	key_rpcport := fmt.Sprintf("%s/rpc_port", prefix)
@@ -76,17 +68,20 @@ func serve(c *cli.Context) {
 			if strings.Contains(err.Error(), "not found") {
 				log.Printf("key not found in config store: %s/api_port", prefix)
 			} else {
-				log.Print("Error on connection: %v", err)
+				log.Printf("Error on connection: %v", err)
 			}
 		} else {
 			port, err := strconv.Atoi(string(tmp.Value))
-			if err != nil {
+			if err == nil {
 				config.Port = int(port)
 			}
 		}
 	}
 	var port_start = 30000
 	var port_end = 40000
+	var excluded []actions.Exclusion
+	var strategy = actions.StrategyRandom
+	var pools []poolConfig
 	if havestore {
 		//config.RPCPort, err = kv.Get(key_rpcport)
 		log.Printf("Connected to config store")
@@ -103,22 +98,17 @@ func serve(c *cli.Context) {
 			if strings.Contains(err.Error(), "not found") {
 				log.Printf("key not found in config store: %s/rpc_port", prefix)
 			} else {
-				log.Print("Error on connection: %v", err)
+				log.Printf("Error on connection: %v", err)
 			}
 		} else {
-			fmt.Printf("rpcport: %s", string(tmp.Value))
 			port, err := strconv.Atoi(string(tmp.Value))
-			log.Printf("%s %v", port, err)
-			if err != nil {
+			if err == nil {
 				config.RPCPort = int(port)
+			} else {
+				log.Printf("Invalid rpc_port '%s' in config store: %v", tmp.Value, err)
 			}
 		}
-		var my_key string
-		if name != "" {
-			my_key = fmt.Sprintf("%s/%s", prefix, name)
-		} else {
-			my_key = prefix
-		}
+		my_key := instanceKey(prefix, name)
 
 		key_portstart := fmt.Sprintf("%s/ports_begin", my_key)
 		tmp, err = kv.Get(key_portstart)
@@ -126,7 +116,7 @@ func serve(c *cli.Context) {
 			if strings.Contains(err.Error(), "not found") {
 				log.Printf("key not found in config store: %s", key_portstart)
 			} else {
-				log.Print("Error on connection: %v", err)
+				log.Printf("Error on connection: %v", err)
 			}
 		} else {
 			port, err := strconv.Atoi(string(tmp.Value))
@@ -141,7 +131,7 @@ func serve(c *cli.Context) {
 			if strings.Contains(err.Error(), "not found") {
 				log.Printf("key not found in config store: %s", key_portend)
 			} else {
-				log.Print("Error on connection: %v", err)
+				log.Printf("Error on connection: %v", err)
 			}
 		} else {
 			port, err := strconv.Atoi(string(tmp.Value))
@@ -149,19 +139,81 @@ func serve(c *cli.Context) {
 				port_end = int(port)
 			}
 		}
+
+		excluded = readExclusions(kv, fmt.Sprintf("%s/excluded_ports", my_key))
+		strategy = readStrategy(kv, fmt.Sprintf("%s/strategy", my_key))
+		pools = readPoolConfigs(kv, my_key)
 	}
-	if actions.InitializeRedisClient("127.0.0.1:6379", "") != nil {
-		log.Fatal("Can not connect to Redis!")
+	events := actions.NewEventLog()
+	newSink := func(pool poolConfig) actions.EventSink { return events }
+	var newStore func(pool poolConfig) actions.PortStore
+	switch c.String("backend") {
+	case "memory":
+		log.Print("Using the in-memory backend, assignments will not survive a restart")
+		newStore = func(pool poolConfig) actions.PortStore {
+			ms := actions.NewMemoryStore()
+			ms.SetCooldown(c.Duration("cooldown"))
+			ms.SetStrategy(pool.Strategy)
+			return ms
+		}
+	case "redis":
+		rs, err := actions.NewRedisStore(c.String("redis"), "")
+		if err != nil {
+			log.Fatal("Can not connect to Redis!")
+		}
+		rs.SetCooldown(c.Duration("cooldown"))
+		newSink = func(pool poolConfig) actions.EventSink {
+			re := actions.NewRedisEvents(rs.Pool(pool.Name))
+			go re.Listen(events, nil)
+			return re
+		}
+		newStore = func(pool poolConfig) actions.PortStore {
+			ps := rs.Pool(pool.Name)
+			ps.SetStrategy(pool.Strategy)
+			return ps
+		}
+	default:
+		log.Fatalf("Unknown backend '%s'", c.String("backend"))
 	}
-	log.Printf("Initializing with ports from %d to %d", port_start, port_end)
-	err = actions.InitializePorts(port_start, port_end)
+	prober, err := actions.NewProber(c.String("probe"), c.String("probe-address"))
 	if err != nil {
-		if strings.Contains(err.Error(), "already been init") {
-			log.Print(err.Error())
-		} else {
-			log.Printf("Error on init: %v", err)
+		log.Fatal(err)
+	}
+	if prober != nil {
+		log.Printf("Probing new ports with the %s probe before handing them out", c.String("probe"))
+		backend := newStore
+		newStore = func(pool poolConfig) actions.PortStore {
+			return actions.NewProbingStore(backend(pool), prober)
+		}
+	}
+	unrecorded := newStore
+	newStore = func(pool poolConfig) actions.PortStore {
+		return actions.NewEventStore(unrecorded(pool), pool.Name, newSink(pool))
+	}
+	pools = append([]poolConfig{{Name: actions.DefaultPool, Start: port_start, End: port_end, Excluded: excluded, Strategy: strategy}}, pools...)
+	stores := make(actions.Pools)
+	for _, pool := range pools {
+		ps := newStore(pool)
+		log.Printf("Initializing pool '%s' with ports from %d to %d, picked with the %s strategy", pool.Name, pool.Start, pool.End, pool.Strategy)
+		err = ps.InitializePorts(pool.Start, pool.End, pool.Excluded)
+		if err != nil {
+			if err == actions.ErrAlreadyInitialized {
+				log.Printf("Pool '%s' is already initialized, reconciling it with the configured range", pool.Name)
+				report, err := ps.Reconcile(pool.Start, pool.End, pool.Excluded)
+				if err != nil {
+					log.Printf("Error on reconcile: %v", err)
+				} else {
+					logReconcileReport(pool.Name, report)
+				}
+			} else {
+				log.Printf("Error on init: %v", err)
+			}
 		}
+		go actions.StartReaper(ps, c.Duration("reap-interval"), nil)
+		stores[pool.Name] = ps
 	}
+	api := handlers.NewAPI(stores)
+	api.Events = events
 
 	if len(config.BindAddress) != 0 {
 		flag.Set("bind", config.BindAddress)
@@ -200,14 +252,70 @@ func serve(c *cli.Context) {
 	log.Printf("Config: %s", config_json)
 	// HTML Interface URLS
 	// API URLS
-	goji.Put("/api/service/:id", handlers.APIGetOpenPort)
-	goji.Get("/api/service/:id", handlers.APIGetPortFromInstance)
-	goji.Delete("/api/service/:id", handlers.APIRemoveService)
-	goji.Get("/api/port/:port", handlers.APIGetInstanceFromPort)
-	goji.Get("/api/ports/inventory/count", handlers.APIGetPortCapacity)
-	goji.Get("/api/ports/inventory/list", handlers.APIGetAvailableInventory)
-	goji.Get("/api/ports/assigned/count", handlers.APIGetAssignedCount)
-	goji.Get("/api/ports/assigned/list", handlers.APIGetAssignedList)
+	goji.Put("/api/service/:id", api.GetOpenPort)
+	goji.Get("/api/service/:id", api.GetPortFromInstance)
+	goji.Delete("/api/service/:id", api.RemoveService)
+	goji.Put("/api/service/:id/renew", api.RenewLease)
+	goji.Get("/api/port/:port", api.GetInstanceFromPort)
+	goji.Get("/api/ports/inventory/count", api.GetPortCapacity)
+	goji.Get("/api/ports/inventory/list", api.GetAvailableInventory)
+	goji.Get("/api/ports/assigned/count", api.GetAssignedCount)
+	goji.Get("/api/ports/assigned/list", api.GetAssignedList)
+	goji.Get("/api/ports/assigned/map", api.GetMappings)
+	goji.Get("/api/ports/cooling/count", api.GetCoolingCount)
+	goji.Get("/api/ports/cooling/list", api.GetCoolingList)
+	goji.Get("/api/ports/excluded", api.GetExclusions)
+	goji.Get("/api/ports/quarantine", api.GetQuarantine)
+	goji.Delete("/api/ports/quarantine/:port", api.ReleaseQuarantine)
+	goji.Get("/api/admin/fsck", api.CheckConsistency)
+	goji.Post("/api/admin/fsck", api.RepairConsistency)
+	goji.Get("/api/admin/range", api.GetRange)
+	goji.Post("/api/admin/range", api.ReconcileRange)
+	goji.Get("/api/services", api.FindServices)
+	goji.Delete("/api/services", api.ReleaseServices)
+	goji.Post("/api/services", api.AllocateServices)
+	goji.Post("/api/services/release", api.ReleaseServiceList)
+	goji.Get("/api/hosts", api.ListHosts)
+	goji.Get("/api/pools", api.ListPools)
+	goji.Get("/api/pools/:pool/hosts", api.ListHosts)
+	goji.Get("/api/pools/:pool/services", api.FindServices)
+	goji.Delete("/api/pools/:pool/services", api.ReleaseServices)
+	goji.Post("/api/pools/:pool/services", api.AllocateServices)
+	goji.Post("/api/pools/:pool/services/release", api.ReleaseServiceList)
+	goji.Put("/api/pools/:pool/service/:id", api.GetOpenPort)
+	goji.Get("/api/pools/:pool/service/:id", api.GetPortFromInstance)
+	goji.Delete("/api/pools/:pool/service/:id", api.RemoveService)
+	goji.Put("/api/pools/:pool/service/:id/renew", api.RenewLease)
+	goji.Get("/api/pools/:pool/port/:port", api.GetInstanceFromPort)
+	goji.Get("/api/pools/:pool/ports/inventory/count", api.GetPortCapacity)
+	goji.Get("/api/pools/:pool/ports/inventory/list", api.GetAvailableInventory)
+	goji.Get("/api/pools/:pool/ports/assigned/count", api.GetAssignedCount)
+	goji.Get("/api/pools/:pool/ports/assigned/list", api.GetAssignedList)
+	goji.Get("/api/pools/:pool/ports/assigned/map", api.GetMappings)
+	goji.Get("/api/pools/:pool/ports/cooling/count", api.GetCoolingCount)
+	goji.Get("/api/pools/:pool/ports/cooling/list", api.GetCoolingList)
+	goji.Get("/api/pools/:pool/ports/excluded", api.GetExclusions)
+	goji.Get("/api/pools/:pool/ports/quarantine", api.GetQuarantine)
+	goji.Delete("/api/pools/:pool/ports/quarantine/:port", api.ReleaseQuarantine)
+	goji.Get("/api/pools/:pool/admin/fsck", api.CheckConsistency)
+	goji.Post("/api/pools/:pool/admin/fsck", api.RepairConsistency)
+	goji.Get("/api/pools/:pool/admin/range", api.GetRange)
+	goji.Post("/api/pools/:pool/admin/range", api.ReconcileRange)
+	v2 := handlers.NewV2(api)
+	v2.Route(goji.DefaultMux)
+
+	rpcHost := config.BindAddress
+	if host, _, err := net.SplitHostPort(config.BindAddress); err == nil {
+		rpcHost = host
+	}
+	rpcAddr := net.JoinHostPort(rpcHost, strconv.Itoa(config.RPCPort))
+	listener, err := net.Listen("tcp", rpcAddr)
+	if err != nil {
+		log.Fatalf("Can not listen for RPC on %s: %v", rpcAddr, err)
+	}
+	go func() {
+		log.Fatal(handlers.ServeRPC(api, listener))
+	}()
 	goji.Serve()
 }
 
@@ -237,7 +345,59 @@ func main() {
 			EnvVar: "PA_ENV",
 			Value:  "development",
 		},
+		cli.StringFlag{
+			Name:   "backend,b",
+			Usage:  "Allocation backend to use: redis or memory",
+			EnvVar: "PA_BACKEND",
+			Value:  "redis",
+		},
+		cli.StringFlag{
+			Name:   "redis",
+			Usage:  "Address of the Redis server",
+			EnvVar: "PA_REDIS",
+			Value:  "127.0.0.1:6379",
+		},
+		cli.StringFlag{
+			Name:   "probe",
+			Usage:  "Check new ports are unused before handing them out: none, bind (on this host) or dial (probe-address)",
+			EnvVar: "PA_PROBE",
+			Value:  "none",
+		},
+		cli.StringFlag{
+			Name:   "probe-address",
+			Usage:  "Address to bind on for the bind probe, or host to connect to for the dial probe",
+			EnvVar: "PA_PROBE_ADDRESS",
+		},
+		cli.DurationFlag{
+			Name:   "cooldown",
+			Usage:  "How long released ports wait before they are handed out again",
+			EnvVar: "PA_COOLDOWN",
+		},
+		cli.DurationFlag{
+			Name:   "reap-interval",
+			Usage:  "How often to release ports whose lease has expired",
+			EnvVar: "PA_REAP_INTERVAL",
+			Value:  10 * time.Second,
+		},
 	}
 	app.Action = serve
+	app.Commands = []cli.Command{
+		{
+			Name:   "fsck",
+			Usage:  "Check the Redis data model for inconsistencies and optionally repair them",
+			Action: fsck,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "repair",
+					Usage: "Repair what is found instead of only reporting it",
+				},
+				cli.StringFlag{
+					Name:  "pool,p",
+					Usage: "Only check this pool",
+				},
+			},
+		},
+	}
+	app.Commands = append(app.Commands, clientCommands()...)
 	app.Run(os.Args)
 }
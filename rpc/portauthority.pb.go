@@ -0,0 +1,2387 @@
+// The PortAuthority gRPC service, served on the RPC port alongside the HTTP
+// API. It offers the operations of /api/v2; see the RPC section of the
+// README.
+//
+// Every request carries a Target picking the pool, the default one when
+// empty, and the host within it. A failed call returns the gRPC code
+// matching the HTTP status /api/v2 answers with, and an ErrorInfo detail
+// whose reason is the code of its JSON body, such as "conflict", with the
+// holder of the port in its "owner" metadata for a conflict and the service
+// which failed in "id" for a bulk allocation.
+//
+// Regenerate the Go code with go generate ./rpc after changing this file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: portauthority.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// PortState is the state of the ports Ports lists.
+type PortState int32
+
+const (
+	PortState_OPEN     PortState = 0
+	PortState_ASSIGNED PortState = 1
+	// COOLING ports were released lately and aren't handed out again yet.
+	PortState_COOLING PortState = 2
+)
+
+// Enum value maps for PortState.
+var (
+	PortState_name = map[int32]string{
+		0: "OPEN",
+		1: "ASSIGNED",
+		2: "COOLING",
+	}
+	PortState_value = map[string]int32{
+		"OPEN":     0,
+		"ASSIGNED": 1,
+		"COOLING":  2,
+	}
+)
+
+func (x PortState) Enum() *PortState {
+	p := new(PortState)
+	*p = x
+	return p
+}
+
+func (x PortState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PortState) Descriptor() protoreflect.EnumDescriptor {
+	return file_portauthority_proto_enumTypes[0].Descriptor()
+}
+
+func (PortState) Type() protoreflect.EnumType {
+	return &file_portauthority_proto_enumTypes[0]
+}
+
+func (x PortState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PortState.Descriptor instead.
+func (PortState) EnumDescriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{0}
+}
+
+// Target picks the pool a call works on, the default one when pool is
+// empty, and the host within it, if any.
+type Target struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pool          string                 `protobuf:"bytes,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Host          string                 `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Target) Reset() {
+	*x = Target{}
+	mi := &file_portauthority_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Target) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Target) ProtoMessage() {}
+
+func (x *Target) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Target.ProtoReflect.Descriptor instead.
+func (*Target) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Target) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *Target) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+// Labels are the labels of a service. Being a message, a request can tell
+// leaving them alone, by not setting it, from clearing them.
+type Labels struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Labels        map[string]string      `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Labels) Reset() {
+	*x = Labels{}
+	mi := &file_portauthority_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Labels) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Labels) ProtoMessage() {}
+
+func (x *Labels) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Labels.ProtoReflect.Descriptor instead.
+func (*Labels) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Labels) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// ServiceRequest asks for the ports of a service, as the body of an
+// /api/v2 allocation does.
+type ServiceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// lease is how long the service holds its ports, for good when unset.
+	Lease *durationpb.Duration `protobuf:"bytes,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	// port asks for a given port, failing when it is taken unless prefer is
+	// set.
+	Port   int32 `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Prefer bool  `protobuf:"varint,4,opt,name=prefer,proto3" json:"prefer,omitempty"`
+	// block asks for that many consecutive ports.
+	Block int32 `protobuf:"varint,5,opt,name=block,proto3" json:"block,omitempty"`
+	// names asks for a port for each name.
+	Names []string `protobuf:"bytes,6,rep,name=names,proto3" json:"names,omitempty"`
+	// labels, when set, replace those stored with the service.
+	Labels        *Labels `protobuf:"bytes,7,opt,name=labels,proto3" json:"labels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceRequest) Reset() {
+	*x = ServiceRequest{}
+	mi := &file_portauthority_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceRequest) ProtoMessage() {}
+
+func (x *ServiceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceRequest.ProtoReflect.Descriptor instead.
+func (*ServiceRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ServiceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ServiceRequest) GetLease() *durationpb.Duration {
+	if x != nil {
+		return x.Lease
+	}
+	return nil
+}
+
+func (x *ServiceRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ServiceRequest) GetPrefer() bool {
+	if x != nil {
+		return x.Prefer
+	}
+	return false
+}
+
+func (x *ServiceRequest) GetBlock() int32 {
+	if x != nil {
+		return x.Block
+	}
+	return 0
+}
+
+func (x *ServiceRequest) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+func (x *ServiceRequest) GetLabels() *Labels {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// Service is a service and the ports it holds: port, ports for a block, or
+// named for named ports.
+type Service struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Port  int32                  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Ports []int32                `protobuf:"varint,3,rep,packed,name=ports,proto3" json:"ports,omitempty"`
+	Named map[string]int32       `protobuf:"bytes,4,rep,name=named,proto3" json:"named,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// expires is when its lease runs out, unset when it has none.
+	Expires       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires,proto3" json:"expires,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Service) Reset() {
+	*x = Service{}
+	mi := &file_portauthority_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Service) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Service) ProtoMessage() {}
+
+func (x *Service) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Service.ProtoReflect.Descriptor instead.
+func (*Service) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Service) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Service) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Service) GetPorts() []int32 {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *Service) GetNamed() map[string]int32 {
+	if x != nil {
+		return x.Named
+	}
+	return nil
+}
+
+func (x *Service) GetExpires() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Expires
+	}
+	return nil
+}
+
+func (x *Service) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type AllocateRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Target  *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Service *ServiceRequest        `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	// wait is how long to wait for a port when the pool is exhausted, up to
+	// five minutes.
+	Wait          *durationpb.Duration `protobuf:"bytes,3,opt,name=wait,proto3" json:"wait,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateRequest) Reset() {
+	*x = AllocateRequest{}
+	mi := &file_portauthority_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateRequest) ProtoMessage() {}
+
+func (x *AllocateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateRequest.ProtoReflect.Descriptor instead.
+func (*AllocateRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AllocateRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *AllocateRequest) GetService() *ServiceRequest {
+	if x != nil {
+		return x.Service
+	}
+	return nil
+}
+
+func (x *AllocateRequest) GetWait() *durationpb.Duration {
+	if x != nil {
+		return x.Wait
+	}
+	return nil
+}
+
+type AllocateServicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Services      []*ServiceRequest      `protobuf:"bytes,2,rep,name=services,proto3" json:"services,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateServicesRequest) Reset() {
+	*x = AllocateServicesRequest{}
+	mi := &file_portauthority_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateServicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateServicesRequest) ProtoMessage() {}
+
+func (x *AllocateServicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateServicesRequest.ProtoReflect.Descriptor instead.
+func (*AllocateServicesRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AllocateServicesRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *AllocateServicesRequest) GetServices() []*ServiceRequest {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+// ServiceMap maps the id of each service allocated to its ports.
+type ServiceMap struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Services      map[string]*Service    `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceMap) Reset() {
+	*x = ServiceMap{}
+	mi := &file_portauthority_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceMap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceMap) ProtoMessage() {}
+
+func (x *ServiceMap) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceMap.ProtoReflect.Descriptor instead.
+func (*ServiceMap) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ServiceMap) GetServices() map[string]*Service {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+// ServiceRef names the service a call works on.
+type ServiceRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceRef) Reset() {
+	*x = ServiceRef{}
+	mi := &file_portauthority_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceRef) ProtoMessage() {}
+
+func (x *ServiceRef) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceRef.ProtoReflect.Descriptor instead.
+func (*ServiceRef) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ServiceRef) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *ServiceRef) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ServiceList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Services      []*Service             `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceList) Reset() {
+	*x = ServiceList{}
+	mi := &file_portauthority_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceList) ProtoMessage() {}
+
+func (x *ServiceList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceList.ProtoReflect.Descriptor instead.
+func (*ServiceList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ServiceList) GetServices() []*Service {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+// SelectRequest selects services by their labels with a selector such as
+// "team=cars,env!=prod".
+type SelectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Selector      string                 `protobuf:"bytes,2,opt,name=selector,proto3" json:"selector,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SelectRequest) Reset() {
+	*x = SelectRequest{}
+	mi := &file_portauthority_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SelectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SelectRequest) ProtoMessage() {}
+
+func (x *SelectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SelectRequest.ProtoReflect.Descriptor instead.
+func (*SelectRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SelectRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *SelectRequest) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+type ReleaseServicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Ids           []string               `protobuf:"bytes,2,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseServicesRequest) Reset() {
+	*x = ReleaseServicesRequest{}
+	mi := &file_portauthority_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseServicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseServicesRequest) ProtoMessage() {}
+
+func (x *ReleaseServicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseServicesRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseServicesRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReleaseServicesRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *ReleaseServicesRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// ReleasedMap tells for each id released whether it held any ports.
+type ReleasedMap struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Released      map[string]bool        `protobuf:"bytes,1,rep,name=released,proto3" json:"released,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleasedMap) Reset() {
+	*x = ReleasedMap{}
+	mi := &file_portauthority_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleasedMap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleasedMap) ProtoMessage() {}
+
+func (x *ReleasedMap) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleasedMap.ProtoReflect.Descriptor instead.
+func (*ReleasedMap) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ReleasedMap) GetReleased() map[string]bool {
+	if x != nil {
+		return x.Released
+	}
+	return nil
+}
+
+type RenewRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Target *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Id     string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// lease is the length of the new lease, that of the last one when unset.
+	Lease         *durationpb.Duration `protobuf:"bytes,3,opt,name=lease,proto3" json:"lease,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewRequest) Reset() {
+	*x = RenewRequest{}
+	mi := &file_portauthority_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewRequest) ProtoMessage() {}
+
+func (x *RenewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewRequest.ProtoReflect.Descriptor instead.
+func (*RenewRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RenewRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *RenewRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RenewRequest) GetLease() *durationpb.Duration {
+	if x != nil {
+		return x.Lease
+	}
+	return nil
+}
+
+// PortRequest names the port a call works on.
+type PortRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Port          int32                  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortRequest) Reset() {
+	*x = PortRequest{}
+	mi := &file_portauthority_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortRequest) ProtoMessage() {}
+
+func (x *PortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortRequest.ProtoReflect.Descriptor instead.
+func (*PortRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PortRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *PortRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+type PortOwner struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          int32                  `protobuf:"varint,1,opt,name=port,proto3" json:"port,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortOwner) Reset() {
+	*x = PortOwner{}
+	mi := &file_portauthority_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortOwner) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortOwner) ProtoMessage() {}
+
+func (x *PortOwner) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortOwner.ProtoReflect.Descriptor instead.
+func (*PortOwner) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PortOwner) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *PortOwner) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type PortsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	State         PortState              `protobuf:"varint,2,opt,name=state,proto3,enum=portauthority.PortState" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortsRequest) Reset() {
+	*x = PortsRequest{}
+	mi := &file_portauthority_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortsRequest) ProtoMessage() {}
+
+func (x *PortsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortsRequest.ProtoReflect.Descriptor instead.
+func (*PortsRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PortsRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *PortsRequest) GetState() PortState {
+	if x != nil {
+		return x.State
+	}
+	return PortState_OPEN
+}
+
+type PortList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ports         []int32                `protobuf:"varint,1,rep,packed,name=ports,proto3" json:"ports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortList) Reset() {
+	*x = PortList{}
+	mi := &file_portauthority_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortList) ProtoMessage() {}
+
+func (x *PortList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortList.ProtoReflect.Descriptor instead.
+func (*PortList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PortList) GetPorts() []int32 {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+type PortCounts struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Open          int64                  `protobuf:"varint,1,opt,name=open,proto3" json:"open,omitempty"`
+	Assigned      int64                  `protobuf:"varint,2,opt,name=assigned,proto3" json:"assigned,omitempty"`
+	Cooling       int64                  `protobuf:"varint,3,opt,name=cooling,proto3" json:"cooling,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortCounts) Reset() {
+	*x = PortCounts{}
+	mi := &file_portauthority_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortCounts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortCounts) ProtoMessage() {}
+
+func (x *PortCounts) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortCounts.ProtoReflect.Descriptor instead.
+func (*PortCounts) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PortCounts) GetOpen() int64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *PortCounts) GetAssigned() int64 {
+	if x != nil {
+		return x.Assigned
+	}
+	return 0
+}
+
+func (x *PortCounts) GetCooling() int64 {
+	if x != nil {
+		return x.Cooling
+	}
+	return 0
+}
+
+// MappingsRequest asks for a page of the mapping of ids to ports, of the
+// ids matching the glob pattern match and whose labels match the
+// selector, if any.
+type MappingsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Target *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	// cursor is the one the last page returned, zero for the first page.
+	Cursor uint64 `protobuf:"varint,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// count is about how many ids a page holds, up to 1000, 100 when zero.
+	Count         int32  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Match         string `protobuf:"bytes,4,opt,name=match,proto3" json:"match,omitempty"`
+	Selector      string `protobuf:"bytes,5,opt,name=selector,proto3" json:"selector,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MappingsRequest) Reset() {
+	*x = MappingsRequest{}
+	mi := &file_portauthority_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MappingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MappingsRequest) ProtoMessage() {}
+
+func (x *MappingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MappingsRequest.ProtoReflect.Descriptor instead.
+func (*MappingsRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *MappingsRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *MappingsRequest) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *MappingsRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *MappingsRequest) GetMatch() string {
+	if x != nil {
+		return x.Match
+	}
+	return ""
+}
+
+func (x *MappingsRequest) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+type MappingPage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// mappings maps each id to its port, or the first port of its block.
+	// Named ports are listed under their ID/NAME.
+	Mappings map[string]int32 `protobuf:"bytes,1,rep,name=mappings,proto3" json:"mappings,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// labels holds the labels of the listed ids which have any.
+	Labels map[string]*Labels `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// cursor is passed to get the next page, zero once everything has been
+	// listed.
+	Cursor        uint64 `protobuf:"varint,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MappingPage) Reset() {
+	*x = MappingPage{}
+	mi := &file_portauthority_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MappingPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MappingPage) ProtoMessage() {}
+
+func (x *MappingPage) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MappingPage.ProtoReflect.Descriptor instead.
+func (*MappingPage) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *MappingPage) GetMappings() map[string]int32 {
+	if x != nil {
+		return x.Mappings
+	}
+	return nil
+}
+
+func (x *MappingPage) GetLabels() map[string]*Labels {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *MappingPage) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+// EventsRequest asks for the events after the one whose id is since. An
+// empty pool here means every pool.
+type EventsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Target *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Since  int64                  `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`
+	// wait is how long to wait for an event when there are none yet, up to
+	// five minutes.
+	Wait          *durationpb.Duration `protobuf:"bytes,3,opt,name=wait,proto3" json:"wait,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventsRequest) Reset() {
+	*x = EventsRequest{}
+	mi := &file_portauthority_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventsRequest) ProtoMessage() {}
+
+func (x *EventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventsRequest.ProtoReflect.Descriptor instead.
+func (*EventsRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *EventsRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *EventsRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *EventsRequest) GetWait() *durationpb.Duration {
+	if x != nil {
+		return x.Wait
+	}
+	return nil
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Stamp         *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=stamp,proto3" json:"stamp,omitempty"`
+	Data          map[string]string      `protobuf:"bytes,4,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_portauthority_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Event) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Event) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Event) GetStamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Stamp
+	}
+	return nil
+}
+
+func (x *Event) GetData() map[string]string {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type EventList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*Event               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventList) Reset() {
+	*x = EventList{}
+	mi := &file_portauthority_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventList) ProtoMessage() {}
+
+func (x *EventList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventList.ProtoReflect.Descriptor instead.
+func (*EventList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *EventList) GetEvents() []*Event {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type QuarantinedPort struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          int32                  `protobuf:"varint,1,opt,name=port,proto3" json:"port,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuarantinedPort) Reset() {
+	*x = QuarantinedPort{}
+	mi := &file_portauthority_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuarantinedPort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuarantinedPort) ProtoMessage() {}
+
+func (x *QuarantinedPort) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuarantinedPort.ProtoReflect.Descriptor instead.
+func (*QuarantinedPort) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *QuarantinedPort) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *QuarantinedPort) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+type QuarantineList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ports         []*QuarantinedPort     `protobuf:"bytes,1,rep,name=ports,proto3" json:"ports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuarantineList) Reset() {
+	*x = QuarantineList{}
+	mi := &file_portauthority_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuarantineList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuarantineList) ProtoMessage() {}
+
+func (x *QuarantineList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuarantineList.ProtoReflect.Descriptor instead.
+func (*QuarantineList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *QuarantineList) GetPorts() []*QuarantinedPort {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+// PortRange is the range of ports a pool manages, from start up to, but
+// not including, end.
+type PortRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int32                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortRange) Reset() {
+	*x = PortRange{}
+	mi := &file_portauthority_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortRange) ProtoMessage() {}
+
+func (x *PortRange) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortRange.ProtoReflect.Descriptor instead.
+func (*PortRange) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PortRange) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *PortRange) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+// SetRangeRequest changes the range of a pool. A bound left at zero is
+// kept as it is, so a request setting neither puts back ports which went
+// missing from the pool.
+type SetRangeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Range         *PortRange             `protobuf:"bytes,2,opt,name=range,proto3" json:"range,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRangeRequest) Reset() {
+	*x = SetRangeRequest{}
+	mi := &file_portauthority_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRangeRequest) ProtoMessage() {}
+
+func (x *SetRangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRangeRequest.ProtoReflect.Descriptor instead.
+func (*SetRangeRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SetRangeRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *SetRangeRequest) GetRange() *PortRange {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+// ExclusionList lists the ports within the range of a pool it never hands
+// out, each a PORT or an inclusive START-END range.
+type ExclusionList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Excluded      []string               `protobuf:"bytes,1,rep,name=excluded,proto3" json:"excluded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExclusionList) Reset() {
+	*x = ExclusionList{}
+	mi := &file_portauthority_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExclusionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExclusionList) ProtoMessage() {}
+
+func (x *ExclusionList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExclusionList.ProtoReflect.Descriptor instead.
+func (*ExclusionList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ExclusionList) GetExcluded() []string {
+	if x != nil {
+		return x.Excluded
+	}
+	return nil
+}
+
+type SetExclusionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *Target                `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Excluded      []string               `protobuf:"bytes,2,rep,name=excluded,proto3" json:"excluded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetExclusionsRequest) Reset() {
+	*x = SetExclusionsRequest{}
+	mi := &file_portauthority_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetExclusionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetExclusionsRequest) ProtoMessage() {}
+
+func (x *SetExclusionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetExclusionsRequest.ProtoReflect.Descriptor instead.
+func (*SetExclusionsRequest) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *SetExclusionsRequest) GetTarget() *Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *SetExclusionsRequest) GetExcluded() []string {
+	if x != nil {
+		return x.Excluded
+	}
+	return nil
+}
+
+// ReconcileReport describes what changing the range or exclusions of a
+// pool did.
+type ReconcileReport struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Range    *PortRange             `protobuf:"bytes,1,opt,name=range,proto3" json:"range,omitempty"`
+	Excluded []string               `protobuf:"bytes,2,rep,name=excluded,proto3" json:"excluded,omitempty"`
+	// added are the ports newly put in the pool, removed the free ports
+	// taken out of it.
+	Added   []int32 `protobuf:"varint,3,rep,packed,name=added,proto3" json:"added,omitempty"`
+	Removed []int32 `protobuf:"varint,4,rep,packed,name=removed,proto3" json:"removed,omitempty"`
+	// stranded maps the assigned ports now outside of the range, or
+	// excluded, to the id holding them. They leave the pool once released.
+	Stranded      map[int32]string `protobuf:"bytes,5,rep,name=stranded,proto3" json:"stranded,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReconcileReport) Reset() {
+	*x = ReconcileReport{}
+	mi := &file_portauthority_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReconcileReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReconcileReport) ProtoMessage() {}
+
+func (x *ReconcileReport) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReconcileReport.ProtoReflect.Descriptor instead.
+func (*ReconcileReport) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ReconcileReport) GetRange() *PortRange {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+func (x *ReconcileReport) GetExcluded() []string {
+	if x != nil {
+		return x.Excluded
+	}
+	return nil
+}
+
+func (x *ReconcileReport) GetAdded() []int32 {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *ReconcileReport) GetRemoved() []int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+func (x *ReconcileReport) GetStranded() map[int32]string {
+	if x != nil {
+		return x.Stranded
+	}
+	return nil
+}
+
+// Inconsistency is a problem found in the data model of a pool, along with
+// how it is, or would be, repaired.
+type Inconsistency struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Port          string                 `protobuf:"bytes,3,opt,name=port,proto3" json:"port,omitempty"`
+	Detail        string                 `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty"`
+	Repair        string                 `protobuf:"bytes,5,opt,name=repair,proto3" json:"repair,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Inconsistency) Reset() {
+	*x = Inconsistency{}
+	mi := &file_portauthority_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Inconsistency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Inconsistency) ProtoMessage() {}
+
+func (x *Inconsistency) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Inconsistency.ProtoReflect.Descriptor instead.
+func (*Inconsistency) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Inconsistency) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Inconsistency) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Inconsistency) GetPort() string {
+	if x != nil {
+		return x.Port
+	}
+	return ""
+}
+
+func (x *Inconsistency) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *Inconsistency) GetRepair() string {
+	if x != nil {
+		return x.Repair
+	}
+	return ""
+}
+
+type InconsistencyList struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Inconsistencies []*Inconsistency       `protobuf:"bytes,1,rep,name=inconsistencies,proto3" json:"inconsistencies,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *InconsistencyList) Reset() {
+	*x = InconsistencyList{}
+	mi := &file_portauthority_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InconsistencyList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InconsistencyList) ProtoMessage() {}
+
+func (x *InconsistencyList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InconsistencyList.ProtoReflect.Descriptor instead.
+func (*InconsistencyList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *InconsistencyList) GetInconsistencies() []*Inconsistency {
+	if x != nil {
+		return x.Inconsistencies
+	}
+	return nil
+}
+
+type NameList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NameList) Reset() {
+	*x = NameList{}
+	mi := &file_portauthority_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameList) ProtoMessage() {}
+
+func (x *NameList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameList.ProtoReflect.Descriptor instead.
+func (*NameList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *NameList) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type IDList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IDList) Reset() {
+	*x = IDList{}
+	mi := &file_portauthority_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IDList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IDList) ProtoMessage() {}
+
+func (x *IDList) ProtoReflect() protoreflect.Message {
+	mi := &file_portauthority_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IDList.ProtoReflect.Descriptor instead.
+func (*IDList) Descriptor() ([]byte, []int) {
+	return file_portauthority_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *IDList) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+var File_portauthority_proto protoreflect.FileDescriptor
+
+const file_portauthority_proto_rawDesc = "" +
+	"\n" +
+	"\x13portauthority.proto\x12\rportauthority\x1a\x1egoogle/protobuf/duration.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"0\n" +
+	"\x06Target\x12\x12\n" +
+	"\x04pool\x18\x01 \x01(\tR\x04pool\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\"~\n" +
+	"\x06Labels\x129\n" +
+	"\x06labels\x18\x01 \x03(\v2!.portauthority.Labels.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd8\x01\n" +
+	"\x0eServiceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12/\n" +
+	"\x05lease\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x05lease\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x16\n" +
+	"\x06prefer\x18\x04 \x01(\bR\x06prefer\x12\x14\n" +
+	"\x05block\x18\x05 \x01(\x05R\x05block\x12\x14\n" +
+	"\x05names\x18\x06 \x03(\tR\x05names\x12-\n" +
+	"\x06labels\x18\a \x01(\v2\x15.portauthority.LabelsR\x06labels\"\xe3\x02\n" +
+	"\aService\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\x05R\x04port\x12\x14\n" +
+	"\x05ports\x18\x03 \x03(\x05R\x05ports\x127\n" +
+	"\x05named\x18\x04 \x03(\v2!.portauthority.Service.NamedEntryR\x05named\x124\n" +
+	"\aexpires\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\aexpires\x12:\n" +
+	"\x06labels\x18\x06 \x03(\v2\".portauthority.Service.LabelsEntryR\x06labels\x1a8\n" +
+	"\n" +
+	"NamedEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa8\x01\n" +
+	"\x0fAllocateRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x127\n" +
+	"\aservice\x18\x02 \x01(\v2\x1d.portauthority.ServiceRequestR\aservice\x12-\n" +
+	"\x04wait\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x04wait\"\x83\x01\n" +
+	"\x17AllocateServicesRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x129\n" +
+	"\bservices\x18\x02 \x03(\v2\x1d.portauthority.ServiceRequestR\bservices\"\xa6\x01\n" +
+	"\n" +
+	"ServiceMap\x12C\n" +
+	"\bservices\x18\x01 \x03(\v2'.portauthority.ServiceMap.ServicesEntryR\bservices\x1aS\n" +
+	"\rServicesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x02 \x01(\v2\x16.portauthority.ServiceR\x05value:\x028\x01\"K\n" +
+	"\n" +
+	"ServiceRef\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"A\n" +
+	"\vServiceList\x122\n" +
+	"\bservices\x18\x01 \x03(\v2\x16.portauthority.ServiceR\bservices\"Z\n" +
+	"\rSelectRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x1a\n" +
+	"\bselector\x18\x02 \x01(\tR\bselector\"Y\n" +
+	"\x16ReleaseServicesRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\tR\x03ids\"\x90\x01\n" +
+	"\vReleasedMap\x12D\n" +
+	"\breleased\x18\x01 \x03(\v2(.portauthority.ReleasedMap.ReleasedEntryR\breleased\x1a;\n" +
+	"\rReleasedEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\"~\n" +
+	"\fRenewRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12/\n" +
+	"\x05lease\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x05lease\"P\n" +
+	"\vPortRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\x05R\x04port\"/\n" +
+	"\tPortOwner\x12\x12\n" +
+	"\x04port\x18\x01 \x01(\x05R\x04port\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"m\n" +
+	"\fPortsRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12.\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x18.portauthority.PortStateR\x05state\" \n" +
+	"\bPortList\x12\x14\n" +
+	"\x05ports\x18\x01 \x03(\x05R\x05ports\"V\n" +
+	"\n" +
+	"PortCounts\x12\x12\n" +
+	"\x04open\x18\x01 \x01(\x03R\x04open\x12\x1a\n" +
+	"\bassigned\x18\x02 \x01(\x03R\bassigned\x12\x18\n" +
+	"\acooling\x18\x03 \x01(\x03R\acooling\"\xa0\x01\n" +
+	"\x0fMappingsRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\x04R\x06cursor\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\x12\x14\n" +
+	"\x05match\x18\x04 \x01(\tR\x05match\x12\x1a\n" +
+	"\bselector\x18\x05 \x01(\tR\bselector\"\xba\x02\n" +
+	"\vMappingPage\x12D\n" +
+	"\bmappings\x18\x01 \x03(\v2(.portauthority.MappingPage.MappingsEntryR\bmappings\x12>\n" +
+	"\x06labels\x18\x02 \x03(\v2&.portauthority.MappingPage.LabelsEntryR\x06labels\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\x04R\x06cursor\x1a;\n" +
+	"\rMappingsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\x1aP\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12+\n" +
+	"\x05value\x18\x02 \x01(\v2\x15.portauthority.LabelsR\x05value:\x028\x01\"\x83\x01\n" +
+	"\rEventsRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x14\n" +
+	"\x05since\x18\x02 \x01(\x03R\x05since\x12-\n" +
+	"\x04wait\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x04wait\"\xca\x01\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x120\n" +
+	"\x05stamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05stamp\x122\n" +
+	"\x04data\x18\x04 \x03(\v2\x1e.portauthority.Event.DataEntryR\x04data\x1a7\n" +
+	"\tDataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"9\n" +
+	"\tEventList\x12,\n" +
+	"\x06events\x18\x01 \x03(\v2\x14.portauthority.EventR\x06events\"W\n" +
+	"\x0fQuarantinedPort\x12\x12\n" +
+	"\x04port\x18\x01 \x01(\x05R\x04port\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\"F\n" +
+	"\x0eQuarantineList\x124\n" +
+	"\x05ports\x18\x01 \x03(\v2\x1e.portauthority.QuarantinedPortR\x05ports\"3\n" +
+	"\tPortRange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x05R\x03end\"p\n" +
+	"\x0fSetRangeRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12.\n" +
+	"\x05range\x18\x02 \x01(\v2\x18.portauthority.PortRangeR\x05range\"+\n" +
+	"\rExclusionList\x12\x1a\n" +
+	"\bexcluded\x18\x01 \x03(\tR\bexcluded\"a\n" +
+	"\x14SetExclusionsRequest\x12-\n" +
+	"\x06target\x18\x01 \x01(\v2\x15.portauthority.TargetR\x06target\x12\x1a\n" +
+	"\bexcluded\x18\x02 \x03(\tR\bexcluded\"\x94\x02\n" +
+	"\x0fReconcileReport\x12.\n" +
+	"\x05range\x18\x01 \x01(\v2\x18.portauthority.PortRangeR\x05range\x12\x1a\n" +
+	"\bexcluded\x18\x02 \x03(\tR\bexcluded\x12\x14\n" +
+	"\x05added\x18\x03 \x03(\x05R\x05added\x12\x18\n" +
+	"\aremoved\x18\x04 \x03(\x05R\aremoved\x12H\n" +
+	"\bstranded\x18\x05 \x03(\v2,.portauthority.ReconcileReport.StrandedEntryR\bstranded\x1a;\n" +
+	"\rStrandedEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"w\n" +
+	"\rInconsistency\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\tR\x04port\x12\x16\n" +
+	"\x06detail\x18\x04 \x01(\tR\x06detail\x12\x16\n" +
+	"\x06repair\x18\x05 \x01(\tR\x06repair\"[\n" +
+	"\x11InconsistencyList\x12F\n" +
+	"\x0finconsistencies\x18\x01 \x03(\v2\x1c.portauthority.InconsistencyR\x0finconsistencies\" \n" +
+	"\bNameList\x12\x14\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\"\x1a\n" +
+	"\x06IDList\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids*0\n" +
+	"\tPortState\x12\b\n" +
+	"\x04OPEN\x10\x00\x12\f\n" +
+	"\bASSIGNED\x10\x01\x12\v\n" +
+	"\aCOOLING\x10\x022\xaa\f\n" +
+	"\rPortAuthority\x128\n" +
+	"\x05Pools\x12\x16.google.protobuf.Empty\x1a\x17.portauthority.NameList\x127\n" +
+	"\x05Hosts\x12\x15.portauthority.Target\x1a\x17.portauthority.NameList\x12B\n" +
+	"\bAllocate\x12\x1e.portauthority.AllocateRequest\x1a\x16.portauthority.Service\x12U\n" +
+	"\x10AllocateServices\x12&.portauthority.AllocateServicesRequest\x1a\x19.portauthority.ServiceMap\x12;\n" +
+	"\x06Lookup\x12\x19.portauthority.ServiceRef\x1a\x16.portauthority.Service\x12D\n" +
+	"\bServices\x12\x1c.portauthority.SelectRequest\x1a\x1a.portauthority.ServiceList\x12<\n" +
+	"\aRelease\x12\x19.portauthority.ServiceRef\x1a\x16.google.protobuf.Empty\x12T\n" +
+	"\x0fReleaseServices\x12%.portauthority.ReleaseServicesRequest\x1a\x1a.portauthority.ReleasedMap\x12F\n" +
+	"\x0fReleaseSelected\x12\x1c.portauthority.SelectRequest\x1a\x15.portauthority.IDList\x12<\n" +
+	"\x05Renew\x12\x1b.portauthority.RenewRequest\x1a\x16.portauthority.Service\x12=\n" +
+	"\x05Whois\x12\x1a.portauthority.PortRequest\x1a\x18.portauthority.PortOwner\x12=\n" +
+	"\x05Ports\x12\x1b.portauthority.PortsRequest\x1a\x17.portauthority.PortList\x12:\n" +
+	"\x06Counts\x12\x15.portauthority.Target\x1a\x19.portauthority.PortCounts\x12F\n" +
+	"\bMappings\x12\x1e.portauthority.MappingsRequest\x1a\x1a.portauthority.MappingPage\x12@\n" +
+	"\x06Events\x12\x1c.portauthority.EventsRequest\x1a\x18.portauthority.EventList\x12B\n" +
+	"\n" +
+	"Quarantine\x12\x15.portauthority.Target\x1a\x1d.portauthority.QuarantineList\x12B\n" +
+	"\fUnquarantine\x12\x1a.portauthority.PortRequest\x1a\x16.google.protobuf.Empty\x128\n" +
+	"\x05Range\x12\x15.portauthority.Target\x1a\x18.portauthority.PortRange\x12J\n" +
+	"\bSetRange\x12\x1e.portauthority.SetRangeRequest\x1a\x1e.portauthority.ReconcileReport\x12A\n" +
+	"\n" +
+	"Exclusions\x12\x15.portauthority.Target\x1a\x1c.portauthority.ExclusionList\x12T\n" +
+	"\rSetExclusions\x12#.portauthority.SetExclusionsRequest\x1a\x1e.portauthority.ReconcileReport\x12@\n" +
+	"\x05Check\x12\x15.portauthority.Target\x1a .portauthority.InconsistencyList\x12A\n" +
+	"\x06Repair\x12\x15.portauthority.Target\x1a .portauthority.InconsistencyListB+Z)github.com/therealbill/port-authority/rpcb\x06proto3"
+
+var (
+	file_portauthority_proto_rawDescOnce sync.Once
+	file_portauthority_proto_rawDescData []byte
+)
+
+func file_portauthority_proto_rawDescGZIP() []byte {
+	file_portauthority_proto_rawDescOnce.Do(func() {
+		file_portauthority_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_portauthority_proto_rawDesc), len(file_portauthority_proto_rawDesc)))
+	})
+	return file_portauthority_proto_rawDescData
+}
+
+var file_portauthority_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_portauthority_proto_msgTypes = make([]protoimpl.MessageInfo, 43)
+var file_portauthority_proto_goTypes = []any{
+	(PortState)(0),                  // 0: portauthority.PortState
+	(*Target)(nil),                  // 1: portauthority.Target
+	(*Labels)(nil),                  // 2: portauthority.Labels
+	(*ServiceRequest)(nil),          // 3: portauthority.ServiceRequest
+	(*Service)(nil),                 // 4: portauthority.Service
+	(*AllocateRequest)(nil),         // 5: portauthority.AllocateRequest
+	(*AllocateServicesRequest)(nil), // 6: portauthority.AllocateServicesRequest
+	(*ServiceMap)(nil),              // 7: portauthority.ServiceMap
+	(*ServiceRef)(nil),              // 8: portauthority.ServiceRef
+	(*ServiceList)(nil),             // 9: portauthority.ServiceList
+	(*SelectRequest)(nil),           // 10: portauthority.SelectRequest
+	(*ReleaseServicesRequest)(nil),  // 11: portauthority.ReleaseServicesRequest
+	(*ReleasedMap)(nil),             // 12: portauthority.ReleasedMap
+	(*RenewRequest)(nil),            // 13: portauthority.RenewRequest
+	(*PortRequest)(nil),             // 14: portauthority.PortRequest
+	(*PortOwner)(nil),               // 15: portauthority.PortOwner
+	(*PortsRequest)(nil),            // 16: portauthority.PortsRequest
+	(*PortList)(nil),                // 17: portauthority.PortList
+	(*PortCounts)(nil),              // 18: portauthority.PortCounts
+	(*MappingsRequest)(nil),         // 19: portauthority.MappingsRequest
+	(*MappingPage)(nil),             // 20: portauthority.MappingPage
+	(*EventsRequest)(nil),           // 21: portauthority.EventsRequest
+	(*Event)(nil),                   // 22: portauthority.Event
+	(*EventList)(nil),               // 23: portauthority.EventList
+	(*QuarantinedPort)(nil),         // 24: portauthority.QuarantinedPort
+	(*QuarantineList)(nil),          // 25: portauthority.QuarantineList
+	(*PortRange)(nil),               // 26: portauthority.PortRange
+	(*SetRangeRequest)(nil),         // 27: portauthority.SetRangeRequest
+	(*ExclusionList)(nil),           // 28: portauthority.ExclusionList
+	(*SetExclusionsRequest)(nil),    // 29: portauthority.SetExclusionsRequest
+	(*ReconcileReport)(nil),         // 30: portauthority.ReconcileReport
+	(*Inconsistency)(nil),           // 31: portauthority.Inconsistency
+	(*InconsistencyList)(nil),       // 32: portauthority.InconsistencyList
+	(*NameList)(nil),                // 33: portauthority.NameList
+	(*IDList)(nil),                  // 34: portauthority.IDList
+	nil,                             // 35: portauthority.Labels.LabelsEntry
+	nil,                             // 36: portauthority.Service.NamedEntry
+	nil,                             // 37: portauthority.Service.LabelsEntry
+	nil,                             // 38: portauthority.ServiceMap.ServicesEntry
+	nil,                             // 39: portauthority.ReleasedMap.ReleasedEntry
+	nil,                             // 40: portauthority.MappingPage.MappingsEntry
+	nil,                             // 41: portauthority.MappingPage.LabelsEntry
+	nil,                             // 42: portauthority.Event.DataEntry
+	nil,                             // 43: portauthority.ReconcileReport.StrandedEntry
+	(*durationpb.Duration)(nil),     // 44: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),   // 45: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),           // 46: google.protobuf.Empty
+}
+var file_portauthority_proto_depIdxs = []int32{
+	35, // 0: portauthority.Labels.labels:type_name -> portauthority.Labels.LabelsEntry
+	44, // 1: portauthority.ServiceRequest.lease:type_name -> google.protobuf.Duration
+	2,  // 2: portauthority.ServiceRequest.labels:type_name -> portauthority.Labels
+	36, // 3: portauthority.Service.named:type_name -> portauthority.Service.NamedEntry
+	45, // 4: portauthority.Service.expires:type_name -> google.protobuf.Timestamp
+	37, // 5: portauthority.Service.labels:type_name -> portauthority.Service.LabelsEntry
+	1,  // 6: portauthority.AllocateRequest.target:type_name -> portauthority.Target
+	3,  // 7: portauthority.AllocateRequest.service:type_name -> portauthority.ServiceRequest
+	44, // 8: portauthority.AllocateRequest.wait:type_name -> google.protobuf.Duration
+	1,  // 9: portauthority.AllocateServicesRequest.target:type_name -> portauthority.Target
+	3,  // 10: portauthority.AllocateServicesRequest.services:type_name -> portauthority.ServiceRequest
+	38, // 11: portauthority.ServiceMap.services:type_name -> portauthority.ServiceMap.ServicesEntry
+	1,  // 12: portauthority.ServiceRef.target:type_name -> portauthority.Target
+	4,  // 13: portauthority.ServiceList.services:type_name -> portauthority.Service
+	1,  // 14: portauthority.SelectRequest.target:type_name -> portauthority.Target
+	1,  // 15: portauthority.ReleaseServicesRequest.target:type_name -> portauthority.Target
+	39, // 16: portauthority.ReleasedMap.released:type_name -> portauthority.ReleasedMap.ReleasedEntry
+	1,  // 17: portauthority.RenewRequest.target:type_name -> portauthority.Target
+	44, // 18: portauthority.RenewRequest.lease:type_name -> google.protobuf.Duration
+	1,  // 19: portauthority.PortRequest.target:type_name -> portauthority.Target
+	1,  // 20: portauthority.PortsRequest.target:type_name -> portauthority.Target
+	0,  // 21: portauthority.PortsRequest.state:type_name -> portauthority.PortState
+	1,  // 22: portauthority.MappingsRequest.target:type_name -> portauthority.Target
+	40, // 23: portauthority.MappingPage.mappings:type_name -> portauthority.MappingPage.MappingsEntry
+	41, // 24: portauthority.MappingPage.labels:type_name -> portauthority.MappingPage.LabelsEntry
+	1,  // 25: portauthority.EventsRequest.target:type_name -> portauthority.Target
+	44, // 26: portauthority.EventsRequest.wait:type_name -> google.protobuf.Duration
+	45, // 27: portauthority.Event.stamp:type_name -> google.protobuf.Timestamp
+	42, // 28: portauthority.Event.data:type_name -> portauthority.Event.DataEntry
+	22, // 29: portauthority.EventList.events:type_name -> portauthority.Event
+	45, // 30: portauthority.QuarantinedPort.since:type_name -> google.protobuf.Timestamp
+	24, // 31: portauthority.QuarantineList.ports:type_name -> portauthority.QuarantinedPort
+	1,  // 32: portauthority.SetRangeRequest.target:type_name -> portauthority.Target
+	26, // 33: portauthority.SetRangeRequest.range:type_name -> portauthority.PortRange
+	1,  // 34: portauthority.SetExclusionsRequest.target:type_name -> portauthority.Target
+	26, // 35: portauthority.ReconcileReport.range:type_name -> portauthority.PortRange
+	43, // 36: portauthority.ReconcileReport.stranded:type_name -> portauthority.ReconcileReport.StrandedEntry
+	31, // 37: portauthority.InconsistencyList.inconsistencies:type_name -> portauthority.Inconsistency
+	4,  // 38: portauthority.ServiceMap.ServicesEntry.value:type_name -> portauthority.Service
+	2,  // 39: portauthority.MappingPage.LabelsEntry.value:type_name -> portauthority.Labels
+	46, // 40: portauthority.PortAuthority.Pools:input_type -> google.protobuf.Empty
+	1,  // 41: portauthority.PortAuthority.Hosts:input_type -> portauthority.Target
+	5,  // 42: portauthority.PortAuthority.Allocate:input_type -> portauthority.AllocateRequest
+	6,  // 43: portauthority.PortAuthority.AllocateServices:input_type -> portauthority.AllocateServicesRequest
+	8,  // 44: portauthority.PortAuthority.Lookup:input_type -> portauthority.ServiceRef
+	10, // 45: portauthority.PortAuthority.Services:input_type -> portauthority.SelectRequest
+	8,  // 46: portauthority.PortAuthority.Release:input_type -> portauthority.ServiceRef
+	11, // 47: portauthority.PortAuthority.ReleaseServices:input_type -> portauthority.ReleaseServicesRequest
+	10, // 48: portauthority.PortAuthority.ReleaseSelected:input_type -> portauthority.SelectRequest
+	13, // 49: portauthority.PortAuthority.Renew:input_type -> portauthority.RenewRequest
+	14, // 50: portauthority.PortAuthority.Whois:input_type -> portauthority.PortRequest
+	16, // 51: portauthority.PortAuthority.Ports:input_type -> portauthority.PortsRequest
+	1,  // 52: portauthority.PortAuthority.Counts:input_type -> portauthority.Target
+	19, // 53: portauthority.PortAuthority.Mappings:input_type -> portauthority.MappingsRequest
+	21, // 54: portauthority.PortAuthority.Events:input_type -> portauthority.EventsRequest
+	1,  // 55: portauthority.PortAuthority.Quarantine:input_type -> portauthority.Target
+	14, // 56: portauthority.PortAuthority.Unquarantine:input_type -> portauthority.PortRequest
+	1,  // 57: portauthority.PortAuthority.Range:input_type -> portauthority.Target
+	27, // 58: portauthority.PortAuthority.SetRange:input_type -> portauthority.SetRangeRequest
+	1,  // 59: portauthority.PortAuthority.Exclusions:input_type -> portauthority.Target
+	29, // 60: portauthority.PortAuthority.SetExclusions:input_type -> portauthority.SetExclusionsRequest
+	1,  // 61: portauthority.PortAuthority.Check:input_type -> portauthority.Target
+	1,  // 62: portauthority.PortAuthority.Repair:input_type -> portauthority.Target
+	33, // 63: portauthority.PortAuthority.Pools:output_type -> portauthority.NameList
+	33, // 64: portauthority.PortAuthority.Hosts:output_type -> portauthority.NameList
+	4,  // 65: portauthority.PortAuthority.Allocate:output_type -> portauthority.Service
+	7,  // 66: portauthority.PortAuthority.AllocateServices:output_type -> portauthority.ServiceMap
+	4,  // 67: portauthority.PortAuthority.Lookup:output_type -> portauthority.Service
+	9,  // 68: portauthority.PortAuthority.Services:output_type -> portauthority.ServiceList
+	46, // 69: portauthority.PortAuthority.Release:output_type -> google.protobuf.Empty
+	12, // 70: portauthority.PortAuthority.ReleaseServices:output_type -> portauthority.ReleasedMap
+	34, // 71: portauthority.PortAuthority.ReleaseSelected:output_type -> portauthority.IDList
+	4,  // 72: portauthority.PortAuthority.Renew:output_type -> portauthority.Service
+	15, // 73: portauthority.PortAuthority.Whois:output_type -> portauthority.PortOwner
+	17, // 74: portauthority.PortAuthority.Ports:output_type -> portauthority.PortList
+	18, // 75: portauthority.PortAuthority.Counts:output_type -> portauthority.PortCounts
+	20, // 76: portauthority.PortAuthority.Mappings:output_type -> portauthority.MappingPage
+	23, // 77: portauthority.PortAuthority.Events:output_type -> portauthority.EventList
+	25, // 78: portauthority.PortAuthority.Quarantine:output_type -> portauthority.QuarantineList
+	46, // 79: portauthority.PortAuthority.Unquarantine:output_type -> google.protobuf.Empty
+	26, // 80: portauthority.PortAuthority.Range:output_type -> portauthority.PortRange
+	30, // 81: portauthority.PortAuthority.SetRange:output_type -> portauthority.ReconcileReport
+	28, // 82: portauthority.PortAuthority.Exclusions:output_type -> portauthority.ExclusionList
+	30, // 83: portauthority.PortAuthority.SetExclusions:output_type -> portauthority.ReconcileReport
+	32, // 84: portauthority.PortAuthority.Check:output_type -> portauthority.InconsistencyList
+	32, // 85: portauthority.PortAuthority.Repair:output_type -> portauthority.InconsistencyList
+	63, // [63:86] is the sub-list for method output_type
+	40, // [40:63] is the sub-list for method input_type
+	40, // [40:40] is the sub-list for extension type_name
+	40, // [40:40] is the sub-list for extension extendee
+	0,  // [0:40] is the sub-list for field type_name
+}
+
+func init() { file_portauthority_proto_init() }
+func file_portauthority_proto_init() {
+	if File_portauthority_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_portauthority_proto_rawDesc), len(file_portauthority_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   43,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_portauthority_proto_goTypes,
+		DependencyIndexes: file_portauthority_proto_depIdxs,
+		EnumInfos:         file_portauthority_proto_enumTypes,
+		MessageInfos:      file_portauthority_proto_msgTypes,
+	}.Build()
+	File_portauthority_proto = out.File
+	file_portauthority_proto_goTypes = nil
+	file_portauthority_proto_depIdxs = nil
+}
@@ -0,0 +1,1041 @@
+// The PortAuthority gRPC service, served on the RPC port alongside the HTTP
+// API. It offers the operations of /api/v2; see the RPC section of the
+// README.
+//
+// Every request carries a Target picking the pool, the default one when
+// empty, and the host within it. A failed call returns the gRPC code
+// matching the HTTP status /api/v2 answers with, and an ErrorInfo detail
+// whose reason is the code of its JSON body, such as "conflict", with the
+// holder of the port in its "owner" metadata for a conflict and the service
+// which failed in "id" for a bulk allocation.
+//
+// Regenerate the Go code with go generate ./rpc after changing this file.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: portauthority.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PortAuthority_Pools_FullMethodName            = "/portauthority.PortAuthority/Pools"
+	PortAuthority_Hosts_FullMethodName            = "/portauthority.PortAuthority/Hosts"
+	PortAuthority_Allocate_FullMethodName         = "/portauthority.PortAuthority/Allocate"
+	PortAuthority_AllocateServices_FullMethodName = "/portauthority.PortAuthority/AllocateServices"
+	PortAuthority_Lookup_FullMethodName           = "/portauthority.PortAuthority/Lookup"
+	PortAuthority_Services_FullMethodName         = "/portauthority.PortAuthority/Services"
+	PortAuthority_Release_FullMethodName          = "/portauthority.PortAuthority/Release"
+	PortAuthority_ReleaseServices_FullMethodName  = "/portauthority.PortAuthority/ReleaseServices"
+	PortAuthority_ReleaseSelected_FullMethodName  = "/portauthority.PortAuthority/ReleaseSelected"
+	PortAuthority_Renew_FullMethodName            = "/portauthority.PortAuthority/Renew"
+	PortAuthority_Whois_FullMethodName            = "/portauthority.PortAuthority/Whois"
+	PortAuthority_Ports_FullMethodName            = "/portauthority.PortAuthority/Ports"
+	PortAuthority_Counts_FullMethodName           = "/portauthority.PortAuthority/Counts"
+	PortAuthority_Mappings_FullMethodName         = "/portauthority.PortAuthority/Mappings"
+	PortAuthority_Events_FullMethodName           = "/portauthority.PortAuthority/Events"
+	PortAuthority_Quarantine_FullMethodName       = "/portauthority.PortAuthority/Quarantine"
+	PortAuthority_Unquarantine_FullMethodName     = "/portauthority.PortAuthority/Unquarantine"
+	PortAuthority_Range_FullMethodName            = "/portauthority.PortAuthority/Range"
+	PortAuthority_SetRange_FullMethodName         = "/portauthority.PortAuthority/SetRange"
+	PortAuthority_Exclusions_FullMethodName       = "/portauthority.PortAuthority/Exclusions"
+	PortAuthority_SetExclusions_FullMethodName    = "/portauthority.PortAuthority/SetExclusions"
+	PortAuthority_Check_FullMethodName            = "/portauthority.PortAuthority/Check"
+	PortAuthority_Repair_FullMethodName           = "/portauthority.PortAuthority/Repair"
+)
+
+// PortAuthorityClient is the client API for PortAuthority service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PortAuthorityClient interface {
+	// Pools lists the names of the pools served.
+	Pools(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NameList, error)
+	// Hosts lists the hosts the pool has assigned ports on.
+	Hosts(ctx context.Context, in *Target, opts ...grpc.CallOption) (*NameList, error)
+	// Allocate assigns ports to a service, or returns those it holds.
+	Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (*Service, error)
+	// AllocateServices assigns ports to every service listed, or to none of
+	// them.
+	AllocateServices(ctx context.Context, in *AllocateServicesRequest, opts ...grpc.CallOption) (*ServiceMap, error)
+	// Lookup returns a service, failing with NOT_FOUND when it holds no
+	// ports.
+	Lookup(ctx context.Context, in *ServiceRef, opts ...grpc.CallOption) (*Service, error)
+	// Services lists the services whose labels match the selector, every
+	// service when it is empty.
+	Services(ctx context.Context, in *SelectRequest, opts ...grpc.CallOption) (*ServiceList, error)
+	// Release releases a service, failing with NOT_FOUND when it held no
+	// ports.
+	Release(ctx context.Context, in *ServiceRef, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ReleaseServices releases the services listed and reports for each
+	// whether it held any ports.
+	ReleaseServices(ctx context.Context, in *ReleaseServicesRequest, opts ...grpc.CallOption) (*ReleasedMap, error)
+	// ReleaseSelected releases the services whose labels match the selector,
+	// which is required, and lists their ids.
+	ReleaseSelected(ctx context.Context, in *SelectRequest, opts ...grpc.CallOption) (*IDList, error)
+	// Renew extends the lease on a service.
+	Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*Service, error)
+	// Whois returns the id holding a port, failing with NOT_FOUND when none
+	// does.
+	Whois(ctx context.Context, in *PortRequest, opts ...grpc.CallOption) (*PortOwner, error)
+	// Ports lists the ports in a state.
+	Ports(ctx context.Context, in *PortsRequest, opts ...grpc.CallOption) (*PortList, error)
+	// Counts counts the ports in each state.
+	Counts(ctx context.Context, in *Target, opts ...grpc.CallOption) (*PortCounts, error)
+	// Mappings returns a page of the mapping of ids to ports.
+	Mappings(ctx context.Context, in *MappingsRequest, opts ...grpc.CallOption) (*MappingPage, error)
+	// Events returns the events after a given one, waiting for one when
+	// there are none yet.
+	Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (*EventList, error)
+	// Quarantine lists the ports found in use outside of port-authority.
+	Quarantine(ctx context.Context, in *Target, opts ...grpc.CallOption) (*QuarantineList, error)
+	// Unquarantine puts a quarantined port back into the pool.
+	Unquarantine(ctx context.Context, in *PortRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Range returns the range of ports the pool manages.
+	Range(ctx context.Context, in *Target, opts ...grpc.CallOption) (*PortRange, error)
+	// SetRange changes the range of ports the pool manages and reports what
+	// changed.
+	SetRange(ctx context.Context, in *SetRangeRequest, opts ...grpc.CallOption) (*ReconcileReport, error)
+	// Exclusions lists the ports within the range which are never handed
+	// out.
+	Exclusions(ctx context.Context, in *Target, opts ...grpc.CallOption) (*ExclusionList, error)
+	// SetExclusions replaces the exclusions of the pool and reports what
+	// changed.
+	SetExclusions(ctx context.Context, in *SetExclusionsRequest, opts ...grpc.CallOption) (*ReconcileReport, error)
+	// Check reports the inconsistencies in the data model of the pool.
+	Check(ctx context.Context, in *Target, opts ...grpc.CallOption) (*InconsistencyList, error)
+	// Repair repairs the inconsistencies in the data model of the pool and
+	// reports what it repaired.
+	Repair(ctx context.Context, in *Target, opts ...grpc.CallOption) (*InconsistencyList, error)
+}
+
+type portAuthorityClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPortAuthorityClient(cc grpc.ClientConnInterface) PortAuthorityClient {
+	return &portAuthorityClient{cc}
+}
+
+func (c *portAuthorityClient) Pools(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*NameList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NameList)
+	err := c.cc.Invoke(ctx, PortAuthority_Pools_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Hosts(ctx context.Context, in *Target, opts ...grpc.CallOption) (*NameList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NameList)
+	err := c.cc.Invoke(ctx, PortAuthority_Hosts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Allocate(ctx context.Context, in *AllocateRequest, opts ...grpc.CallOption) (*Service, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Service)
+	err := c.cc.Invoke(ctx, PortAuthority_Allocate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) AllocateServices(ctx context.Context, in *AllocateServicesRequest, opts ...grpc.CallOption) (*ServiceMap, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServiceMap)
+	err := c.cc.Invoke(ctx, PortAuthority_AllocateServices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Lookup(ctx context.Context, in *ServiceRef, opts ...grpc.CallOption) (*Service, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Service)
+	err := c.cc.Invoke(ctx, PortAuthority_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Services(ctx context.Context, in *SelectRequest, opts ...grpc.CallOption) (*ServiceList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServiceList)
+	err := c.cc.Invoke(ctx, PortAuthority_Services_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Release(ctx context.Context, in *ServiceRef, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, PortAuthority_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) ReleaseServices(ctx context.Context, in *ReleaseServicesRequest, opts ...grpc.CallOption) (*ReleasedMap, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleasedMap)
+	err := c.cc.Invoke(ctx, PortAuthority_ReleaseServices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) ReleaseSelected(ctx context.Context, in *SelectRequest, opts ...grpc.CallOption) (*IDList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IDList)
+	err := c.cc.Invoke(ctx, PortAuthority_ReleaseSelected_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*Service, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Service)
+	err := c.cc.Invoke(ctx, PortAuthority_Renew_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Whois(ctx context.Context, in *PortRequest, opts ...grpc.CallOption) (*PortOwner, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PortOwner)
+	err := c.cc.Invoke(ctx, PortAuthority_Whois_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Ports(ctx context.Context, in *PortsRequest, opts ...grpc.CallOption) (*PortList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PortList)
+	err := c.cc.Invoke(ctx, PortAuthority_Ports_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Counts(ctx context.Context, in *Target, opts ...grpc.CallOption) (*PortCounts, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PortCounts)
+	err := c.cc.Invoke(ctx, PortAuthority_Counts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Mappings(ctx context.Context, in *MappingsRequest, opts ...grpc.CallOption) (*MappingPage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MappingPage)
+	err := c.cc.Invoke(ctx, PortAuthority_Mappings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (*EventList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EventList)
+	err := c.cc.Invoke(ctx, PortAuthority_Events_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Quarantine(ctx context.Context, in *Target, opts ...grpc.CallOption) (*QuarantineList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuarantineList)
+	err := c.cc.Invoke(ctx, PortAuthority_Quarantine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Unquarantine(ctx context.Context, in *PortRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, PortAuthority_Unquarantine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Range(ctx context.Context, in *Target, opts ...grpc.CallOption) (*PortRange, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PortRange)
+	err := c.cc.Invoke(ctx, PortAuthority_Range_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) SetRange(ctx context.Context, in *SetRangeRequest, opts ...grpc.CallOption) (*ReconcileReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReconcileReport)
+	err := c.cc.Invoke(ctx, PortAuthority_SetRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Exclusions(ctx context.Context, in *Target, opts ...grpc.CallOption) (*ExclusionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExclusionList)
+	err := c.cc.Invoke(ctx, PortAuthority_Exclusions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) SetExclusions(ctx context.Context, in *SetExclusionsRequest, opts ...grpc.CallOption) (*ReconcileReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReconcileReport)
+	err := c.cc.Invoke(ctx, PortAuthority_SetExclusions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Check(ctx context.Context, in *Target, opts ...grpc.CallOption) (*InconsistencyList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InconsistencyList)
+	err := c.cc.Invoke(ctx, PortAuthority_Check_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *portAuthorityClient) Repair(ctx context.Context, in *Target, opts ...grpc.CallOption) (*InconsistencyList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InconsistencyList)
+	err := c.cc.Invoke(ctx, PortAuthority_Repair_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PortAuthorityServer is the server API for PortAuthority service.
+// All implementations must embed UnimplementedPortAuthorityServer
+// for forward compatibility.
+type PortAuthorityServer interface {
+	// Pools lists the names of the pools served.
+	Pools(context.Context, *emptypb.Empty) (*NameList, error)
+	// Hosts lists the hosts the pool has assigned ports on.
+	Hosts(context.Context, *Target) (*NameList, error)
+	// Allocate assigns ports to a service, or returns those it holds.
+	Allocate(context.Context, *AllocateRequest) (*Service, error)
+	// AllocateServices assigns ports to every service listed, or to none of
+	// them.
+	AllocateServices(context.Context, *AllocateServicesRequest) (*ServiceMap, error)
+	// Lookup returns a service, failing with NOT_FOUND when it holds no
+	// ports.
+	Lookup(context.Context, *ServiceRef) (*Service, error)
+	// Services lists the services whose labels match the selector, every
+	// service when it is empty.
+	Services(context.Context, *SelectRequest) (*ServiceList, error)
+	// Release releases a service, failing with NOT_FOUND when it held no
+	// ports.
+	Release(context.Context, *ServiceRef) (*emptypb.Empty, error)
+	// ReleaseServices releases the services listed and reports for each
+	// whether it held any ports.
+	ReleaseServices(context.Context, *ReleaseServicesRequest) (*ReleasedMap, error)
+	// ReleaseSelected releases the services whose labels match the selector,
+	// which is required, and lists their ids.
+	ReleaseSelected(context.Context, *SelectRequest) (*IDList, error)
+	// Renew extends the lease on a service.
+	Renew(context.Context, *RenewRequest) (*Service, error)
+	// Whois returns the id holding a port, failing with NOT_FOUND when none
+	// does.
+	Whois(context.Context, *PortRequest) (*PortOwner, error)
+	// Ports lists the ports in a state.
+	Ports(context.Context, *PortsRequest) (*PortList, error)
+	// Counts counts the ports in each state.
+	Counts(context.Context, *Target) (*PortCounts, error)
+	// Mappings returns a page of the mapping of ids to ports.
+	Mappings(context.Context, *MappingsRequest) (*MappingPage, error)
+	// Events returns the events after a given one, waiting for one when
+	// there are none yet.
+	Events(context.Context, *EventsRequest) (*EventList, error)
+	// Quarantine lists the ports found in use outside of port-authority.
+	Quarantine(context.Context, *Target) (*QuarantineList, error)
+	// Unquarantine puts a quarantined port back into the pool.
+	Unquarantine(context.Context, *PortRequest) (*emptypb.Empty, error)
+	// Range returns the range of ports the pool manages.
+	Range(context.Context, *Target) (*PortRange, error)
+	// SetRange changes the range of ports the pool manages and reports what
+	// changed.
+	SetRange(context.Context, *SetRangeRequest) (*ReconcileReport, error)
+	// Exclusions lists the ports within the range which are never handed
+	// out.
+	Exclusions(context.Context, *Target) (*ExclusionList, error)
+	// SetExclusions replaces the exclusions of the pool and reports what
+	// changed.
+	SetExclusions(context.Context, *SetExclusionsRequest) (*ReconcileReport, error)
+	// Check reports the inconsistencies in the data model of the pool.
+	Check(context.Context, *Target) (*InconsistencyList, error)
+	// Repair repairs the inconsistencies in the data model of the pool and
+	// reports what it repaired.
+	Repair(context.Context, *Target) (*InconsistencyList, error)
+	mustEmbedUnimplementedPortAuthorityServer()
+}
+
+// UnimplementedPortAuthorityServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPortAuthorityServer struct{}
+
+func (UnimplementedPortAuthorityServer) Pools(context.Context, *emptypb.Empty) (*NameList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pools not implemented")
+}
+func (UnimplementedPortAuthorityServer) Hosts(context.Context, *Target) (*NameList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Hosts not implemented")
+}
+func (UnimplementedPortAuthorityServer) Allocate(context.Context, *AllocateRequest) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Allocate not implemented")
+}
+func (UnimplementedPortAuthorityServer) AllocateServices(context.Context, *AllocateServicesRequest) (*ServiceMap, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AllocateServices not implemented")
+}
+func (UnimplementedPortAuthorityServer) Lookup(context.Context, *ServiceRef) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedPortAuthorityServer) Services(context.Context, *SelectRequest) (*ServiceList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Services not implemented")
+}
+func (UnimplementedPortAuthorityServer) Release(context.Context, *ServiceRef) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedPortAuthorityServer) ReleaseServices(context.Context, *ReleaseServicesRequest) (*ReleasedMap, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseServices not implemented")
+}
+func (UnimplementedPortAuthorityServer) ReleaseSelected(context.Context, *SelectRequest) (*IDList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseSelected not implemented")
+}
+func (UnimplementedPortAuthorityServer) Renew(context.Context, *RenewRequest) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Renew not implemented")
+}
+func (UnimplementedPortAuthorityServer) Whois(context.Context, *PortRequest) (*PortOwner, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Whois not implemented")
+}
+func (UnimplementedPortAuthorityServer) Ports(context.Context, *PortsRequest) (*PortList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ports not implemented")
+}
+func (UnimplementedPortAuthorityServer) Counts(context.Context, *Target) (*PortCounts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Counts not implemented")
+}
+func (UnimplementedPortAuthorityServer) Mappings(context.Context, *MappingsRequest) (*MappingPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mappings not implemented")
+}
+func (UnimplementedPortAuthorityServer) Events(context.Context, *EventsRequest) (*EventList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Events not implemented")
+}
+func (UnimplementedPortAuthorityServer) Quarantine(context.Context, *Target) (*QuarantineList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Quarantine not implemented")
+}
+func (UnimplementedPortAuthorityServer) Unquarantine(context.Context, *PortRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unquarantine not implemented")
+}
+func (UnimplementedPortAuthorityServer) Range(context.Context, *Target) (*PortRange, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Range not implemented")
+}
+func (UnimplementedPortAuthorityServer) SetRange(context.Context, *SetRangeRequest) (*ReconcileReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRange not implemented")
+}
+func (UnimplementedPortAuthorityServer) Exclusions(context.Context, *Target) (*ExclusionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Exclusions not implemented")
+}
+func (UnimplementedPortAuthorityServer) SetExclusions(context.Context, *SetExclusionsRequest) (*ReconcileReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetExclusions not implemented")
+}
+func (UnimplementedPortAuthorityServer) Check(context.Context, *Target) (*InconsistencyList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedPortAuthorityServer) Repair(context.Context, *Target) (*InconsistencyList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Repair not implemented")
+}
+func (UnimplementedPortAuthorityServer) mustEmbedUnimplementedPortAuthorityServer() {}
+func (UnimplementedPortAuthorityServer) testEmbeddedByValue()                       {}
+
+// UnsafePortAuthorityServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PortAuthorityServer will
+// result in compilation errors.
+type UnsafePortAuthorityServer interface {
+	mustEmbedUnimplementedPortAuthorityServer()
+}
+
+func RegisterPortAuthorityServer(s grpc.ServiceRegistrar, srv PortAuthorityServer) {
+	// If the following call pancis, it indicates UnimplementedPortAuthorityServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PortAuthority_ServiceDesc, srv)
+}
+
+func _PortAuthority_Pools_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Pools(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Pools_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Pools(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Hosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Hosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Hosts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Hosts(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Allocate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Allocate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Allocate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Allocate(ctx, req.(*AllocateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_AllocateServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).AllocateServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_AllocateServices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).AllocateServices(ctx, req.(*AllocateServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Lookup(ctx, req.(*ServiceRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Services_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Services(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Services_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Services(ctx, req.(*SelectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ServiceRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Release(ctx, req.(*ServiceRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_ReleaseServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseServicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).ReleaseServices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_ReleaseServices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).ReleaseServices(ctx, req.(*ReleaseServicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_ReleaseSelected_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).ReleaseSelected(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_ReleaseSelected_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).ReleaseSelected(ctx, req.(*SelectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Renew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Renew(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Renew_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Renew(ctx, req.(*RenewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Whois_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Whois(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Whois_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Whois(ctx, req.(*PortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Ports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Ports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Ports_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Ports(ctx, req.(*PortsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Counts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Counts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Counts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Counts(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Mappings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MappingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Mappings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Mappings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Mappings(ctx, req.(*MappingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Events_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Events(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Events_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Events(ctx, req.(*EventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Quarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Quarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Quarantine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Quarantine(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Unquarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Unquarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Unquarantine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Unquarantine(ctx, req.(*PortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Range_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Range(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Range_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Range(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_SetRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).SetRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_SetRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).SetRange(ctx, req.(*SetRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Exclusions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Exclusions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Exclusions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Exclusions(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_SetExclusions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetExclusionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).SetExclusions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_SetExclusions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).SetExclusions(ctx, req.(*SetExclusionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Check(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PortAuthority_Repair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Target)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PortAuthorityServer).Repair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PortAuthority_Repair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PortAuthorityServer).Repair(ctx, req.(*Target))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PortAuthority_ServiceDesc is the grpc.ServiceDesc for PortAuthority service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PortAuthority_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "portauthority.PortAuthority",
+	HandlerType: (*PortAuthorityServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Pools",
+			Handler:    _PortAuthority_Pools_Handler,
+		},
+		{
+			MethodName: "Hosts",
+			Handler:    _PortAuthority_Hosts_Handler,
+		},
+		{
+			MethodName: "Allocate",
+			Handler:    _PortAuthority_Allocate_Handler,
+		},
+		{
+			MethodName: "AllocateServices",
+			Handler:    _PortAuthority_AllocateServices_Handler,
+		},
+		{
+			MethodName: "Lookup",
+			Handler:    _PortAuthority_Lookup_Handler,
+		},
+		{
+			MethodName: "Services",
+			Handler:    _PortAuthority_Services_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _PortAuthority_Release_Handler,
+		},
+		{
+			MethodName: "ReleaseServices",
+			Handler:    _PortAuthority_ReleaseServices_Handler,
+		},
+		{
+			MethodName: "ReleaseSelected",
+			Handler:    _PortAuthority_ReleaseSelected_Handler,
+		},
+		{
+			MethodName: "Renew",
+			Handler:    _PortAuthority_Renew_Handler,
+		},
+		{
+			MethodName: "Whois",
+			Handler:    _PortAuthority_Whois_Handler,
+		},
+		{
+			MethodName: "Ports",
+			Handler:    _PortAuthority_Ports_Handler,
+		},
+		{
+			MethodName: "Counts",
+			Handler:    _PortAuthority_Counts_Handler,
+		},
+		{
+			MethodName: "Mappings",
+			Handler:    _PortAuthority_Mappings_Handler,
+		},
+		{
+			MethodName: "Events",
+			Handler:    _PortAuthority_Events_Handler,
+		},
+		{
+			MethodName: "Quarantine",
+			Handler:    _PortAuthority_Quarantine_Handler,
+		},
+		{
+			MethodName: "Unquarantine",
+			Handler:    _PortAuthority_Unquarantine_Handler,
+		},
+		{
+			MethodName: "Range",
+			Handler:    _PortAuthority_Range_Handler,
+		},
+		{
+			MethodName: "SetRange",
+			Handler:    _PortAuthority_SetRange_Handler,
+		},
+		{
+			MethodName: "Exclusions",
+			Handler:    _PortAuthority_Exclusions_Handler,
+		},
+		{
+			MethodName: "SetExclusions",
+			Handler:    _PortAuthority_SetExclusions_Handler,
+		},
+		{
+			MethodName: "Check",
+			Handler:    _PortAuthority_Check_Handler,
+		},
+		{
+			MethodName: "Repair",
+			Handler:    _PortAuthority_Repair_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "portauthority.proto",
+}
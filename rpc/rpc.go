@@ -0,0 +1,5 @@
+// Package rpc holds the code generated from portauthority.proto, the
+// PortAuthority gRPC service handlers.ServeRPC serves on the RPC port.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative portauthority.proto